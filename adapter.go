@@ -0,0 +1,119 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// errNoResponse is returned by the Interceptor made by FromCallInterceptor
+// when the CallInterceptor returns no error without having called next.
+var errNoResponse = errors.New("interceptor: call interceptor returned without a response")
+
+// Call describes an outgoing call independently of the protocol carrying it,
+// so that cross-cutting logic such as attaching credentials or propagating
+// trace context can be written once, as a CallInterceptor, and shared between
+// HTTP and other transports such as gRPC.
+type Call struct {
+	// Protocol names the transport of the call, such as "http" or "grpc".
+	Protocol string
+	// Method is the HTTP method, or for gRPC the full method name, such as
+	// "/users.v1.Users/Get".
+	Method string
+	// Target is the request URL, or for gRPC the target of the connection.
+	Target string
+	// Metadata holds the headers sent with the call.
+	Metadata Metadata
+}
+
+// Metadata holds the headers of a Call. Keys are lower case, as in gRPC
+// metadata, so a gRPC metadata.MD can be converted to and from Metadata
+// directly.
+type Metadata map[string][]string
+
+// Get returns the first value associated with key, or "" if there is none.
+func (m Metadata) Get(key string) string {
+	if values := m[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values associated with key.
+func (m Metadata) Set(key string, values ...string) {
+	m[strings.ToLower(key)] = values
+}
+
+// CallHandler performs a Call.
+type CallHandler func(ctx context.Context, call *Call) error
+
+// CallInterceptor is protocol-independent logic run around a Call. It may
+// change the call's metadata and context before passing them to next, or fail
+// the call by returning an error without calling next.
+type CallInterceptor func(ctx context.Context, call *Call, next CallHandler) error
+
+// FromCallInterceptor adapts a CallInterceptor into an Interceptor. The call
+// passed to ci has the protocol "http", the request method and URL, and the
+// request headers as metadata. The request is sent with the context and
+// metadata that ci passes on, and the error that next returns to ci is the
+// transport's error, if any. If ci calls next more than once, for example to
+// retry, the response of the last call is returned and the earlier ones are
+// closed. If ci returns nil without calling next, the request fails.
+//
+// The same CallInterceptor can be bridged to gRPC in a few lines without this
+// package depending on gRPC:
+//
+//	func UnaryClientInterceptor(ci interceptor.CallInterceptor) grpc.UnaryClientInterceptor {
+//		return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+//			md, _ := metadata.FromOutgoingContext(ctx)
+//			call := &interceptor.Call{Protocol: "grpc", Method: method, Target: cc.Target(), Metadata: interceptor.Metadata(md.Copy())}
+//			return ci(ctx, call, func(ctx context.Context, call *interceptor.Call) error {
+//				ctx = metadata.NewOutgoingContext(ctx, metadata.MD(call.Metadata))
+//				return invoker(ctx, method, req, reply, cc, opts...)
+//			})
+//		}
+//	}
+func FromCallInterceptor(ci CallInterceptor) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			call := &Call{
+				Protocol: "http",
+				Method:   req.Method,
+				Target:   req.URL.String(),
+				Metadata: make(Metadata, len(req.Header)),
+			}
+			if call.Method == "" {
+				call.Method = http.MethodGet
+			}
+			for name, values := range req.Header {
+				call.Metadata[strings.ToLower(name)] = append([]string(nil), values...)
+			}
+
+			var resp *http.Response
+			err := ci(req.Context(), call, func(ctx context.Context, call *Call) error {
+				out := req.Clone(ctx)
+				out.Header = make(http.Header, len(call.Metadata))
+				for key, values := range call.Metadata {
+					out.Header[http.CanonicalHeaderKey(key)] = values
+				}
+				if resp != nil {
+					discard(resp)
+				}
+				var err error
+				resp, err = next.RoundTrip(out)
+				return err
+			})
+			if err != nil {
+				if resp != nil && resp.Body != nil {
+					resp.Body.Close()
+				}
+				return nil, err
+			}
+			if resp == nil {
+				return nil, newError("CallInterceptor", req, errNoResponse)
+			}
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// bearer is a protocol-independent CallInterceptor that attaches a token.
+func bearer(token string) CallInterceptor {
+	return func(ctx context.Context, call *Call, next CallHandler) error {
+		if token == "" {
+			return errors.New("no token")
+		}
+		call.Metadata.Set("Authorization", "Bearer "+token)
+		return next(ctx, call)
+	}
+}
+
+func TestFromCallInterceptor(t *testing.T) {
+	var received *http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	var seen Call
+	observe := func(ctx context.Context, call *Call, next CallHandler) error {
+		seen = *call
+		return next(ctx, call)
+	}
+	rt := Chain(FromCallInterceptor(observe), FromCallInterceptor(bearer("token")))(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if seen.Protocol != "http" || seen.Method != "GET" || seen.Target != "http://example.com/users" {
+		t.Errorf("Expected the call to describe the request, got %+v", seen)
+	}
+	if seen.Metadata.Get("x-request-id") != "abc" {
+		t.Errorf("Expected headers as lower-case metadata, got %v", seen.Metadata)
+	}
+	if received.Header.Get("Authorization") != "Bearer token" || received.Header.Get("X-Request-ID") != "abc" {
+		t.Errorf("Expected the metadata to be sent as headers, got %v", received.Header)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("Expected the caller's request to be left unchanged")
+	}
+
+	rt = FromCallInterceptor(bearer(""))(transport)
+	if _, err := rt.RoundTrip(req); err == nil || err.Error() != "no token" {
+		t.Errorf("Expected the interceptor's error, got %v", err)
+	}
+}
+
+func TestFromCallInterceptorContract(t *testing.T) {
+	var bodies []*closeRecorder
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		closed := false
+		body := &closeRecorder{ReadCloser: http.NoBody, closed: &closed}
+		bodies = append(bodies, body)
+		return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+	})
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+
+	// A CallInterceptor that calls next twice gets the last response, and the
+	// first is closed.
+	twice := func(ctx context.Context, call *Call, next CallHandler) error {
+		if err := next(ctx, call); err != nil {
+			return err
+		}
+		return next(ctx, call)
+	}
+	resp, err := FromCallInterceptor(twice)(transport).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if len(bodies) != 2 || resp.Body != bodies[1] || !*bodies[0].closed || *bodies[1].closed {
+		t.Errorf("Expected only the earlier response to be closed")
+	}
+
+	// One that never calls next fails the request instead of returning nothing.
+	never := func(context.Context, *Call, CallHandler) error { return nil }
+	resp, err = FromCallInterceptor(never)(transport).RoundTrip(req)
+	if resp != nil || !errors.Is(err, errNoResponse) {
+		t.Errorf("Expected errNoResponse, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,121 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+type attrsKey struct{}
+
+// WithAttrs returns a copy of ctx carrying attrs in addition to the attributes
+// ctx already carries. An attribute replaces an earlier one with the same key.
+//
+// Attributes describe a request for the interceptors that handle it, such as
+// the tenant it is made for or the name of the operation, and are read with
+// AttrsFrom. The Metrics interceptor reports them in RequestMetrics.Attrs, and
+// the Attrs interceptor can send them as headers.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := AttrsFrom(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, attr := range existing {
+		if !hasAttr(attrs, attr.Key) {
+			merged = append(merged, attr)
+		}
+	}
+	for i, attr := range attrs {
+		if !hasAttr(attrs[i+1:], attr.Key) {
+			merged = append(merged, attr)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// AttrsFrom returns the attributes carried by ctx, oldest first. The returned
+// slice must not be modified.
+func AttrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// AttrFrom returns the value of the attribute with the given key carried by
+// ctx, if any.
+func AttrFrom(ctx context.Context, key string) (slog.Value, bool) {
+	for _, attr := range AttrsFrom(ctx) {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// hasAttr reports whether attrs contains an attribute with the given key.
+func hasAttr(attrs []slog.Attr, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// AttrsOption configures the Attrs interceptor.
+type AttrsOption func(*attrsConfig)
+
+type attrsConfig struct {
+	derive  []func(*http.Request) []slog.Attr
+	headers map[string]string
+}
+
+// AttrsFunc adds the attributes returned by f for each request, for example an
+// operation name derived from its path. Attributes already carried by the
+// request context take precedence over the ones f returns.
+func AttrsFunc(f func(*http.Request) []slog.Attr) AttrsOption {
+	return func(c *attrsConfig) {
+		c.derive = append(c.derive, f)
+	}
+}
+
+// AttrsHeader sends the value of the attribute with the given key, when a
+// request carries it, in the named header.
+func AttrsHeader(key, header string) AttrsOption {
+	return func(c *attrsConfig) {
+		c.headers[key] = header
+	}
+}
+
+// Attrs returns an Interceptor that makes request attributes, set at call
+// sites with WithAttrs, available uniformly to the interceptors after it. It
+// adds the attributes configured with AttrsFunc to the request context and
+// sends those configured with AttrsHeader as headers.
+func Attrs(opts ...AttrsOption) Interceptor {
+	cfg := attrsConfig{headers: make(map[string]string)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			for _, derive := range cfg.derive {
+				var added []slog.Attr
+				for _, attr := range derive(req) {
+					if _, ok := AttrFrom(ctx, attr.Key); !ok {
+						added = append(added, attr)
+					}
+				}
+				if len(added) > 0 {
+					ctx = WithAttrs(ctx, added...)
+				}
+			}
+
+			req = req.Clone(ctx)
+			for key, header := range cfg.headers {
+				if value, ok := AttrFrom(ctx, key); ok {
+					req.Header.Set(header, value.String())
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
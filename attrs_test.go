@@ -0,0 +1,51 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestWithAttrs(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("tenant", "acme"), slog.String("realm", "alpha"))
+	ctx = WithAttrs(ctx, slog.String("realm", "bravo"), slog.Int("attempt", 1))
+
+	attrs := AttrsFrom(ctx)
+	if len(attrs) != 3 {
+		t.Fatalf("Expected 3 attributes, got %v", attrs)
+	}
+	if value, ok := AttrFrom(ctx, "realm"); !ok || value.String() != "bravo" {
+		t.Errorf("Expected the later attribute to replace the earlier one, got %v", value)
+	}
+	if _, ok := AttrFrom(context.Background(), "realm"); ok {
+		t.Errorf("Expected no attributes in an empty context")
+	}
+}
+
+func TestAttrsInterceptor(t *testing.T) {
+	var received *http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := Attrs(
+		AttrsFunc(func(req *http.Request) []slog.Attr {
+			return []slog.Attr{slog.String("operation", req.Method+" "+req.URL.Path), slog.String("tenant", "default")}
+		}),
+		AttrsHeader("tenant", "X-Tenant-ID"),
+	)(transport)
+
+	ctx := WithAttrs(context.Background(), slog.String("tenant", "acme"))
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/users", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if received.Header.Get("X-Tenant-ID") != "acme" {
+		t.Errorf("Expected the attribute from the call site to be sent, got %q", received.Header.Get("X-Tenant-ID"))
+	}
+	if value, _ := AttrFrom(received.Context(), "operation"); value.String() != "GET /users" {
+		t.Errorf("Expected the derived attribute to be added, got %q", value)
+	}
+}
@@ -0,0 +1,114 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BasicAuth returns an Interceptor that sends username and password with every
+// request using HTTP Basic authentication.
+func BasicAuth(username, password string) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = withHeader(req)
+			req.SetBasicAuth(username, password)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// BearerTokenOption configures the BearerToken interceptor.
+type BearerTokenOption func(*bearerTokenConfig)
+
+type bearerTokenConfig struct {
+	ttl time.Duration
+	now func() time.Time
+}
+
+// BearerTokenTTL caches the token returned by the token function for d, so it
+// is called at most once per period instead of on every request.
+func BearerTokenTTL(d time.Duration) BearerTokenOption {
+	return func(c *bearerTokenConfig) {
+		c.ttl = d
+	}
+}
+
+// BearerToken returns an Interceptor that sends the token returned by fn in
+// the Authorization header of every request. fn is called with the request's
+// context each time a token is needed, so credentials can rotate without
+// rebuilding the Pipeline. Use BearerTokenTTL to cache the token.
+//
+// If fn returns an error, the request fails with that error without being sent.
+func BearerToken(fn func(ctx context.Context) (string, error), opts ...BearerTokenOption) Interceptor {
+	cfg := bearerTokenConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var (
+		mu       sync.Mutex
+		token    string
+		expires  time.Time
+		fetching *bearerFetch
+	)
+	// tokenFor returns the cached token, or fetches a new one. Concurrent
+	// requests share a single call to fn, made without holding the lock and
+	// detached from the cancellation of the request that started it, and each
+	// stops waiting for it once its own context is done.
+	tokenFor := func(ctx context.Context) (string, error) {
+		if cfg.ttl <= 0 {
+			return fn(ctx)
+		}
+		mu.Lock()
+		if cached := token; cached != "" && cfg.now().Before(expires) {
+			mu.Unlock()
+			return cached, nil
+		}
+		f := fetching
+		if f == nil {
+			f = &bearerFetch{done: make(chan struct{})}
+			fetching = f
+			go func() {
+				fresh, err := fn(context.WithoutCancel(ctx))
+				mu.Lock()
+				if err == nil {
+					token, expires = fresh, cfg.now().Add(cfg.ttl)
+				}
+				fetching = nil
+				mu.Unlock()
+				f.token, f.err = fresh, err
+				close(f.done)
+			}()
+		}
+		mu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.token, f.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			token, err := tokenFor(req.Context())
+			if err != nil {
+				return nil, newError("BearerToken", req, err)
+			}
+			req = withHeader(req)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// bearerFetch is a call to the token function of BearerToken shared by the
+// requests waiting for it. token and err are set before done is closed.
+type bearerFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
@@ -0,0 +1,134 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBasicAuthInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := BasicAuth("alice", "s3cret")(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	user, pass, ok := mockRT.Request.BasicAuth()
+	if !ok || user != "alice" || pass != "s3cret" {
+		t.Errorf("Expected basic auth alice:s3cret, got %s:%s (%v)", user, pass, ok)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("Expected original request to be left unmodified")
+	}
+}
+
+func TestBearerTokenInterceptor(t *testing.T) {
+	now := time.Now()
+	calls := 0
+	fetch := func(ctx context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("token-%d", calls), nil
+	}
+
+	tests := []struct {
+		name     string
+		opts     []BearerTokenOption
+		expected []string
+	}{
+		{"no cache", nil, []string{"Bearer token-1", "Bearer token-2", "Bearer token-3"}},
+		{"ttl", []BearerTokenOption{BearerTokenTTL(time.Minute)}, []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}},
+	}
+
+	for _, test := range tests {
+		calls = 0
+		opts := append(test.opts, func(c *bearerTokenConfig) { c.now = func() time.Time { return now } })
+		mockRT := &mockRoundTripper{
+			Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+		}
+		rt := BearerToken(fetch, opts...)(mockRT)
+
+		for i, expected := range test.expected {
+			if i == 2 {
+				now = now.Add(2 * time.Minute)
+			}
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			if _, err := rt.RoundTrip(req); err != nil {
+				t.Fatalf("%s: failed to perform request: %v", test.name, err)
+			}
+			if got := mockRT.Request.Header.Get("Authorization"); got != expected {
+				t.Errorf("%s: request %d expected '%s', got '%s'", test.name, i, expected, got)
+			}
+		}
+	}
+}
+
+func TestBearerTokenInterceptorError(t *testing.T) {
+	fetchErr := errors.New("secret store unavailable")
+	mockRT := &mockRoundTripper{}
+	rt := BearerToken(func(context.Context) (string, error) { return "", fetchErr })(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, fetchErr) {
+		t.Errorf("Expected token error, got %v", err)
+	}
+	if mockRT.Request != nil {
+		t.Errorf("Expected request not to be sent")
+	}
+}
+
+func TestBearerTokenInterceptorBlockedFetch(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	fetch := func(ctx context.Context) (string, error) {
+		calls++
+		close(started)
+		<-release
+		return "token", nil
+	}
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := BearerToken(fetch, BearerTokenTTL(time.Minute))(mockRT)
+
+	first := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		_, err := rt.RoundTrip(req)
+		first <- err
+	}()
+	<-started
+
+	// A second request gives up on its own context while the fetch hangs.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := rt.RoundTrip(req)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the canceled request not to wait for the blocked fetch")
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if got := mockRT.Request.Header.Get("Authorization"); got != "Bearer token" || calls != 1 {
+		t.Errorf("Expected a single fetch of the token, got %q after %d calls", got, calls)
+	}
+}
@@ -0,0 +1,140 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// BandwidthOption configures the Bandwidth interceptor.
+type BandwidthOption func(*bandwidthConfig)
+
+type bandwidthConfig struct {
+	perHost  bool
+	upload   bool
+	download bool
+}
+
+// BandwidthPerHost makes all requests to the same host share their bandwidth,
+// instead of each request being limited on its own.
+func BandwidthPerHost() BandwidthOption {
+	return func(c *bandwidthConfig) {
+		c.perHost = true
+	}
+}
+
+// BandwidthUploadOnly limits request bodies only.
+func BandwidthUploadOnly() BandwidthOption {
+	return func(c *bandwidthConfig) {
+		c.upload, c.download = true, false
+	}
+}
+
+// BandwidthDownloadOnly limits response bodies only.
+func BandwidthDownloadOnly() BandwidthOption {
+	return func(c *bandwidthConfig) {
+		c.upload, c.download = false, true
+	}
+}
+
+// Bandwidth returns an Interceptor that limits the throughput of request and
+// response bodies to bytesPerSec in each direction, using a token bucket, so
+// that bulk transfers do not saturate shared links. Uploads and downloads are
+// limited separately, and by default every request has its own limit.
+//
+// Bodies are read in pieces of at most bytesPerSec bytes, and each read waits
+// until the bucket allows it. Waiting respects the request context. If
+// bytesPerSec is not positive, bodies are not limited.
+func Bandwidth(bytesPerSec int64, opts ...BandwidthOption) Interceptor {
+	cfg := bandwidthConfig{upload: true, download: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	burst := int(min(bytesPerSec, int64(maxBandwidthBurst)))
+	newLimiters := func() *bandwidthLimiters {
+		return &bandwidthLimiters{
+			upload:   rate.NewLimiter(rate.Limit(bytesPerSec), burst),
+			download: rate.NewLimiter(rate.Limit(bytesPerSec), burst),
+		}
+	}
+
+	var mu sync.Mutex
+	hosts := make(map[string]*bandwidthLimiters)
+	limitersFor := func(req *http.Request) *bandwidthLimiters {
+		if !cfg.perHost {
+			return newLimiters()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := hosts[req.URL.Host]
+		if !ok {
+			l = newLimiters()
+			hosts[req.URL.Host] = l
+		}
+		return l
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if bytesPerSec <= 0 {
+				return next.RoundTrip(req)
+			}
+			limiters := limitersFor(req)
+			ctx := req.Context()
+
+			if cfg.upload && req.Body != nil && req.Body != http.NoBody {
+				req = req.Clone(ctx)
+				req.Body = &bandwidthBody{ReadCloser: req.Body, ctx: ctx, limiter: limiters.upload}
+				if getBody := req.GetBody; getBody != nil {
+					req.GetBody = func() (io.ReadCloser, error) {
+						body, err := getBody()
+						if err != nil {
+							return nil, err
+						}
+						return &bandwidthBody{ReadCloser: body, ctx: ctx, limiter: limiters.upload}, nil
+					}
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || !cfg.download || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, err
+			}
+			resp.Body = &bandwidthBody{ReadCloser: resp.Body, ctx: ctx, limiter: limiters.download}
+			return resp, nil
+		})
+	}
+}
+
+// maxBandwidthBurst caps the size of a single read from a limited body, so
+// that high limits still pace transfers smoothly.
+const maxBandwidthBurst = 64 << 10
+
+// bandwidthLimiters holds the token buckets of one request or host.
+type bandwidthLimiters struct {
+	upload   *rate.Limiter
+	download *rate.Limiter
+}
+
+// bandwidthBody is a body whose reads are paced by limiter.
+type bandwidthBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func (b *bandwidthBody) Read(p []byte) (int, error) {
+	if burst := b.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.WaitN(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
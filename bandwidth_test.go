@@ -0,0 +1,46 @@
+package interceptor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBandwidthInterceptor(t *testing.T) {
+	payload := strings.Repeat("x", 15000)
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}, nil
+	})
+
+	tests := []struct {
+		name    string
+		opts    []BandwidthOption
+		minimum time.Duration
+	}{
+		{"both directions", nil, 900 * time.Millisecond},
+		{"upload only", []BandwidthOption{BandwidthUploadOnly()}, 400 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		rt := Bandwidth(10000, test.opts...)(transport)
+		start := time.Now()
+		req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader(payload))
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		elapsed := time.Since(start)
+
+		if string(body) != payload {
+			t.Errorf("%s: expected the body to be unchanged, got %d bytes", test.name, len(body))
+		}
+		if elapsed < test.minimum || elapsed > test.minimum+time.Second {
+			t.Errorf("%s: expected the transfer to take about %v, took %v", test.name, test.minimum, elapsed)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package interceptor
+
+import (
+	"net/http"
+	"testing"
+)
+
+// passThrough is an interceptor that does nothing but call the next transport.
+func passThrough(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return next.RoundTrip(req)
+	})
+}
+
+func benchmarkPipeline(b *testing.B, pipeline *Pipeline) {
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pipeline.RoundTrip(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var benchResponse = &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}
+
+var benchTransport = RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+	return benchResponse, nil
+})
+
+func BenchmarkPipelineEmpty(b *testing.B) {
+	benchmarkPipeline(b, New(benchTransport))
+}
+
+func BenchmarkPipelinePassThrough(b *testing.B) {
+	benchmarkPipeline(b, New(benchTransport, passThrough, passThrough, passThrough, passThrough))
+}
+
+func BenchmarkPipelineNamed(b *testing.B) {
+	pipeline := New(benchTransport)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		pipeline.UseNamed(name, passThrough)
+	}
+	benchmarkPipeline(b, pipeline)
+}
+
+func BenchmarkPipelineHooks(b *testing.B) {
+	pipeline := New(benchTransport, passThrough, passThrough)
+	pipeline.OnRequest(func(*http.Request) {})
+	pipeline.OnResponse(func(*http.Response) {})
+	benchmarkPipeline(b, pipeline)
+}
+
+// The header benchmarks cannot reach zero allocations: the caller's request
+// is left unmodified, so each request is copied once along with its header.
+func BenchmarkPipelineHeaders(b *testing.B) {
+	benchmarkPipeline(b, New(benchTransport,
+		Header("X-A", "a"),
+		Header("X-B", "b"),
+		Header("X-C", "c"),
+		Header("X-D", "d"),
+	))
+}
+
+func BenchmarkPipelineHeadersMap(b *testing.B) {
+	benchmarkPipeline(b, New(benchTransport,
+		Headers(map[string]string{"X-A": "a", "X-B": "b", "X-C": "c", "X-D": "d"}),
+	))
+}
@@ -0,0 +1,81 @@
+package interceptor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// BufferBody returns an Interceptor that reads each request body whose
+// GetBody is unset and sends a copy of the request whose GetBody replays it,
+// so that interceptors sending requests more than once, such as Retry, Hedge,
+// and Failover, can do so with bodies created from one-shot readers. It must
+// come before those interceptors in the chain.
+//
+// Bodies of up to maxBytes are held in memory, and larger ones are written to
+// a temporary file that is removed once the response body is closed, or when
+// the request fails. The request's ContentLength is set to the size of the
+// body.
+func BufferBody(maxBytes int64) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+				return next.RoundTrip(req)
+			}
+
+			getBody, size, cleanup, err := bufferBody(req.Body, maxBytes)
+			req.Body.Close()
+			if err != nil {
+				return nil, newError("BufferBody", req, err)
+			}
+			req = req.Clone(req.Context())
+			req.GetBody = getBody
+			req.Body, _ = getBody()
+			req.ContentLength = size
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: sync.OnceFunc(cleanup)}
+			return resp, nil
+		})
+	}
+}
+
+// bufferBody reads body into memory, or into a temporary file once it grows
+// beyond maxBytes. It returns a function opening a new reader over the
+// contents, their size, and a function releasing them.
+func bufferBody(body io.Reader, maxBytes int64) (func() (io.ReadCloser, error), int64, func(), error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if n <= maxBytes {
+		data := buf.Bytes()
+		return func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}, n, func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "interceptor-body-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	size, err := io.Copy(f, io.MultiReader(&buf, body))
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(f, 0, size)), nil
+	}, size, cleanup, nil
+}
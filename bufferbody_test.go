@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBufferBodyInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		body     string
+		files    int
+	}{
+		{"in memory", 1024, "hello world", 0},
+		{"in a temporary file", 4, "hello world", 1},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		t.Setenv("TMPDIR", dir)
+
+		var bodies []string
+		var files int
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.GetBody == nil || req.ContentLength != int64(len(test.body)) {
+				t.Fatalf("%s: expected GetBody and ContentLength to be set, got %d", test.name, req.ContentLength)
+			}
+			for i := 0; i < 2; i++ {
+				data, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(data))
+				req.Body, _ = req.GetBody()
+			}
+			entries, _ := os.ReadDir(dir)
+			files = len(entries)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+
+		// http.NewRequest cannot replay a MultiReader, so GetBody is unset.
+		req, _ := http.NewRequest("POST", "http://example.com", io.MultiReader(strings.NewReader(test.body)))
+		resp, err := BufferBody(test.maxBytes)(transport).RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if len(bodies) != 2 || bodies[0] != test.body || bodies[1] != test.body {
+			t.Errorf("%s: expected the body to be replayable, got %q", test.name, bodies)
+		}
+		if files != test.files {
+			t.Errorf("%s: expected %d temporary files, got %d", test.name, test.files, files)
+		}
+
+		resp.Body.Close()
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: expected temporary files to be removed, got %d", test.name, len(entries))
+		}
+	}
+}
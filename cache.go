@@ -0,0 +1,515 @@
+package interceptor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned by the Cache interceptor when it is offline and
+// has no stored response for a request.
+var ErrCacheMiss = errors.New("interceptor: response not in cache")
+
+// Values of the X-Cache header set by the Cache interceptor.
+const (
+	// CacheHit marks a fresh stored response, or one revalidated with the
+	// server.
+	CacheHit = "HIT"
+	// CacheStale marks a stale stored response served without the server's
+	// confirmation.
+	CacheStale = "STALE"
+	// CacheMiss marks a response received from the server.
+	CacheMiss = "MISS"
+)
+
+// CacheOption configures the Cache interceptor.
+type CacheOption func(*cacheConfig)
+
+type cacheConfig struct {
+	offline           func() bool
+	maxBodySize       int64
+	revalidateTimeout time.Duration
+}
+
+// CacheOffline enables offline mode. While offline reports true, requests are
+// answered from the store alone, with stale responses served as they are and
+// missing ones failing with ErrCacheMiss. At other times, a stored response of
+// any age is served when the request cannot be sent, for example because the
+// network is unreachable. If offline is nil, only the latter applies.
+func CacheOffline(offline func() bool) CacheOption {
+	return func(c *cacheConfig) {
+		if offline == nil {
+			offline = func() bool { return false }
+		}
+		c.offline = offline
+	}
+}
+
+// CacheMaxBodySize sets the size of the largest response body the cache
+// stores, since bodies are held in memory while they are stored. Larger
+// responses are streamed to the caller without being stored. The default is
+// 10 MiB, and zero or less means no limit.
+func CacheMaxBodySize(n int64) CacheOption {
+	return func(c *cacheConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// CacheRevalidateTimeout sets the longest a background revalidation, made for
+// stale-while-revalidate, may take before it is canceled. The default is 30
+// seconds.
+func CacheRevalidateTimeout(d time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		if d > 0 {
+			c.revalidateTimeout = d
+		}
+	}
+}
+
+// Cache returns an Interceptor that caches GET and HEAD responses in store
+// following HTTP caching semantics for a private cache.
+//
+// Responses are stored when their Cache-Control and Expires headers allow it,
+// they carry either an explicit freshness lifetime or a validator (ETag or
+// Last-Modified), and their body is no larger than CacheMaxBodySize. Fresh
+// responses are served without contacting the server. Stale responses with a
+// validator are revalidated with a conditional request, and a 304 Not Modified
+// answer refreshes the stored copy. Responses to successful unsafe requests
+// (POST, PUT, PATCH, DELETE) invalidate the cached entries for their URL.
+//
+// The stale-while-revalidate and stale-if-error extensions of RFC 5861 are
+// supported: a response stale by less than its stale-while-revalidate period
+// is served while it is revalidated in the background, or fetched again if it
+// has no validator, within CacheRevalidateTimeout, and one stale by less
+// than its stale-if-error period, given in the response or the request, is
+// served when the server cannot be reached or answers with a 5xx status.
+//
+// Every response passing through the cache carries an X-Cache header of
+// CacheHit, CacheStale, or CacheMiss, replacing any sent by the server.
+//
+// Requests with Cache-Control: no-store bypass the cache, and requests with
+// Cache-Control: no-cache are always revalidated. Requests that already carry
+// conditional headers are passed through untouched, as are streaming requests
+// and responses, as reported by IsStreamingRequest and IsStreamingResponse.
+//
+// Pipeline.Shutdown cancels background revalidations and waits for them to
+// return. It then closes store if it implements io.Closer, so the Pipeline
+// takes ownership of such a store: wrap it in a type without a Close method
+// to keep using it after Shutdown.
+func Cache(store CacheStore, opts ...CacheOption) Interceptor {
+	return newCache(store, time.Now, opts...).interceptor
+}
+
+type httpCache struct {
+	store CacheStore
+	now   func() time.Time
+	cfg   cacheConfig
+
+	// revalidating holds the keys being revalidated in the background.
+	mu           sync.Mutex
+	revalidating map[string]bool
+	background   *background
+}
+
+func newCache(store CacheStore, now func() time.Time, opts ...CacheOption) *httpCache {
+	c := &httpCache{
+		store:        store,
+		now:          now,
+		cfg:          cacheConfig{maxBodySize: 10 << 20, revalidateTimeout: 30 * time.Second},
+		revalidating: make(map[string]bool),
+		background:   newBackground(),
+	}
+	for _, opt := range opts {
+		opt(&c.cfg)
+	}
+	return c
+}
+
+// cacheEntry is the serialized form of a response held in a CacheStore.
+type cacheEntry struct {
+	// StoredAt is when the response was received or last revalidated.
+	StoredAt time.Time `json:"stored_at"`
+	// Vary holds the request header values named by the response's Vary header.
+	Vary http.Header `json:"vary,omitempty"`
+	// Response is the response in HTTP/1.1 wire format.
+	Response []byte `json:"response"`
+}
+
+func (c *httpCache) interceptor(next http.RoundTripper) http.RoundTripper {
+	return withClose(c.transport(next), func() error {
+		c.background.Close()
+		if closer, ok := c.store.(io.Closer); ok {
+			return closer.Close()
+		}
+		return nil
+	})
+}
+
+func (c *httpCache) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if IsPreparing(req.Context()) {
+			return next.RoundTrip(req)
+		}
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			resp, err := next.RoundTrip(req)
+			if err == nil && isUnsafeMethod(req.Method) && resp.StatusCode < http.StatusBadRequest {
+				c.store.Delete(cacheKey(http.MethodGet, req))
+				c.store.Delete(cacheKey(http.MethodHead, req))
+			}
+			return resp, err
+		}
+
+		directives := parseCacheControl(req.Header)
+		if _, ok := directives["no-store"]; ok || isConditional(req) || IsStreamingRequest(req) {
+			return next.RoundTrip(req)
+		}
+
+		key := cacheKey(req.Method, req)
+		offline := c.cfg.offline != nil && c.cfg.offline()
+		entry, cached, err := c.load(key, req)
+		if err != nil || cached == nil {
+			if offline {
+				return nil, newError("Cache", req, ErrCacheMiss)
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			resp, err = c.save(key, req, resp)
+			return withCacheStatus(resp, CacheMiss), err
+		}
+
+		now := c.now()
+		age, lifetime := entry.age(cached, now), freshnessLifetime(cached.Header)
+		_, noCache := directives["no-cache"]
+		switch {
+		case !noCache && age < lifetime:
+			return entry.serve(cached, now, CacheHit), nil
+		case offline:
+			return entry.serve(cached, now, CacheStale), nil
+		case !noCache && age < lifetime+cacheDirectiveSeconds(cached.Header, "stale-while-revalidate"):
+			c.revalidateInBackground(next, key, req)
+			return entry.serve(cached, now, CacheStale), nil
+		}
+
+		resp, status, err := c.revalidate(next, key, req, cached)
+		if (err != nil || resp.StatusCode >= http.StatusInternalServerError) && c.canServeStale(req, cached, age, lifetime, err) {
+			if resp != nil {
+				discard(resp)
+			}
+			return entry.serve(cached, now, CacheStale), nil
+		}
+		if status != CacheHit {
+			cached.Body.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return withCacheStatus(resp, status), nil
+	})
+}
+
+// revalidate sends req, made conditional on the validators of the stored
+// response cached, and stores the outcome. It returns the response to serve
+// and whether it is cached itself, refreshed by a 304 Not Modified answer
+// (CacheHit), or a new response from the server (CacheMiss).
+func (c *httpCache) revalidate(next http.RoundTripper, key string, req *http.Request, cached *http.Response) (*http.Response, string, error) {
+	conditional := req
+	etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		conditional = req.Clone(req.Context())
+		if etag != "" {
+			conditional.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			conditional.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	resp, err := next.RoundTrip(conditional)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusNotModified || conditional == req {
+		resp, err = c.save(key, req, resp)
+		return resp, CacheMiss, err
+	}
+
+	// The stored response is still valid: merge the new headers into it.
+	discard(resp)
+	cached.Header.Del("Age")
+	for name, values := range resp.Header {
+		if name != "Content-Length" {
+			cached.Header[name] = values
+		}
+	}
+	resp, err = c.save(key, req, cached)
+	return resp, CacheHit, err
+}
+
+// revalidateInBackground revalidates the response stored under key for req
+// without delaying the caller, unless it is already being revalidated. The
+// revalidation outlives the caller's request but is bounded by the configured
+// timeout, and canceled by Pipeline.Shutdown.
+func (c *httpCache) revalidateInBackground(next http.RoundTripper, key string, req *http.Request) {
+	c.mu.Lock()
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	ctx, cancel := c.background.detach(req.Context())
+	ctx, cancelTimeout := context.WithTimeout(ctx, c.cfg.revalidateTimeout)
+	req = req.Clone(ctx)
+	c.background.run(func() {
+		defer cancel(nil)
+		defer cancelTimeout()
+		defer func() {
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+		_, cached, err := c.load(key, req)
+		if err != nil || cached == nil {
+			return
+		}
+		resp, _, err := c.revalidate(next, key, req, cached)
+		if err == nil {
+			discard(resp)
+		}
+	})
+}
+
+// canServeStale reports whether the stored response cached, of the given age
+// and freshness lifetime, may be served in place of a failed request: within
+// its stale-if-error period, or at any age in offline mode if the request
+// could not be sent.
+func (c *httpCache) canServeStale(req *http.Request, cached *http.Response, age, lifetime time.Duration, err error) bool {
+	if err != nil && req.Context().Err() != nil {
+		return false
+	}
+	if err != nil && c.cfg.offline != nil {
+		return true
+	}
+	staleIfError := max(cacheDirectiveSeconds(cached.Header, "stale-if-error"), cacheDirectiveSeconds(req.Header, "stale-if-error"))
+	return age < lifetime+staleIfError
+}
+
+// withCacheStatus sets the X-Cache header of resp to status.
+func withCacheStatus(resp *http.Response, status string) *http.Response {
+	if resp != nil {
+		resp.Header.Set("X-Cache", status)
+	}
+	return resp
+}
+
+// load returns the entry stored under key and the response it holds, if one
+// exists and matches the request's Vary headers.
+func (c *httpCache) load(key string, req *http.Request) (*cacheEntry, *http.Response, error) {
+	data, ok := c.store.Get(key)
+	if !ok {
+		return nil, nil, nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, err
+	}
+	for name, values := range entry.Vary {
+		if !slices.Equal(values, req.Header.Values(name)) {
+			return nil, nil, nil
+		}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(entry.Response)), req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &entry, resp, nil
+}
+
+// save stores resp under key if it is cacheable and returns a response with an
+// unread body for the caller.
+func (c *httpCache) save(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	limit := c.cfg.maxBodySize
+	if !isStorable(resp) || IsStreamingResponse(resp) || limit > 0 && resp.ContentLength > limit {
+		return resp, nil
+	}
+
+	var r io.Reader = resp.Body
+	if limit > 0 {
+		r = io.LimitReader(resp.Body, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		resp.Body.Close()
+		return nil, newError("Cache", req, err)
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		// The body is too large to store: give the caller the part already
+		// read followed by the rest.
+		original := resp.Body
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	stored := *resp
+	stored.Request = req
+	stored.TransferEncoding = nil
+	if req.Method != http.MethodHead {
+		stored.ContentLength = int64(len(body))
+	}
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	var wire bytes.Buffer
+	if err := stored.Write(&wire); err != nil {
+		return resp, nil
+	}
+
+	entry := cacheEntry{StoredAt: c.now(), Response: wire.Bytes()}
+	for _, name := range headerTokens(resp.Header, "Vary") {
+		if entry.Vary == nil {
+			entry.Vary = make(http.Header)
+		}
+		name = http.CanonicalHeaderKey(name)
+		entry.Vary[name] = req.Header.Values(name)
+	}
+	if data, err := json.Marshal(entry); err == nil {
+		c.store.Set(key, data)
+	}
+	return resp, nil
+}
+
+// age returns the current age of a stored response.
+func (e *cacheEntry) age(resp *http.Response, now time.Time) time.Duration {
+	age := now.Sub(e.StoredAt)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// serve prepares a stored response to be returned to the caller with the
+// given cache status.
+func (e *cacheEntry) serve(resp *http.Response, now time.Time, status string) *http.Response {
+	resp.Header.Set("Age", strconv.Itoa(int(e.age(resp, now).Seconds())))
+	return withCacheStatus(resp, status)
+}
+
+// cacheKey identifies the cached response for method and the request's URL.
+func cacheKey(method string, req *http.Request) string {
+	return method + " " + req.URL.String()
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isConditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isStorable reports whether resp may be stored by a private cache and is
+// useful to store, meaning it is either fresh for some time or can be
+// revalidated.
+func isStorable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusGone:
+	default:
+		return false
+	}
+	if _, ok := parseCacheControl(resp.Header)["no-store"]; ok {
+		return false
+	}
+	if slices.Contains(headerTokens(resp.Header, "Vary"), "*") {
+		return false
+	}
+	return freshnessLifetime(resp.Header) > 0 ||
+		resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != ""
+}
+
+// freshnessLifetime returns how long a response with the given headers may be
+// served from the cache without revalidation.
+func freshnessLifetime(h http.Header) time.Duration {
+	directives := parseCacheControl(h)
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if h.Get("Expires") != "" {
+		expires, err := http.ParseTime(h.Get("Expires"))
+		if err != nil {
+			return 0
+		}
+		date, err := http.ParseTime(h.Get("Date"))
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	return 0
+}
+
+// cacheDirectiveSeconds returns the duration given in seconds by the
+// Cache-Control directive name, or zero if it is absent or invalid.
+func cacheDirectiveSeconds(h http.Header, name string) time.Duration {
+	seconds, err := strconv.Atoi(parseCacheControl(h)[name])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseCacheControl parses the Cache-Control header into a map of lowercase
+// directive names to their (possibly empty) values.
+func parseCacheControl(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range headerTokens(h, "Cache-Control") {
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
+
+// headerTokens splits all values of a comma-separated header into trimmed,
+// non-empty tokens.
+func headerTokens(h http.Header, name string) []string {
+	var tokens []string
+	for _, value := range h.Values(name) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
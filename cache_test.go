@@ -0,0 +1,419 @@
+package interceptor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// cacheOrigin is a fake server whose responses are built by the test.
+type cacheOrigin struct {
+	calls    int
+	requests []*http.Request
+	respond  func(req *http.Request) *http.Response
+}
+
+func (o *cacheOrigin) RoundTrip(req *http.Request) (*http.Response, error) {
+	o.calls++
+	o.requests = append(o.requests, req)
+	return o.respond(req), nil
+}
+
+func cachedResponse(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	resp.Body.Close()
+	return string(body)
+}
+
+func TestCacheInterceptorServesFreshResponses(t *testing.T) {
+	now := time.Now()
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		return cachedResponse(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, "hello")
+	}}
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if body := readBody(t, resp); body != "hello" {
+			t.Errorf("Expected body 'hello', got '%s'", body)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	if origin.calls != 1 {
+		t.Errorf("Expected 1 call to the origin, got %d", origin.calls)
+	}
+
+	// Once stale, the response is fetched again.
+	now = now.Add(time.Minute)
+	req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if origin.calls != 2 {
+		t.Errorf("Expected stale response to be refetched, got %d calls", origin.calls)
+	}
+}
+
+func TestCacheInterceptorRevalidates(t *testing.T) {
+	now := time.Now()
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return cachedResponse(http.StatusNotModified, http.Header{"X-Revalidated": {"yes"}}, "")
+		}
+		return cachedResponse(http.StatusOK, http.Header{"Etag": {`"v1"`}}, "hello")
+	}}
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if body := readBody(t, resp); body != "hello" {
+			t.Errorf("Expected body 'hello', got '%s'", body)
+		}
+		if i == 1 && resp.Header.Get("X-Revalidated") != "yes" {
+			t.Errorf("Expected headers from 304 response to be merged")
+		}
+	}
+	if origin.calls != 2 {
+		t.Errorf("Expected 2 calls to the origin, got %d", origin.calls)
+	}
+	if got := origin.requests[1].Header.Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("Expected conditional request with If-None-Match, got '%s'", got)
+	}
+}
+
+func TestCacheInterceptorBypass(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		requestCC    string
+		responseCC   string
+		expectedHits int
+	}{
+		{"response no-store", "GET", "", "no-store, max-age=60", 2},
+		{"request no-store", "GET", "no-store", "max-age=60", 2},
+		{"request no-cache", "GET", "no-cache", "max-age=60", 2},
+		{"uncacheable method", "POST", "", "max-age=60", 2},
+		{"head is cached", "HEAD", "", "max-age=60", 1},
+	}
+
+	for _, test := range tests {
+		origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+			return cachedResponse(http.StatusOK, http.Header{"Cache-Control": {test.responseCC}}, "")
+		}}
+		rt := Cache(NewMemoryCacheStore(10))(origin)
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(test.method, "http://example.com", nil)
+			if test.requestCC != "" {
+				req.Header.Set("Cache-Control", test.requestCC)
+			}
+			if _, err := rt.RoundTrip(req); err != nil {
+				t.Fatalf("%s: failed to perform request: %v", test.name, err)
+			}
+		}
+		if origin.calls != test.expectedHits {
+			t.Errorf("%s: expected %d calls to the origin, got %d", test.name, test.expectedHits, origin.calls)
+		}
+	}
+}
+
+func TestCacheInterceptorInvalidatesOnUnsafeMethods(t *testing.T) {
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		return cachedResponse(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, "")
+	}}
+	store := NewMemoryCacheStore(10)
+	rt := Cache(store)(origin)
+
+	for _, method := range []string{"GET", "PUT", "GET"} {
+		req, _ := http.NewRequest(method, "http://example.com/resource", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+	if origin.calls != 3 {
+		t.Errorf("Expected PUT to invalidate the cached GET, got %d calls", origin.calls)
+	}
+}
+
+func TestCacheInterceptorVary(t *testing.T) {
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		header := http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept"}}
+		return cachedResponse(http.StatusOK, header, req.Header.Get("Accept"))
+	}}
+	rt := Cache(NewMemoryCacheStore(10))(origin)
+
+	for _, accept := range []string{"text/plain", "text/plain", "application/json"} {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("Accept", accept)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if body := readBody(t, resp); body != accept {
+			t.Errorf("Expected body '%s', got '%s'", accept, body)
+		}
+	}
+	if origin.calls != 2 {
+		t.Errorf("Expected 2 calls to the origin, got %d", origin.calls)
+	}
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header   http.Header
+		expected time.Duration
+	}{
+		{http.Header{"Cache-Control": {"max-age=120"}}, 2 * time.Minute},
+		{http.Header{"Cache-Control": {"public, max-age=\"30\""}}, 30 * time.Second},
+		{http.Header{"Cache-Control": {"no-cache, max-age=120"}}, 0},
+		{http.Header{
+			"Date":    {date.Format(http.TimeFormat)},
+			"Expires": {date.Add(time.Hour).Format(http.TimeFormat)},
+		}, time.Hour},
+		{http.Header{"Expires": {"0"}}, 0},
+		{http.Header{}, 0},
+	}
+
+	for _, test := range tests {
+		if got := freshnessLifetime(test.header); got != test.expected {
+			t.Errorf("Expected lifetime %v for %v, got %v", test.expected, test.header, got)
+		}
+	}
+}
+
+func TestCacheInterceptorStatusHeader(t *testing.T) {
+	now := time.Now()
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return cachedResponse(http.StatusNotModified, nil, "")
+		}
+		return cachedResponse(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}}, "hello")
+	}}
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	for _, expected := range []string{CacheMiss, CacheHit, CacheHit} {
+		req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if got := resp.Header.Get("X-Cache"); got != expected {
+			t.Errorf("Expected X-Cache %s, got %s", expected, got)
+		}
+		readBody(t, resp)
+		now = now.Add(45 * time.Second)
+	}
+	if origin.calls != 2 {
+		t.Errorf("Expected the stale response to be revalidated, got %d calls", origin.calls)
+	}
+}
+
+func TestCacheInterceptorStaleWhileRevalidate(t *testing.T) {
+	now := time.Now()
+	revalidated := make(chan *http.Request, 1)
+	version := "v1"
+	origin := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("If-None-Match") != "" {
+			revalidated <- req
+		}
+		header := http.Header{"Cache-Control": {"max-age=10, stale-while-revalidate=60"}, "Etag": {`"` + version + `"`}}
+		return cachedResponse(http.StatusOK, header, version), nil
+	})
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	resp, _ := rt.RoundTrip(req)
+	readBody(t, resp)
+
+	version = "v2"
+	now = now.Add(30 * time.Second)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if body := readBody(t, resp); body != "v1" || resp.Header.Get("X-Cache") != CacheStale {
+		t.Errorf("Expected the stale response to be served, got %s (%s)", body, resp.Header.Get("X-Cache"))
+	}
+
+	select {
+	case bg := <-revalidated:
+		if bg.Header.Get("If-None-Match") != `"v1"` {
+			t.Errorf("Expected a conditional revalidation, got %v", bg.Header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the response to be revalidated in the background")
+	}
+}
+
+func TestCacheInterceptorStaleWhileRevalidateWithoutValidator(t *testing.T) {
+	now := time.Now()
+	refetched := make(chan *http.Request, 1)
+	calls := 0
+	origin := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if calls++; calls > 1 {
+			refetched <- req
+		}
+		header := http.Header{"Cache-Control": {"max-age=10, stale-while-revalidate=60"}}
+		return cachedResponse(http.StatusOK, header, "hello"), nil
+	})
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	resp, _ := rt.RoundTrip(req)
+	readBody(t, resp)
+
+	now = now.Add(30 * time.Second)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if readBody(t, resp); resp.Header.Get("X-Cache") != CacheStale {
+		t.Errorf("Expected the stale response to be served, got %s", resp.Header.Get("X-Cache"))
+	}
+
+	select {
+	case bg := <-refetched:
+		if isConditional(bg) {
+			t.Errorf("Expected an unconditional refetch, got %v", bg.Header)
+		}
+		if _, ok := bg.Context().Deadline(); !ok {
+			t.Errorf("Expected the background refetch to have a deadline")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the response to be fetched again in the background")
+	}
+}
+
+func TestCacheInterceptorStaleIfError(t *testing.T) {
+	now := time.Now()
+	status := http.StatusOK
+	origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+		return cachedResponse(status, http.Header{"Cache-Control": {"max-age=10, stale-if-error=60"}}, "hello")
+	}}
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor(origin)
+
+	req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	resp, _ := rt.RoundTrip(req)
+	readBody(t, resp)
+
+	status = http.StatusServiceUnavailable
+	tests := []struct {
+		after          time.Duration
+		expectedStatus int
+		expectedCache  string
+	}{
+		{30 * time.Second, http.StatusOK, CacheStale},
+		{time.Minute, http.StatusServiceUnavailable, CacheMiss},
+	}
+	for _, test := range tests {
+		now = now.Add(test.after)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		readBody(t, resp)
+		if resp.StatusCode != test.expectedStatus || resp.Header.Get("X-Cache") != test.expectedCache {
+			t.Errorf("Expected %d (%s), got %d (%s)", test.expectedStatus, test.expectedCache, resp.StatusCode, resp.Header.Get("X-Cache"))
+		}
+	}
+}
+
+func TestCacheInterceptorOffline(t *testing.T) {
+	now := time.Now()
+	offline := false
+	var failure error
+	origin := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failure != nil {
+			return nil, failure
+		}
+		return cachedResponse(http.StatusOK, http.Header{"Cache-Control": {"max-age=10"}}, "hello"), nil
+	})
+	rt := newCache(NewMemoryCacheStore(10), func() time.Time { return now }, CacheOffline(func() bool { return offline })).interceptor(origin)
+
+	req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+	resp, _ := rt.RoundTrip(req)
+	readBody(t, resp)
+
+	offline = true
+	now = now.Add(time.Hour)
+	resp, err := rt.RoundTrip(req)
+	if err != nil || readBody(t, resp) != "hello" || resp.Header.Get("X-Cache") != CacheStale {
+		t.Errorf("Expected the stored response while offline, got %v", err)
+	}
+	other, _ := http.NewRequest("GET", "http://example.com/other", nil)
+	if _, err := rt.RoundTrip(other); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss while offline, got %v", err)
+	}
+
+	offline = false
+	failure = errors.New("network is unreachable")
+	resp, err = rt.RoundTrip(req)
+	if err != nil || readBody(t, resp) != "hello" {
+		t.Errorf("Expected the stored response when the network fails, got %v", err)
+	}
+}
+
+func TestCacheInterceptorMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		{"known length", 16},
+		{"unknown length", -1},
+	}
+
+	for _, test := range tests {
+		origin := &cacheOrigin{respond: func(req *http.Request) *http.Response {
+			resp := cachedResponse(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, "sixteen byte bod")
+			resp.ContentLength = test.contentLength
+			return resp
+		}}
+		store := NewMemoryCacheStore(10)
+		rt := Cache(store, CacheMaxBodySize(8))(origin)
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest("GET", "http://example.com/large", nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("%s: Failed to perform request: %v", test.name, err)
+			}
+			if body := readBody(t, resp); body != "sixteen byte bod" {
+				t.Errorf("%s: Expected the whole body, got %q", test.name, body)
+			}
+		}
+		if store.Len() != 0 || origin.calls != 2 {
+			t.Errorf("%s: Expected the large response not to be stored, got %d entries and %d calls", test.name, store.Len(), origin.calls)
+		}
+	}
+}
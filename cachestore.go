@@ -0,0 +1,94 @@
+package interceptor
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStore is the storage backend used by the Cache interceptor. Keys are
+// derived from the request and values are opaque serialized responses, so any
+// key-value store such as Redis or a directory on disk can be used.
+//
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key, replacing any existing value.
+	Set(key string, value []byte)
+	// Delete removes the value stored under key, if any.
+	Delete(key string)
+}
+
+// MemoryCacheStore is an in-memory CacheStore that evicts the least recently
+// used entry once it holds more than its capacity.
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStore returns a MemoryCacheStore that holds at most capacity
+// entries. A capacity of zero or less means the store is unbounded.
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key and marks it as recently used.
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).value, true
+}
+
+// Set stores value under key, evicting the least recently used entry if the
+// store is full.
+func (s *MemoryCacheStore) Set(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).value = value
+		s.order.MoveToFront(e)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&memoryCacheEntry{key: key, value: value})
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete removes the value stored under key, if any.
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		s.order.Remove(e)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of entries in the store.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
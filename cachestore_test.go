@@ -0,0 +1,32 @@
+package interceptor
+
+import "testing"
+
+func TestMemoryCacheStore(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+
+	store.Set("a", []byte("1"))
+	store.Set("b", []byte("2"))
+	// Reading "a" makes "b" the least recently used entry.
+	if got, ok := store.Get("a"); !ok || string(got) != "1" {
+		t.Errorf("Expected 'a' to be '1', got '%s' (found %v)", got, ok)
+	}
+	store.Set("c", []byte("3"))
+
+	if _, ok := store.Get("b"); ok {
+		t.Errorf("Expected 'b' to be evicted")
+	}
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", store.Len())
+	}
+
+	store.Set("a", []byte("4"))
+	if got, _ := store.Get("a"); string(got) != "4" {
+		t.Errorf("Expected 'a' to be replaced with '4', got '%s'", got)
+	}
+
+	store.Delete("a")
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("Expected 'a' to be deleted")
+	}
+}
@@ -0,0 +1,262 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CanaryOption configures the Canary interceptor.
+type CanaryOption func(*canaryConfig)
+
+type canaryConfig struct {
+	shadow        bool
+	allMethods    bool
+	tolerance     float64
+	shadowTimeout time.Duration
+	onDivergence  func(CanaryResult)
+}
+
+// CanaryShadow sends the selected requests to the new base URL in addition to
+// their original destination, instead of in its place. The original response
+// is returned to the caller and the canary response is only compared with it,
+// so the new endpoint can be evaluated without affecting callers. Only
+// requests for which IsIdempotent reports true are shadowed, unless
+// CanaryAllMethods is given, and requests with a body are only shadowed if
+// req.GetBody is set.
+//
+// Shadow requests outlive the caller's request, so they are not canceled with
+// it. Instead, a shadow request still running once it is slower than the
+// primary by the CanaryLatencyTolerance factor is canceled and reported as a
+// latency divergence, and none runs longer than CanaryShadowTimeout.
+func CanaryShadow() CanaryOption {
+	return func(c *canaryConfig) {
+		c.shadow = true
+	}
+}
+
+// CanaryAllMethods allows requests that are not idempotent to be shadowed. Only
+// use it when sending such requests to both endpoints is harmless.
+func CanaryAllMethods() CanaryOption {
+	return func(c *canaryConfig) {
+		c.allMethods = true
+	}
+}
+
+// CanaryLatencyTolerance sets how many times slower than the primary the
+// canary may be before the difference is reported. The default is 2, and zero
+// disables latency comparisons.
+func CanaryLatencyTolerance(factor float64) CanaryOption {
+	return func(c *canaryConfig) {
+		c.tolerance = factor
+	}
+}
+
+// CanaryShadowTimeout sets the longest a shadow request may run before it is
+// canceled. The default is 30 seconds.
+func CanaryShadowTimeout(d time.Duration) CanaryOption {
+	return func(c *canaryConfig) {
+		if d > 0 {
+			c.shadowTimeout = d
+		}
+	}
+}
+
+// CanaryOnDivergence sets the function called with every comparison in which
+// the canary differs from the primary. It may be called concurrently, and in
+// shadow mode after the request has completed.
+func CanaryOnDivergence(f func(CanaryResult)) CanaryOption {
+	return func(c *canaryConfig) {
+		c.onDivergence = f
+	}
+}
+
+// CanaryOutcome describes how one endpoint answered a request.
+type CanaryOutcome struct {
+	// StatusCode is the response status code, or zero if the request failed.
+	StatusCode int
+	// Latency is the time until the response header was received.
+	Latency time.Duration
+	// Err is the error returned by the transport, if any. It is
+	// context.DeadlineExceeded for a shadow request canceled for taking too
+	// long.
+	Err error
+}
+
+// failed reports whether the outcome is a transport error or a 5xx response.
+func (o CanaryOutcome) failed() bool {
+	return o.Err != nil || o.StatusCode >= http.StatusInternalServerError
+}
+
+// CanaryResult compares the primary and canary endpoints for one request.
+type CanaryResult struct {
+	// Request is the caller's request.
+	Request *http.Request
+	// Shadowed reports whether the request was sent to both endpoints. If it
+	// was not, Primary holds only the primary's average Latency over recent
+	// requests.
+	Shadowed bool
+	// Primary is the outcome of the original destination.
+	Primary CanaryOutcome
+	// Canary is the outcome of the new base URL.
+	Canary CanaryOutcome
+	// StatusDiverged reports whether the canary answered with a different
+	// status code than the primary or, if the request was not shadowed, failed.
+	StatusDiverged bool
+	// LatencyDiverged reports whether the canary was slower than the primary
+	// by more than the latency tolerance.
+	LatencyDiverged bool
+}
+
+// Canary returns an Interceptor that routes percent percent of requests to
+// newBase instead of their original destination, to migrate gradually between
+// endpoints such as two versions of an API gateway. The scheme and host of the
+// request URL are replaced with newBase's and its path is joined to newBase's
+// path, as with Failover.
+//
+// Each request sent to the canary is compared with the primary: it diverges
+// if it fails with a transport error or a 5xx status, or if it is slower than
+// the primary's average latency by more than the CanaryLatencyTolerance
+// factor. With CanaryShadow the selected requests are sent to both endpoints
+// and compared directly. Divergences are reported to the function given with
+// CanaryOnDivergence.
+//
+// Pipeline.Shutdown cancels the shadow requests still in flight, without
+// reporting them, and waits for them to return.
+func Canary(newBase url.URL, percent int, opts ...CanaryOption) Interceptor {
+	cfg := canaryConfig{tolerance: 2, shadowTimeout: 30 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	var average time.Duration
+	// observe folds the latency of a primary response into its moving average.
+	observe := func(latency time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if average == 0 {
+			average = latency
+		} else {
+			average += (latency - average) / 5
+		}
+	}
+	averageLatency := func() time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return average
+	}
+	report := func(result CanaryResult) {
+		if cfg.tolerance > 0 && result.Primary.Latency > 0 && result.Canary.Err == nil {
+			result.LatencyDiverged = float64(result.Canary.Latency) > cfg.tolerance*float64(result.Primary.Latency)
+		}
+		if errors.Is(result.Canary.Err, context.DeadlineExceeded) {
+			result.LatencyDiverged = true
+		}
+		if (result.StatusDiverged || result.LatencyDiverged) && cfg.onDivergence != nil {
+			cfg.onDivergence(result)
+		}
+	}
+
+	tasks := newBackground()
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		send := func(req *http.Request) (*http.Response, CanaryOutcome) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			outcome := CanaryOutcome{Latency: time.Since(start), Err: err}
+			if err == nil {
+				outcome.StatusCode = resp.StatusCode
+			}
+			return resp, outcome
+		}
+
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			if rand.IntN(100) >= percent {
+				resp, primary := send(req)
+				if primary.Err == nil {
+					observe(primary.Latency)
+				}
+				return resp, primary.Err
+			}
+
+			if !cfg.shadow {
+				resp, canary := send(retarget(req, &newBase))
+				report(CanaryResult{
+					Request: req,
+					Primary: CanaryOutcome{Latency: averageLatency()},
+					Canary:  canary,
+					// Only failures can be told apart without a primary
+					// response to compare with.
+					StatusDiverged: canary.failed(),
+				})
+				return resp, canary.Err
+			}
+
+			hasBody := req.Body != nil && req.Body != http.NoBody
+			if (!cfg.allMethods && !IsIdempotent(req)) || (hasBody && req.GetBody == nil) {
+				return next.RoundTrip(req)
+			}
+			var body io.ReadCloser
+			if hasBody {
+				var err error
+				if body, err = req.GetBody(); err != nil {
+					return nil, newError("Canary", req, err)
+				}
+			}
+			ctx, cancel := tasks.detach(req.Context())
+			start := time.Now()
+			timer := time.AfterFunc(cfg.shadowTimeout, func() { cancel(context.DeadlineExceeded) })
+			shadow := retarget(req.WithContext(ctx), &newBase)
+			if hasBody {
+				shadow.Body = body
+			}
+			canaryDone := make(chan CanaryOutcome, 1)
+			tasks.run(func() {
+				resp, canary := send(shadow)
+				if resp != nil {
+					discard(resp)
+				}
+				if canary.Err != nil && context.Cause(ctx) != nil {
+					canary.Err = context.Cause(ctx)
+				}
+				canaryDone <- canary
+			})
+
+			resp, primary := send(req)
+			if primary.Err == nil {
+				observe(primary.Latency)
+				if cfg.tolerance > 0 {
+					limit := min(time.Duration(cfg.tolerance*float64(primary.Latency)), cfg.shadowTimeout)
+					timer.Reset(limit - time.Since(start))
+				}
+			}
+			tasks.run(func() {
+				canary := <-canaryDone
+				timer.Stop()
+				cancel(nil)
+				if errors.Is(canary.Err, ErrShutdown) {
+					return
+				}
+				report(CanaryResult{
+					Request:        req,
+					Shadowed:       true,
+					Primary:        primary,
+					Canary:         canary,
+					StatusDiverged: primary.StatusCode != canary.StatusCode,
+				})
+			})
+
+			return resp, primary.Err
+		})
+		return withClose(transport, tasks.Close)
+	}
+}
@@ -0,0 +1,142 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCanaryInterceptor(t *testing.T) {
+	newBase, _ := url.Parse("https://v2.example.com/api")
+	var mu sync.Mutex
+	var hosts []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, req.URL.String())
+		mu.Unlock()
+		status := http.StatusOK
+		if req.URL.Host == "v2.example.com" {
+			status = http.StatusInternalServerError
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+
+	tests := []struct {
+		name      string
+		percent   int
+		expected  string
+		divergent bool
+	}{
+		{"primary", 0, "http://example.com/users", false},
+		{"canary", 100, "https://v2.example.com/api/users", true},
+	}
+
+	for _, test := range tests {
+		hosts = nil
+		var results []CanaryResult
+		rt := Canary(*newBase, test.percent, CanaryOnDivergence(func(r CanaryResult) {
+			results = append(results, r)
+		}))(transport)
+
+		req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if len(hosts) != 1 || hosts[0] != test.expected {
+			t.Errorf("%s: expected the request to go to %s, got %v", test.name, test.expected, hosts)
+		}
+		if divergent := len(results) == 1 && results[0].StatusDiverged; divergent != test.divergent {
+			t.Errorf("%s: expected divergence %v, got %+v", test.name, test.divergent, results)
+		}
+	}
+}
+
+func TestCanaryInterceptorShadow(t *testing.T) {
+	newBase, _ := url.Parse("https://v2.example.com")
+	var mu sync.Mutex
+	var hosts []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, req.URL.Host)
+		mu.Unlock()
+		if req.URL.Host == "v2.example.com" {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	results := make(chan CanaryResult, 1)
+	rt := Canary(*newBase, 100, CanaryShadow(), CanaryOnDivergence(func(r CanaryResult) {
+		results <- r
+	}))(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the primary response, got %v (%v)", resp, err)
+	}
+
+	select {
+	case r := <-results:
+		if !r.Shadowed || !r.StatusDiverged || r.Primary.StatusCode != http.StatusOK || r.Canary.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected a status divergence between 200 and 404, got %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the divergence to be reported")
+	}
+	if len(hosts) != 2 {
+		t.Errorf("Expected the request to be sent to both endpoints, got %v", hosts)
+	}
+
+	hosts = nil
+	post, _ := http.NewRequest("POST", "http://example.com/users", nil)
+	rt.RoundTrip(post)
+	if len(hosts) != 1 {
+		t.Errorf("Expected a POST not to be shadowed, got %v", hosts)
+	}
+}
+
+func TestCanaryInterceptorShadowTimeout(t *testing.T) {
+	newBase, _ := url.Parse("https://v2.example.com")
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "v2.example.com" {
+			// The canary hangs until it is canceled.
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	tests := []struct {
+		name string
+		opts []CanaryOption
+	}{
+		{"latency tolerance", nil},
+		{"shadow timeout", []CanaryOption{CanaryLatencyTolerance(0), CanaryShadowTimeout(20 * time.Millisecond)}},
+	}
+	for _, test := range tests {
+		results := make(chan CanaryResult, 1)
+		opts := append([]CanaryOption{CanaryShadow(), CanaryOnDivergence(func(r CanaryResult) {
+			results <- r
+		})}, test.opts...)
+		rt := Canary(*newBase, 100, opts...)(transport)
+
+		req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: Failed to perform request: %v", test.name, err)
+		}
+		select {
+		case r := <-results:
+			if !errors.Is(r.Canary.Err, context.DeadlineExceeded) || !r.LatencyDiverged {
+				t.Errorf("%s: Expected the canary to time out with a latency divergence, got %+v", test.name, r)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Expected the hanging canary to be canceled", test.name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package interceptor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ChaosEnvVar is the environment variable that must be set to a true value,
+// such as "1" or "true", for the Chaos interceptor to inject any faults.
+const ChaosEnvVar = "INTERCEPTOR_CHAOS"
+
+// ErrChaos is wrapped by the errors the Chaos interceptor injects.
+var ErrChaos = errors.New("interceptor: fault injected by chaos")
+
+// ChaosOption configures the Chaos interceptor.
+type ChaosOption func(*chaosConfig)
+
+type chaosConfig struct {
+	latencyP   float64
+	minLatency time.Duration
+	maxLatency time.Duration
+	errorP     float64
+	err        error
+	dropP      float64
+	statusP    float64
+	statusCode int
+}
+
+// ChaosLatency delays requests, with probability p, by a random duration
+// between minDelay and maxDelay before they are sent.
+func ChaosLatency(p float64, minDelay, maxDelay time.Duration) ChaosOption {
+	return func(c *chaosConfig) {
+		c.latencyP, c.minLatency, c.maxLatency = p, minDelay, max(minDelay, maxDelay)
+	}
+}
+
+// ChaosError fails requests, with probability p, with an error wrapping both
+// ErrChaos and err instead of sending them. If err is nil, ErrChaos is used.
+func ChaosError(p float64, err error) ChaosOption {
+	return func(c *chaosConfig) {
+		if err == nil {
+			err = ErrChaos
+		} else if !errors.Is(err, ErrChaos) {
+			err = fmt.Errorf("%w: %w", ErrChaos, err)
+		}
+		c.errorP, c.err = p, err
+	}
+}
+
+// ChaosDrop simulates, with probability p, a connection dropped after the
+// request was sent: the request reaches the server, but its response is
+// discarded and the caller receives an error wrapping both ErrChaos and
+// io.ErrUnexpectedEOF.
+func ChaosDrop(p float64) ChaosOption {
+	return func(c *chaosConfig) {
+		c.dropP = p
+	}
+}
+
+// ChaosStatus replaces, with probability p, the status code of responses with
+// code, such as 503, leaving the rest of the response as it was.
+func ChaosStatus(p float64, code int) ChaosOption {
+	return func(c *chaosConfig) {
+		c.statusP, c.statusCode = p, code
+	}
+}
+
+// Chaos returns an Interceptor that injects faults into requests to verify how
+// a client copes with them, for example that retries and circuit breakers
+// behave as intended. Each fault is configured by an option with the
+// probability, from 0 to 1, that it affects a request. To inject faults only
+// into some requests, combine Chaos with When:
+//
+//	interceptor.When(interceptor.HostIs("payments.internal"),
+//		interceptor.Chaos(interceptor.ChaosStatus(0.2, http.StatusServiceUnavailable)))
+//
+// As a safeguard against shipping it enabled, Chaos does nothing unless the
+// ChaosEnvVar environment variable is set to a true value when Chaos is called.
+func Chaos(opts ...ChaosOption) Interceptor {
+	if enabled, _ := strconv.ParseBool(os.Getenv(ChaosEnvVar)); !enabled {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return next
+		}
+	}
+	var cfg chaosConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			if chance(cfg.latencyP) {
+				delay := cfg.minLatency
+				if spread := cfg.maxLatency - cfg.minLatency; spread > 0 {
+					delay += rand.N(spread)
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, newError("Chaos", req, req.Context().Err())
+				}
+			}
+			if chance(cfg.errorP) {
+				return nil, newError("Chaos", req, cfg.err)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if chance(cfg.dropP) {
+				if resp.Body != nil {
+					resp.Body.Close()
+				}
+				return nil, newError("Chaos", req, fmt.Errorf("%w: connection dropped: %w", ErrChaos, io.ErrUnexpectedEOF))
+			}
+			if chance(cfg.statusP) {
+				resp.StatusCode = cfg.statusCode
+				resp.Status = fmt.Sprintf("%d %s", cfg.statusCode, http.StatusText(cfg.statusCode))
+			}
+			return resp, nil
+		})
+	}
+}
+
+// chance reports true with probability p.
+func chance(p float64) bool {
+	return p > 0 && rand.Float64() < p
+}
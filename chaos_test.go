@@ -0,0 +1,68 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChaosInterceptor(t *testing.T) {
+	t.Setenv(ChaosEnvVar, "1")
+	okTransport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	if _, err := Chaos(ChaosError(1, nil))(okTransport).RoundTrip(req); !errors.Is(err, ErrChaos) {
+		t.Errorf("Expected an injected error, got %v", err)
+	}
+	_, err := Chaos(ChaosError(1, io.ErrClosedPipe))(okTransport).RoundTrip(req)
+	if !errors.Is(err, ErrChaos) || !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected an injected error wrapping the custom error, got %v", err)
+	}
+
+	var sent bool
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return okTransport.RoundTrip(req)
+	})
+	_, err = Chaos(ChaosDrop(1))(transport).RoundTrip(req)
+	if !sent || !errors.Is(err, ErrChaos) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected the request to be sent and its response dropped, got sent=%v err=%v", sent, err)
+	}
+
+	resp, err := Chaos(ChaosStatus(1, http.StatusServiceUnavailable))(okTransport).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the status to be replaced, got %d", resp.StatusCode)
+	}
+
+	start := time.Now()
+	if _, err := Chaos(ChaosLatency(1, 20*time.Millisecond, 30*time.Millisecond))(okTransport).RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected latency to be injected, took %v", elapsed)
+	}
+
+	resp, err = Chaos(ChaosError(0, nil), ChaosStatus(0, http.StatusInternalServerError))(okTransport).RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected faults with probability 0 never to happen, got %v", err)
+	}
+}
+
+func TestChaosInterceptorDisabled(t *testing.T) {
+	t.Setenv(ChaosEnvVar, "")
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := Chaos(ChaosError(1, nil))(transport).RoundTrip(req); err != nil {
+		t.Errorf("Expected no faults without %s set, got %v", ChaosEnvVar, err)
+	}
+}
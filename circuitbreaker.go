@@ -0,0 +1,219 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the CircuitBreaker interceptor when a request is
+// rejected without being sent because the circuit for its host is open.
+var ErrCircuitOpen = errors.New("interceptor: circuit breaker is open")
+
+// CircuitBreakerOption configures the CircuitBreaker interceptor.
+type CircuitBreakerOption func(*circuitBreakerConfig)
+
+type circuitBreakerConfig struct {
+	threshold int
+	cooldown  time.Duration
+	probes    int
+	isFailure func(*http.Response, error) bool
+	now       func() time.Time
+}
+
+// CircuitBreakerThreshold sets the number of consecutive failures that opens
+// the circuit for a host. The default is 5.
+func CircuitBreakerThreshold(n int) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) {
+		if n > 0 {
+			c.threshold = n
+		}
+	}
+}
+
+// CircuitBreakerCooldown sets how long a circuit stays open before trial
+// requests are let through. The default is 30 seconds.
+func CircuitBreakerCooldown(d time.Duration) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) {
+		if d > 0 {
+			c.cooldown = d
+		}
+	}
+}
+
+// CircuitBreakerProbes sets how many trial requests are allowed while a circuit
+// is half-open, all of which must succeed for it to close again. The default is 1.
+func CircuitBreakerProbes(n int) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) {
+		if n > 0 {
+			c.probes = n
+		}
+	}
+}
+
+// CircuitBreakerFailureFunc overrides how the outcome of a request is
+// classified. By default transport errors and 5xx responses count as failures.
+// Requests canceled by the caller are not classified: they count as neither
+// a success nor a failure.
+func CircuitBreakerFailureFunc(f func(*http.Response, error) bool) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) {
+		if f != nil {
+			c.isFailure = f
+		}
+	}
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// CircuitBreaker returns an Interceptor that stops sending requests to a host
+// after it fails repeatedly. Each host has its own circuit:
+//
+//   - Closed: requests flow normally. Consecutive failures are counted and the
+//     circuit opens once they reach the threshold.
+//   - Open: requests fail immediately with ErrCircuitOpen until the cooldown
+//     has elapsed.
+//   - Half-open: a limited number of trial requests are sent. If they all
+//     succeed the circuit closes, and if any fails it opens again.
+func CircuitBreaker(opts ...CircuitBreakerOption) Interceptor {
+	cfg := circuitBreakerConfig{
+		threshold: 5,
+		cooldown:  30 * time.Second,
+		probes:    1,
+		isFailure: defaultIsFailure,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	circuits := make(map[string]*circuit)
+	circuitFor := func(host string) *circuit {
+		mu.Lock()
+		defer mu.Unlock()
+		c, ok := circuits[host]
+		if !ok {
+			c = &circuit{cfg: &cfg}
+			circuits[host] = c
+		}
+		return c
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			c := circuitFor(req.URL.Host)
+			if !c.allow() {
+				return nil, newError("CircuitBreaker", req, ErrCircuitOpen)
+			}
+			resp, err := next.RoundTrip(req)
+			if errors.Is(err, context.Canceled) {
+				// The caller gave up, which says nothing about the host.
+				c.release()
+			} else {
+				c.record(cfg.isFailure(resp, err))
+			}
+			return resp, err
+		})
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuit tracks the state of a single host.
+type circuit struct {
+	mu       sync.Mutex
+	cfg      *circuitBreakerConfig
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// inFlight and successes count trial requests while half-open.
+	inFlight  int
+	successes int
+}
+
+// allow reports whether a request may be sent, moving an open circuit to
+// half-open once its cooldown has elapsed.
+func (c *circuit) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case circuitOpen:
+		if c.cfg.now().Sub(c.openedAt) < c.cfg.cooldown {
+			return false
+		}
+		c.state = circuitHalfOpen
+		c.inFlight = 0
+		c.successes = 0
+		fallthrough
+	case circuitHalfOpen:
+		if c.inFlight+c.successes >= c.cfg.probes {
+			return false
+		}
+		c.inFlight++
+	}
+	return true
+}
+
+// record updates the circuit with the outcome of a request.
+func (c *circuit) record(failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case circuitClosed:
+		if !failed {
+			c.failures = 0
+			return
+		}
+		c.failures++
+		if c.failures >= c.cfg.threshold {
+			c.trip()
+		}
+	case circuitHalfOpen:
+		c.inFlight--
+		if failed {
+			c.trip()
+			return
+		}
+		c.successes++
+		if c.successes >= c.cfg.probes {
+			c.state = circuitClosed
+			c.failures = 0
+		}
+	}
+}
+
+// release frees the slot of a request whose outcome is not recorded, so that
+// a canceled trial request lets another one through while half-open.
+func (c *circuit) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == circuitHalfOpen {
+		c.inFlight--
+	}
+}
+
+// trip opens the circuit. The caller must hold c.mu.
+func (c *circuit) trip() {
+	c.state = circuitOpen
+	c.openedAt = c.cfg.now()
+	c.failures = 0
+}
@@ -0,0 +1,134 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerInterceptor(t *testing.T) {
+	now := time.Now()
+	status := http.StatusInternalServerError
+	calls := 0
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+	rt := CircuitBreaker(
+		CircuitBreakerThreshold(3),
+		CircuitBreakerCooldown(time.Minute),
+		func(c *circuitBreakerConfig) { c.now = func() time.Time { return now } },
+	)(transport)
+
+	do := func(url string) error {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		_, err = rt.RoundTrip(req)
+		return err
+	}
+
+	// Failures below the threshold are passed through.
+	for i := 0; i < 3; i++ {
+		if err := do("http://a.example.com"); err != nil {
+			t.Fatalf("Expected request %d to be sent, got %v", i, err)
+		}
+	}
+
+	// The circuit is now open for this host only.
+	if err := do("http://a.example.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected open circuit to skip the transport, got %d calls", calls)
+	}
+	if err := do("http://b.example.com"); err != nil {
+		t.Errorf("Expected other hosts to be unaffected, got %v", err)
+	}
+
+	// After the cooldown a failing probe reopens the circuit.
+	now = now.Add(time.Minute)
+	if err := do("http://a.example.com"); err != nil {
+		t.Errorf("Expected probe to be sent, got %v", err)
+	}
+	if err := do("http://a.example.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected failed probe to reopen circuit, got %v", err)
+	}
+
+	// A succeeding probe closes it again.
+	now = now.Add(time.Minute)
+	status = http.StatusOK
+	for i := 0; i < 3; i++ {
+		if err := do("http://a.example.com"); err != nil {
+			t.Errorf("Expected closed circuit to send request %d, got %v", i, err)
+		}
+	}
+}
+
+func TestCircuitBreakerInterceptorHalfOpenProbes(t *testing.T) {
+	now := time.Now()
+	cfg := circuitBreakerConfig{threshold: 1, cooldown: time.Second, probes: 2, now: func() time.Time { return now }}
+	c := &circuit{cfg: &cfg}
+
+	c.record(true)
+	if c.allow() {
+		t.Fatalf("Expected circuit to be open")
+	}
+
+	now = now.Add(time.Second)
+	if !c.allow() || !c.allow() {
+		t.Fatalf("Expected two probes to be allowed")
+	}
+	if c.allow() {
+		t.Errorf("Expected no more than two probes while half-open")
+	}
+
+	c.record(false)
+	if c.state != circuitHalfOpen {
+		t.Errorf("Expected circuit to stay half-open until all probes succeed")
+	}
+	c.record(false)
+	if c.state != circuitClosed {
+		t.Errorf("Expected circuit to close after all probes succeed")
+	}
+}
+
+func TestCircuitBreakerInterceptorCanceledProbe(t *testing.T) {
+	now := time.Now()
+	canceled := false
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if canceled {
+			return nil, context.Canceled
+		}
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	rt := CircuitBreaker(
+		CircuitBreakerThreshold(1),
+		CircuitBreakerCooldown(time.Second),
+		func(c *circuitBreakerConfig) { c.now = func() time.Time { return now } },
+	)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	rt.RoundTrip(req)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to open, got %v", err)
+	}
+
+	// A canceled probe neither closes nor reopens the circuit, and frees its
+	// slot for the next probe.
+	now = now.Add(time.Second)
+	canceled = true
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the probe to be sent, got %v", err)
+	}
+	canceled = false
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Expected another probe to be sent, got %v", err)
+	}
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the failed probe to reopen the circuit, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"net/http"
+	"time"
+)
+
+// ClientOption configures the http.Client returned by NewClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	transport    http.RoundTripper
+	timeout      time.Duration
+	jar          http.CookieJar
+	interceptors []Interceptor
+}
+
+// ClientTransport sets the underlying transport of the client's Pipeline. If
+// not set, http.DefaultTransport is used.
+func ClientTransport(transport http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// ClientTimeout sets the client's overall time limit for each request,
+// including redirects and reading the response body. See http.Client.Timeout.
+func ClientTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// ClientCookieJar sets the cookie jar used by the client.
+func ClientCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *clientConfig) {
+		c.jar = jar
+	}
+}
+
+// ClientInterceptors appends interceptors to the client's Pipeline. It may be
+// given multiple times, and interceptors run in the order they were added.
+func ClientInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *clientConfig) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// NewClient returns an http.Client whose transport is a Pipeline configured by
+// opts.
+func NewClient(opts ...ClientOption) *http.Client {
+	var cfg clientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &http.Client{
+		Transport: New(cfg.transport, cfg.interceptors...),
+		Timeout:   cfg.timeout,
+		Jar:       cfg.jar,
+	}
+}
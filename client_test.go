@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	var got *http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(
+		ClientTransport(transport),
+		ClientTimeout(5*time.Second),
+		ClientCookieJar(jar),
+		ClientInterceptors(Header("X-First", "1")),
+		ClientInterceptors(Header("X-Second", "2")),
+	)
+
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout to be 5s, got %v", client.Timeout)
+	}
+	if client.Jar != jar {
+		t.Errorf("Expected cookie jar to be set")
+	}
+	if _, ok := client.Transport.(*Pipeline); !ok {
+		t.Fatalf("Expected transport to be a *Pipeline, got %T", client.Transport)
+	}
+
+	resp, err := client.Get("http://example.com")
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	resp.Body.Close()
+
+	if got.Header.Get("X-First") != "1" || got.Header.Get("X-Second") != "2" {
+		t.Errorf("Expected both interceptors to run, got headers %v", got.Header)
+	}
+}
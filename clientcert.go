@@ -0,0 +1,190 @@
+package interceptor
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultMaxTransports is the default number of transports kept by ClientCert
+// and DialTarget.
+const defaultMaxTransports = 64
+
+// ClientCertOption configures the ClientCert interceptor.
+type ClientCertOption func(*clientCertConfig)
+
+type clientCertConfig struct {
+	base          *http.Transport
+	maxTransports int
+}
+
+// ClientCertTransport sets the transport that the transports presenting client
+// certificates are cloned from, so that they share its proxy, timeouts, and
+// TLS settings such as root CAs. The default is http.DefaultTransport.
+func ClientCertTransport(base *http.Transport) ClientCertOption {
+	return func(c *clientCertConfig) {
+		if base != nil {
+			c.base = base
+		}
+	}
+}
+
+// ClientCertMaxTransports sets the number of transports, one per certificate,
+// that ClientCert keeps. Once it holds more, the least recently used one is
+// dropped and its idle connections are closed. The default is 64, and zero or
+// less means no limit.
+func ClientCertMaxTransports(n int) ClientCertOption {
+	return func(c *clientCertConfig) {
+		c.maxTransports = n
+	}
+}
+
+// ClientCert returns an Interceptor that authenticates requests with the
+// client certificate chosen for each of them by selector, for example one per
+// host or per tenant. If selector returns a nil certificate, the request is
+// passed down the chain unchanged, and if it returns an error, the request
+// fails with it.
+//
+// The TLS configuration of an http.Transport cannot vary per request, and its
+// connections are reused regardless of the certificate they were made with,
+// so ClientCert keeps one transport per distinct certificate and sends each
+// request through the transport for its certificate. Requests with a
+// certificate therefore do not reach the interceptors after ClientCert or the
+// Pipeline's own transport, and ClientCert should be the last interceptor in
+// the chain. At most ClientCertMaxTransports transports are kept, and
+// Pipeline.Shutdown closes the idle connections of those it holds.
+func ClientCert(selector func(*http.Request) (*tls.Certificate, error), opts ...ClientCertOption) Interceptor {
+	cfg := clientCertConfig{maxTransports: defaultMaxTransports}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.base == nil {
+		cfg.base = defaultTransport()
+	}
+
+	transports := newTransportPool[[sha256.Size]byte](cfg.maxTransports)
+	transportFor := func(cert *tls.Certificate) (*http.Transport, error) {
+		if len(cert.Certificate) == 0 {
+			return nil, errors.New("interceptor: client certificate is empty")
+		}
+		return transports.get(sha256.Sum256(cert.Certificate[0]), func() (*http.Transport, error) {
+			transport := cfg.base.Clone()
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+			transport.TLSClientConfig.GetClientCertificate = nil
+			return transport, nil
+		})
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withClose(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			cert, err := selector(req)
+			if err != nil {
+				return nil, newError("ClientCert", req, err)
+			}
+			if cert == nil {
+				return next.RoundTrip(req)
+			}
+			if IsPreparing(req.Context()) {
+				return nil, newError("ClientCert", req, ErrNotPrepared)
+			}
+			transport, err := transportFor(cert)
+			if err != nil {
+				return nil, newError("ClientCert", req, err)
+			}
+			return transport.RoundTrip(req)
+		}), transports.Close)
+	}
+}
+
+// defaultTransport returns http.DefaultTransport, or a transport with the same
+// settings as the standard library's if it has been replaced by something
+// other than an *http.Transport.
+func defaultTransport() *http.Transport {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		return transport
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// transportPool holds the transports that ClientCert and DialTarget keep per
+// key, dropping the least recently used one once it holds more than max.
+// Requests still in flight on a dropped transport complete normally, and its
+// connections are then closed after its IdleConnTimeout.
+type transportPool[K comparable] struct {
+	mu         sync.Mutex
+	max        int
+	order      *list.List
+	transports map[K]*list.Element
+}
+
+type transportPoolEntry[K comparable] struct {
+	key       K
+	transport *http.Transport
+}
+
+func newTransportPool[K comparable](max int) *transportPool[K] {
+	return &transportPool[K]{
+		max:        max,
+		order:      list.New(),
+		transports: make(map[K]*list.Element),
+	}
+}
+
+// get returns the transport for key, calling create to make it if the pool
+// has none.
+func (p *transportPool[K]) get(key K, create func() (*http.Transport, error)) (*http.Transport, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.transports[key]; ok {
+		p.order.MoveToFront(e)
+		return e.Value.(*transportPoolEntry[K]).transport, nil
+	}
+	transport, err := create()
+	if err != nil {
+		return nil, err
+	}
+	p.transports[key] = p.order.PushFront(&transportPoolEntry[K]{key: key, transport: transport})
+	if p.max > 0 && p.order.Len() > p.max {
+		oldest := p.order.Remove(p.order.Back()).(*transportPoolEntry[K])
+		delete(p.transports, oldest.key)
+		oldest.transport.CloseIdleConnections()
+	}
+	return transport, nil
+}
+
+// Close closes the idle connections of every transport in the pool.
+func (p *transportPool[K]) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for e := p.order.Front(); e != nil; e = e.Next() {
+		e.Value.(*transportPoolEntry[K]).transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+// len returns the number of transports in the pool.
+func (p *transportPool[K]) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.order.Len()
+}
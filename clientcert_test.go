@@ -0,0 +1,120 @@
+package interceptor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// selfSignedCert returns a client certificate with the given common name.
+func selfSignedCert(t *testing.T, name string) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientCertInterceptor(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			io.WriteString(w, "anonymous")
+			return
+		}
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certs := map[string]*tls.Certificate{
+		"alpha": selfSignedCert(t, "alpha"),
+		"bravo": selfSignedCert(t, "bravo"),
+	}
+	base := server.Client().Transport.(*http.Transport)
+	rt := ClientCert(func(req *http.Request) (*tls.Certificate, error) {
+		return certs[req.Header.Get("X-Tenant")], nil
+	}, ClientCertTransport(base))(base)
+
+	for _, tenant := range []string{"alpha", "bravo", "alpha", ""} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("X-Tenant", tenant)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		expected := tenant
+		if expected == "" {
+			expected = "anonymous"
+		}
+		if string(body) != expected {
+			t.Errorf("Expected the server to see certificate %q, got %q", expected, body)
+		}
+	}
+}
+
+func TestTransportPool(t *testing.T) {
+	pool := newTransportPool[string](2)
+	created := 0
+	create := func() (*http.Transport, error) {
+		created++
+		return &http.Transport{}, nil
+	}
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := pool.get(key, create); err != nil {
+			t.Fatalf("Failed to get transport %q: %v", key, err)
+		}
+	}
+	// c evicts b, the least recently used, which is then created again.
+	if created != 4 {
+		t.Errorf("Expected 4 transports to be created, got %d", created)
+	}
+	if pool.len() != 2 {
+		t.Errorf("Expected the pool to hold 2 transports, got %d", pool.len())
+	}
+
+	errCreate := errors.New("create failed")
+	if _, err := pool.get("d", func() (*http.Transport, error) { return nil, errCreate }); !errors.Is(err, errCreate) {
+		t.Errorf("Expected the create error, got %v", err)
+	}
+	if pool.len() != 2 {
+		t.Errorf("Expected a failed create to add nothing, got %d transports", pool.len())
+	}
+}
+
+func TestClientCertReplacedDefaultTransport(t *testing.T) {
+	original := http.DefaultTransport
+	http.DefaultTransport = RoundTripperFunc(original.RoundTrip)
+	defer func() { http.DefaultTransport = original }()
+
+	// Building the interceptors must not assume an *http.Transport.
+	ClientCert(func(*http.Request) (*tls.Certificate, error) { return nil, nil })
+	DialTarget()
+	TLSPin("example.com", nil)
+}
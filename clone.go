@@ -0,0 +1,77 @@
+package interceptor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// ErrRequestModified is returned by the CloneGuard interceptor when an
+// interceptor or transport further down the chain modified the caller's request.
+var ErrRequestModified = errors.New("interceptor: request was modified instead of cloned")
+
+// CloneGuard returns an Interceptor that checks that nothing after it in the
+// chain modifies the request it was given. It records the request's method,
+// URL, Host, headers, and body before passing it on, and if any of them has
+// changed once the response comes back, the response is discarded and an error
+// wrapping ErrRequestModified is returned.
+//
+// CloneGuard is intended for tests and development builds. Install it first in
+// a Pipeline to verify that custom interceptors clone requests before changing
+// them.
+func CloneGuard() Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			before := snapshotRequest(req)
+			resp, err := next.RoundTrip(req)
+			if field := before.diff(req); field != "" {
+				if resp != nil && resp.Body != nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				return nil, newError("CloneGuard", req, fmt.Errorf("%w: %s changed", ErrRequestModified, field))
+			}
+			return resp, err
+		})
+	}
+}
+
+// requestSnapshot holds the parts of a request that CloneGuard checks.
+type requestSnapshot struct {
+	method string
+	url    string
+	host   string
+	header http.Header
+	body   io.ReadCloser
+}
+
+func snapshotRequest(req *http.Request) requestSnapshot {
+	return requestSnapshot{
+		method: req.Method,
+		url:    req.URL.String(),
+		host:   req.Host,
+		header: req.Header.Clone(),
+		body:   req.Body,
+	}
+}
+
+// diff returns the name of the first field of req that differs from the
+// snapshot, or an empty string if none does.
+func (s requestSnapshot) diff(req *http.Request) string {
+	switch {
+	case req.Method != s.method:
+		return "method"
+	case req.URL.String() != s.url:
+		return "URL"
+	case req.Host != s.host:
+		return "Host"
+	case !maps.EqualFunc(req.Header, s.header, slices.Equal[[]string]):
+		return "header"
+	case req.Body != s.body:
+		return "body"
+	}
+	return ""
+}
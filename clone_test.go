@@ -0,0 +1,57 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCloneGuardInterceptor(t *testing.T) {
+	base, err := url.Parse("http://base.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutating := func(mutate func(req *http.Request)) Interceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				mutate(req)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	tests := []struct {
+		name        string
+		interceptor Interceptor
+		field       string
+	}{
+		{"built-in interceptors", Chain(BaseURL(*base), Header("X-Test", "1")), ""},
+		{"header", mutating(func(req *http.Request) { req.Header.Set("X-Test", "1") }), "header"},
+		{"url", mutating(func(req *http.Request) { req.URL.Path = "/other" }), "URL"},
+		{"method", mutating(func(req *http.Request) { req.Method = "POST" }), "method"},
+		{"body", mutating(func(req *http.Request) { req.Body = http.NoBody }), "body"},
+	}
+
+	for _, test := range tests {
+		mockRT := &mockRoundTripper{
+			Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+		}
+		rt := Chain(CloneGuard(), test.interceptor)(mockRT)
+
+		req, _ := http.NewRequest("GET", "/resource", strings.NewReader("body"))
+		_, err := rt.RoundTrip(req)
+		if test.field == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", test.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrRequestModified) {
+			t.Errorf("%s: expected ErrRequestModified, got %v", test.name, err)
+		} else if !strings.Contains(err.Error(), test.field+" changed") {
+			t.Errorf("%s: expected error to name %s, got %v", test.name, test.field, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package interceptor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Codec implements a content coding such as gzip for the Compression
+// interceptor. Codecs for other encodings, such as zstd or br, can be added by
+// implementing this interface on top of a third-party compression library.
+type Codec interface {
+	// Encoding returns the content coding name used in the Content-Encoding
+	// and Accept-Encoding headers, such as "gzip".
+	Encoding() string
+	// NewWriter returns a writer that compresses data written to it into w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCodec is the Codec for the "gzip" content coding.
+type GzipCodec struct{}
+
+// Encoding implements Codec.
+func (GzipCodec) Encoding() string { return "gzip" }
+
+// NewWriter implements Codec.
+func (GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+
+// NewReader implements Codec.
+func (GzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+// DeflateCodec is the Codec for the "deflate" content coding, which HTTP
+// defines as the zlib format.
+type DeflateCodec struct{}
+
+// Encoding implements Codec.
+func (DeflateCodec) Encoding() string { return "deflate" }
+
+// NewWriter implements Codec.
+func (DeflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil }
+
+// NewReader implements Codec.
+func (DeflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) }
+
+// CompressionOption configures the Compression interceptor.
+type CompressionOption func(*compressionConfig)
+
+type compressionConfig struct {
+	codecs          []Codec
+	requestEncoding string
+	threshold       int64
+}
+
+// CompressionCodecs sets the codecs used to decode responses, in order of
+// preference. They are advertised in the Accept-Encoding header. The default is
+// GzipCodec and DeflateCodec.
+func CompressionCodecs(codecs ...Codec) CompressionOption {
+	return func(c *compressionConfig) {
+		c.codecs = codecs
+	}
+}
+
+// CompressionRequestEncoding enables compression of request bodies with the
+// codec for encoding, which must be one of the configured codecs. Request
+// compression is disabled by default because not all servers accept it.
+func CompressionRequestEncoding(encoding string) CompressionOption {
+	return func(c *compressionConfig) {
+		c.requestEncoding = encoding
+	}
+}
+
+// CompressionThreshold sets the minimum request body size, in bytes, that is
+// compressed. The default is 1024.
+func CompressionThreshold(n int64) CompressionOption {
+	return func(c *compressionConfig) {
+		c.threshold = n
+	}
+}
+
+// Compression returns an Interceptor that negotiates compressed responses and
+// decompresses them transparently, and optionally compresses request bodies.
+//
+// Requests that do not already carry an Accept-Encoding header are sent with
+// one listing the configured codecs, and responses using one of them are
+// decoded before being returned, with the Content-Encoding and Content-Length
+// headers removed. Requests whose caller set Accept-Encoding are left alone so
+// the caller receives the encoded body it asked for.
+//
+// When request compression is enabled, bodies of at least the threshold size
+// without a Content-Encoding are compressed and sent with Content-Encoding set.
+func Compression(opts ...CompressionOption) Interceptor {
+	cfg := compressionConfig{
+		codecs:    []Codec{GzipCodec{}, DeflateCodec{}},
+		threshold: 1024,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	codecs := make(map[string]Codec, len(cfg.codecs))
+	names := make([]string, 0, len(cfg.codecs))
+	for _, codec := range cfg.codecs {
+		codecs[codec.Encoding()] = codec
+		names = append(names, codec.Encoding())
+	}
+	acceptEncoding := strings.Join(names, ", ")
+	requestCodec := codecs[cfg.requestEncoding]
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if requestCodec != nil {
+				if err := compressRequest(req, requestCodec, cfg.threshold); err != nil {
+					return nil, newError("Compression", req, err)
+				}
+			}
+
+			negotiate := req.Header.Get("Accept-Encoding") == "" && len(codecs) > 0
+			if negotiate {
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || !negotiate {
+				return resp, err
+			}
+
+			codec, ok := codecs[strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))]
+			if !ok || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, nil
+			}
+			resp.Body = &decodingBody{src: resp.Body, codec: codec}
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+			return resp, nil
+		})
+	}
+}
+
+// compressRequest replaces the body of req with its compressed form if it is
+// large enough and not already encoded.
+func compressRequest(req *http.Request, codec Codec, threshold int64) error {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	if req.ContentLength > 0 && req.ContentLength < threshold {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	payload := body
+	if int64(len(body)) >= threshold {
+		var buf bytes.Buffer
+		w, err := codec.NewWriter(&buf)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+		req.Header.Set("Content-Encoding", codec.Encoding())
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	req.ContentLength = int64(len(payload))
+	return nil
+}
+
+// decodingBody decompresses a response body. The decoder is created on the
+// first read so that empty bodies, such as those of HEAD responses, are not
+// treated as corrupt.
+type decodingBody struct {
+	src     io.ReadCloser
+	codec   Codec
+	decoder io.ReadCloser
+	err     error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && b.err == nil {
+		decoder, err := b.codec.NewReader(b.src)
+		switch {
+		case errors.Is(err, io.EOF):
+			b.err = io.EOF
+		case err != nil:
+			b.err = err
+		default:
+			b.decoder = decoder
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.decoder.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	if b.decoder != nil {
+		b.decoder.Close()
+	}
+	return b.src.Close()
+}
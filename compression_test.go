@@ -0,0 +1,166 @@
+package interceptor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestCompressionInterceptorDecodesResponses(t *testing.T) {
+	compressed := gzipBytes(t, "hello world")
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		encoding       string
+		body           []byte
+		expectedAccept string
+		expectedBody   string
+	}{
+		{"gzip response", "", "gzip", compressed, "gzip, deflate", "hello world"},
+		{"identity response", "", "", []byte("plain"), "gzip, deflate", "plain"},
+		{"empty gzip response", "", "gzip", nil, "gzip, deflate", ""},
+		{"caller negotiates", "gzip", "gzip", compressed, "gzip", string(compressed)},
+	}
+
+	for _, test := range tests {
+		var accept string
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			accept = req.Header.Get("Accept-Encoding")
+			header := http.Header{"Content-Length": {"123"}}
+			if test.encoding != "" {
+				header.Set("Content-Encoding", test.encoding)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader(test.body))}, nil
+		})
+		rt := Compression()(transport)
+
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+
+		if accept != test.expectedAccept {
+			t.Errorf("%s: expected Accept-Encoding '%s', got '%s'", test.name, test.expectedAccept, accept)
+		}
+		if body := readBody(t, resp); body != test.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", test.name, test.expectedBody, body)
+		}
+		if test.acceptEncoding == "" && test.encoding != "" {
+			if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+				t.Errorf("%s: expected response to be marked as decoded", test.name)
+			}
+		}
+	}
+}
+
+func TestCompressionInterceptorCompressesRequests(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedEncoding string
+	}{
+		{"small body", "small", ""},
+		{"large body", large, "deflate"},
+	}
+
+	for _, test := range tests {
+		mockRT := &mockRoundTripper{
+			Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+		}
+		rt := Compression(CompressionRequestEncoding("deflate"))(mockRT)
+
+		req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader(test.body))
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+
+		sent := mockRT.Request
+		if got := sent.Header.Get("Content-Encoding"); got != test.expectedEncoding {
+			t.Errorf("%s: expected Content-Encoding '%s', got '%s'", test.name, test.expectedEncoding, got)
+		}
+		var body io.Reader = sent.Body
+		if test.expectedEncoding != "" {
+			decoder, err := DeflateCodec{}.NewReader(sent.Body)
+			if err != nil {
+				t.Fatalf("%s: failed to decode body: %v", test.name, err)
+			}
+			body = decoder
+		}
+		if decoded, _ := io.ReadAll(body); string(decoded) != test.body {
+			t.Errorf("%s: expected body to round-trip", test.name)
+		}
+		if sent.ContentLength <= 0 || sent.GetBody == nil {
+			t.Errorf("%s: expected Content-Length and GetBody to be set", test.name)
+		}
+	}
+}
+
+// hexCodec stands in for a third-party codec such as br.
+type hexCodec struct{}
+
+func (hexCodec) Encoding() string { return "br" }
+
+func (hexCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{hex.NewEncoder(w)}, nil
+}
+
+func (hexCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(hex.NewDecoder(r)), nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestCompressionInterceptorCustomCodec(t *testing.T) {
+	var accept, encoding, sent string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		accept = req.Header.Get("Accept-Encoding")
+		encoding = req.Header.Get("Content-Encoding")
+		body, _ := io.ReadAll(req.Body)
+		sent = string(body)
+		header := http.Header{"Content-Encoding": {"br"}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(hex.EncodeToString([]byte("pong"))))}, nil
+	})
+	rt := Compression(
+		CompressionCodecs(hexCodec{}, GzipCodec{}),
+		CompressionRequestEncoding("br"),
+		CompressionThreshold(1),
+	)(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("ping"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if accept != "br, gzip" {
+		t.Errorf("Expected Accept-Encoding 'br, gzip', got '%s'", accept)
+	}
+	if encoding != "br" || sent != hex.EncodeToString([]byte("ping")) {
+		t.Errorf("Expected the request body to be encoded with br, got %q with encoding '%s'", sent, encoding)
+	}
+	if body := readBody(t, resp); body != "pong" {
+		t.Errorf("Expected the response body to be decoded, got %q", body)
+	}
+}
@@ -0,0 +1,107 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrConcurrencyLimited is returned by the ConcurrencyLimit interceptor when it
+// is configured not to wait and the limit on requests in flight is reached.
+var ErrConcurrencyLimited = errors.New("interceptor: concurrency limit reached")
+
+// ConcurrencyLimitOption configures the ConcurrencyLimit interceptor.
+type ConcurrencyLimitOption func(*concurrencyLimitConfig)
+
+type concurrencyLimitConfig struct {
+	key  func(*http.Request) string
+	wait bool
+}
+
+// ConcurrencyLimitPerHost applies the limit to each request host separately
+// instead of to all requests together.
+func ConcurrencyLimitPerHost() ConcurrencyLimitOption {
+	return func(c *concurrencyLimitConfig) {
+		c.key = func(req *http.Request) string {
+			return req.URL.Host
+		}
+	}
+}
+
+// ConcurrencyLimitNoWait makes requests over the limit fail immediately with
+// ErrConcurrencyLimited instead of waiting for another request to finish.
+func ConcurrencyLimitNoWait() ConcurrencyLimitOption {
+	return func(c *concurrencyLimitConfig) {
+		c.wait = false
+	}
+}
+
+// ConcurrencyLimit returns an Interceptor that allows at most max requests in
+// flight at once, protecting upstream services and the local process from
+// bursts of connections. A request stays in flight until its response body has
+// been read to the end or closed.
+//
+// By default requests over the limit wait for another request to finish.
+// Waiting respects the request context, so a canceled context fails the
+// request with the context's error. ConcurrencyLimit panics if max is less
+// than 1.
+func ConcurrencyLimit(max int, opts ...ConcurrencyLimitOption) Interceptor {
+	if max < 1 {
+		panic("interceptor: ConcurrencyLimit requires a limit of at least 1")
+	}
+	cfg := concurrencyLimitConfig{
+		key:  func(*http.Request) string { return "" },
+		wait: true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	semaphores := make(map[string]chan struct{})
+	semaphoreFor := func(key string) chan struct{} {
+		mu.Lock()
+		defer mu.Unlock()
+		s, ok := semaphores[key]
+		if !ok {
+			s = make(chan struct{}, max)
+			semaphores[key] = s
+		}
+		return s
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			sem := semaphoreFor(cfg.key(req))
+			if !cfg.wait {
+				select {
+				case sem <- struct{}{}:
+				default:
+					return nil, newError("ConcurrencyLimit", req, ErrConcurrencyLimited)
+				}
+			} else {
+				select {
+				case sem <- struct{}{}:
+				case <-req.Context().Done():
+					return nil, newError("ConcurrencyLimit", req, req.Context().Err())
+				}
+			}
+			release := sync.OnceFunc(func() { <-sem })
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				release()
+				return resp, nil
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: release}
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+	})
+	rt := ConcurrencyLimit(1)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	first, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// The first response is still open, so a second request waits.
+	done := make(chan error, 1)
+	go func() {
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Expected the second request to wait while the first is in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	io.ReadAll(first.Body)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the waiting request to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the second request to proceed once the first body was consumed")
+	}
+}
+
+func TestConcurrencyLimitInterceptorNoWait(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+	})
+	rt := ConcurrencyLimit(1, ConcurrencyLimitNoWait(), ConcurrencyLimitPerHost())(transport)
+
+	a, _ := http.NewRequest("GET", "http://a.example.com", nil)
+	b, _ := http.NewRequest("GET", "http://b.example.com", nil)
+	open, err := rt.RoundTrip(a)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if _, err := rt.RoundTrip(a); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Errorf("Expected ErrConcurrencyLimited, got %v", err)
+	}
+	if _, err := rt.RoundTrip(b); err != nil {
+		t.Errorf("Expected another host to have its own limit, got %v", err)
+	}
+
+	open.Body.Close()
+	if _, err := rt.RoundTrip(a); err != nil {
+		t.Errorf("Expected the limit to be released once the body was closed, got %v", err)
+	}
+}
+
+func TestConcurrencyLimitInterceptorContext(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+	})
+	rt := ConcurrencyLimit(1)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error while waiting, got %v", err)
+	}
+}
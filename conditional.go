@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// When returns an Interceptor that applies i only to requests for which pred
+// returns true. Other requests skip i and go straight to the next transport.
+// If the transport returned by i implements io.Closer, so does the one
+// returned by When.
+func When(pred func(*http.Request) bool, i Interceptor) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		wrapped := i(next)
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if pred(req) {
+				return wrapped.RoundTrip(req)
+			}
+			return next.RoundTrip(req)
+		})
+		if closer, ok := layerCloser(wrapped, next); ok {
+			return withClose(transport, closer.Close)
+		}
+		return transport
+	}
+}
+
+// Unless returns an Interceptor that applies i only to requests for which pred
+// returns false.
+func Unless(pred func(*http.Request) bool, i Interceptor) Interceptor {
+	return When(func(req *http.Request) bool { return !pred(req) }, i)
+}
+
+// Matcher reports whether a request satisfies some condition. It is used to
+// select requests in When, Unless, and Route.
+type Matcher func(*http.Request) bool
+
+// HostIs returns a Matcher that matches requests to any of the given hosts.
+// Hosts are compared case-insensitively and must include the port if the
+// request URL has one.
+func HostIs(hosts ...string) Matcher {
+	return func(req *http.Request) bool {
+		return slices.ContainsFunc(hosts, func(host string) bool {
+			return strings.EqualFold(host, req.URL.Host)
+		})
+	}
+}
+
+// MethodIs returns a Matcher that matches requests using any of the given
+// methods.
+func MethodIs(methods ...string) Matcher {
+	return func(req *http.Request) bool {
+		return slices.Contains(methods, req.Method)
+	}
+}
+
+// IsIdempotent reports whether req uses an idempotent method as defined by
+// RFC 9110 (GET, HEAD, OPTIONS, TRACE, PUT, or DELETE), or carries an
+// idempotency key: an Idempotency-Key header, or the key sent by the
+// IdempotencyKey interceptor in the header it is configured with.
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if header, ok := req.Context().Value(idempotencyHeaderKey{}).(string); ok && req.Header.Get(header) != "" {
+		return true
+	}
+	return req.Header.Get(DefaultIdempotencyKeyHeader) != ""
+}
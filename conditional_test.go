@@ -0,0 +1,72 @@
+package interceptor
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWhenInterceptor(t *testing.T) {
+	var got *http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := Chain(
+		When(HostIs("api.example.com"), Header("Authorization", "Bearer token")),
+		Unless(MethodIs("GET", "HEAD"), Header("X-Write", "true")),
+	)(transport)
+
+	tests := []struct {
+		method        string
+		url           string
+		expectedAuth  string
+		expectedWrite string
+	}{
+		{"GET", "http://api.example.com/users", "Bearer token", ""},
+		{"GET", "http://API.example.com/users", "Bearer token", ""},
+		{"GET", "http://other.example.com/users", "", ""},
+		{"POST", "http://api.example.com/users", "Bearer token", "true"},
+		{"POST", "http://other.example.com/users", "", "true"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if auth := got.Header.Get("Authorization"); auth != test.expectedAuth {
+			t.Errorf("%s %s: expected Authorization '%s', got '%s'", test.method, test.url, test.expectedAuth, auth)
+		}
+		if write := got.Header.Get("X-Write"); write != test.expectedWrite {
+			t.Errorf("%s %s: expected X-Write '%s', got '%s'", test.method, test.url, test.expectedWrite, write)
+		}
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		method   string
+		key      string
+		expected bool
+	}{
+		{"GET", "", true},
+		{"PUT", "", true},
+		{"DELETE", "", true},
+		{"POST", "", false},
+		{"PATCH", "", false},
+		{"POST", "abc123", true},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "http://example.com", nil)
+		if test.key != "" {
+			req.Header.Set("Idempotency-Key", test.key)
+		}
+		if got := IsIdempotent(req); got != test.expected {
+			t.Errorf("Expected IsIdempotent to be %v for %s (key %q), got %v", test.expected, test.method, test.key, got)
+		}
+	}
+}
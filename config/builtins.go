@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"os"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+	"golang.org/x/time/rate"
+)
+
+// registerBuiltins registers the factories documented at NewRegistry.
+func registerBuiltins(r *Registry) {
+	r.Register("base_url", func(o *Options) (interceptor.Interceptor, error) {
+		u, err := url.Parse(o.String("url", ""))
+		if err != nil || u.Scheme == "" {
+			return nil, errors.New("option \"url\" must be an absolute URL")
+		}
+		return interceptor.BaseURL(*u), nil
+	})
+	r.Register("header", func(o *Options) (interceptor.Interceptor, error) {
+		name, value := o.String("name", ""), o.String("value", "")
+		if name == "" {
+			return nil, errors.New("option \"name\" is required")
+		}
+		return interceptor.Header(name, value), nil
+	})
+	r.Register("user_agent", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.UserAgent(o.String("product", ""), o.String("version", "")), nil
+	})
+	r.Register("request_id", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.RequestID(interceptor.RequestIDHeader(o.String("header", ""))), nil
+	})
+	r.Register("timeout", func(o *Options) (interceptor.Interceptor, error) {
+		d := o.Duration("duration", 0)
+		if d <= 0 {
+			return nil, errors.New("option \"duration\" must be positive")
+		}
+		return interceptor.Timeout(d), nil
+	})
+	r.Register("per_try_timeout", func(o *Options) (interceptor.Interceptor, error) {
+		d := o.Duration("duration", 0)
+		if d <= 0 {
+			return nil, errors.New("option \"duration\" must be positive")
+		}
+		return interceptor.PerTryTimeout(d), nil
+	})
+	r.Register("retry", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.Retry(interceptor.RetryPolicy{
+			MaxAttempts: o.Int("max_attempts", 3),
+			Backoff:     o.Duration("backoff", 0),
+			MaxBackoff:  o.Duration("max_backoff", 0),
+			Budget:      o.Float("budget", 0),
+		}), nil
+	})
+	r.Register("circuit_breaker", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.CircuitBreaker(
+			interceptor.CircuitBreakerThreshold(o.Int("threshold", 0)),
+			interceptor.CircuitBreakerCooldown(o.Duration("cooldown", 0)),
+			interceptor.CircuitBreakerProbes(o.Int("probes", 0)),
+		), nil
+	})
+	r.Register("rate_limit", func(o *Options) (interceptor.Interceptor, error) {
+		limit, burst := o.Float("rate", 0), o.Int("burst", 1)
+		var opts []interceptor.RateLimitOption
+		if o.Bool("per_host", false) {
+			opts = append(opts, interceptor.RateLimitPerHost())
+		}
+		if o.Bool("no_wait", false) {
+			opts = append(opts, interceptor.RateLimitNoWait())
+		}
+		if limit <= 0 {
+			return nil, errors.New("option \"rate\" must be positive")
+		}
+		return interceptor.RateLimit(rate.Limit(limit), burst, opts...), nil
+	})
+	r.Register("concurrency_limit", func(o *Options) (interceptor.Interceptor, error) {
+		max := o.Int("max", 0)
+		var opts []interceptor.ConcurrencyLimitOption
+		if o.Bool("per_host", false) {
+			opts = append(opts, interceptor.ConcurrencyLimitPerHost())
+		}
+		if o.Bool("no_wait", false) {
+			opts = append(opts, interceptor.ConcurrencyLimitNoWait())
+		}
+		if max < 1 {
+			return nil, errors.New("option \"max\" must be at least 1")
+		}
+		return interceptor.ConcurrencyLimit(max, opts...), nil
+	})
+	r.Register("compression", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.Compression(), nil
+	})
+	r.Register("dump", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.Dump(os.Stderr, interceptor.DumpBody(o.Bool("body", true))), nil
+	})
+	r.Register("curl_on_error", func(o *Options) (interceptor.Interceptor, error) {
+		var opts []interceptor.CurlOption
+		if o.Bool("show_secrets", false) {
+			opts = append(opts, interceptor.CurlShowSecrets())
+		}
+		return interceptor.CurlOnError(os.Stderr, opts...), nil
+	})
+}
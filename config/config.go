@@ -0,0 +1,171 @@
+// Package config builds an interceptor.Pipeline from a declarative document,
+// so that settings such as retry counts and timeouts can be tuned without
+// recompiling. A document lists interceptors by name, in the order they run,
+// each with its options:
+//
+//	{
+//	  "interceptors": [
+//	    {"name": "request_id"},
+//	    {"name": "timeout", "options": {"duration": "30s"}},
+//	    {"name": "retry", "options": {"max_attempts": 3, "backoff": "200ms"}},
+//	    {"name": "circuit_breaker", "options": {"threshold": 5, "cooldown": "1m"}}
+//	  ]
+//	}
+//
+// Documents are read from JSON by Load. To keep this module free of a YAML
+// dependency, YAML documents are read by LoadWith with the unmarshal function
+// of a YAML library; the Document type carries yaml struct tags for it:
+//
+//	pipeline, err := config.LoadWith(f, yaml.Unmarshal, http.DefaultTransport)
+//
+// Logging is limited to the dump and curl_on_error entries, which write to
+// standard error. Other loggers, such as one with a configurable level, can be
+// registered as custom factories.
+//
+// Interceptors are created by factories registered under their names in a
+// Registry. The default registry knows the built-in interceptors listed at
+// NewRegistry, and applications can register their own with Register.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// Document is the declarative description of a Pipeline.
+type Document struct {
+	// Interceptors lists the interceptors of the Pipeline, outermost first.
+	Interceptors []Entry `json:"interceptors" yaml:"interceptors"`
+}
+
+// Entry configures one interceptor of a Document.
+type Entry struct {
+	// Name is the name the interceptor's factory is registered under. The
+	// interceptor is added to the Pipeline under this name, so that it can be
+	// removed with Pipeline.Remove.
+	Name string `json:"name" yaml:"name"`
+	// Options are passed to the factory.
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Factory creates an interceptor from its options.
+type Factory func(opts *Options) (interceptor.Interceptor, error)
+
+// Registry maps interceptor names to factories. It is safe for concurrent
+// use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry returns a Registry holding factories for these built-in
+// interceptors and options:
+//
+//   - base_url: url
+//   - header: name, value
+//   - user_agent: product, version
+//   - request_id: header
+//   - timeout, per_try_timeout: duration
+//   - retry: max_attempts, backoff, max_backoff, budget
+//   - circuit_breaker: threshold, cooldown, probes
+//   - rate_limit: rate, burst, per_host, no_wait
+//   - concurrency_limit: max, per_host, no_wait
+//   - compression
+//   - dump: body (writes to standard error)
+//   - curl_on_error: show_secrets (writes to standard error)
+//
+// Durations are given as strings such as "1.5s", or as numbers of seconds.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	registerBuiltins(r)
+	return r
+}
+
+// Register makes f available under name, replacing any factory already
+// registered under it.
+func (r *Registry) Register(name string, f Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = f
+}
+
+// Names returns the registered names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build returns a Pipeline around transport with the interceptors described by
+// doc. It fails if an entry names an unknown interceptor, has an option of the
+// wrong type, or has an option its factory does not use, so that typos are
+// caught rather than silently ignored.
+func (r *Registry) Build(doc Document, transport http.RoundTripper) (*interceptor.Pipeline, error) {
+	pipeline := interceptor.New(transport)
+	for i, entry := range doc.Interceptors {
+		r.mu.RLock()
+		factory, ok := r.factories[entry.Name]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("config: interceptor %d: unknown interceptor %q", i, entry.Name)
+		}
+
+		opts := &Options{values: entry.Options, used: make(map[string]bool)}
+		created, err := factory(opts)
+		if err == nil {
+			err = opts.check()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: interceptor %q: %w", entry.Name, err)
+		}
+		pipeline.UseNamed(entry.Name, created)
+	}
+	return pipeline, nil
+}
+
+// defaultRegistry is used by the package-level functions.
+var defaultRegistry = NewRegistry()
+
+// Register makes f available under name in the default registry.
+func Register(name string, f Factory) {
+	defaultRegistry.Register(name, f)
+}
+
+// Build returns a Pipeline built from doc with the default registry.
+func Build(doc Document, transport http.RoundTripper) (*interceptor.Pipeline, error) {
+	return defaultRegistry.Build(doc, transport)
+}
+
+// Load reads a JSON Document from r and builds it with the default registry.
+func Load(r io.Reader, transport http.RoundTripper) (*interceptor.Pipeline, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("config: invalid document: %w", err)
+	}
+	return Build(doc, transport)
+}
+
+// LoadWith reads a Document from r with unmarshal, such as yaml.Unmarshal from
+// gopkg.in/yaml.v3, and builds it with the default registry.
+func LoadWith(r io.Reader, unmarshal func(data []byte, v any) error, transport http.RoundTripper) (*interceptor.Pipeline, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("config: reading document: %w", err)
+	}
+	var doc Document
+	if err := unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config: invalid document: %w", err)
+	}
+	return Build(doc, transport)
+}
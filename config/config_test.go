@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+func TestLoad(t *testing.T) {
+	var received *http.Request
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	pipeline, err := Load(strings.NewReader(`{
+		"interceptors": [
+			{"name": "base_url", "options": {"url": "https://api.example.com"}},
+			{"name": "header", "options": {"name": "X-Realm", "value": "alpha"}},
+			{"name": "timeout", "options": {"duration": "5s"}},
+			{"name": "retry", "options": {"max_attempts": 2, "backoff": 0.01}}
+		]
+	}`), transport)
+	if err != nil {
+		t.Fatalf("Failed to load document: %v", err)
+	}
+
+	infos := pipeline.Interceptors()
+	if len(infos) != 4 || infos[0].Name != "base_url" || infos[3].Name != "retry" {
+		t.Errorf("Expected the interceptors in document order, got %+v", infos)
+	}
+
+	req, _ := http.NewRequest("GET", "/users", nil)
+	if _, err := pipeline.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if received.URL.String() != "https://api.example.com/users" || received.Header.Get("X-Realm") != "alpha" {
+		t.Errorf("Expected the configured interceptors to apply, got %s %v", received.URL, received.Header)
+	}
+}
+
+func TestLoadWith(t *testing.T) {
+	// A YAML library decodes whole numbers as ints rather than float64s.
+	unmarshal := func(data []byte, v any) error {
+		if string(data) != "interceptors: [...]" {
+			return errors.New("unexpected document")
+		}
+		*v.(*Document) = Document{Interceptors: []Entry{
+			{Name: "retry", Options: map[string]any{"max_attempts": 2, "backoff": 1}},
+			{Name: "concurrency_limit", Options: map[string]any{"max": 4}},
+		}}
+		return nil
+	}
+
+	pipeline, err := LoadWith(strings.NewReader("interceptors: [...]"), unmarshal, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("Failed to load document: %v", err)
+	}
+	if infos := pipeline.Interceptors(); len(infos) != 2 || infos[1].Name != "concurrency_limit" {
+		t.Errorf("Expected the decoded interceptors, got %+v", infos)
+	}
+
+	if _, err := LoadWith(strings.NewReader("not yaml"), unmarshal, http.DefaultTransport); err == nil {
+		t.Errorf("Expected an error for an invalid document")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    Entry
+		expected string
+	}{
+		{"unknown interceptor", Entry{Name: "nope"}, `unknown interceptor "nope"`},
+		{"unknown option", Entry{Name: "timeout", Options: map[string]any{"duration": "1s", "duraton": "2s"}}, "unknown options duraton"},
+		{"wrong type", Entry{Name: "retry", Options: map[string]any{"max_attempts": "three"}}, `option "max_attempts": expected an integer`},
+		{"invalid value", Entry{Name: "timeout"}, `option "duration" must be positive`},
+	}
+
+	for _, test := range tests {
+		_, err := Build(Document{Interceptors: []Entry{test.entry}}, nil)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	r.Register("tenant", func(o *Options) (interceptor.Interceptor, error) {
+		return interceptor.Header("X-Tenant", o.String("id", "")), nil
+	})
+
+	var received *http.Request
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	pipeline, err := r.Build(Document{Interceptors: []Entry{{Name: "tenant", Options: map[string]any{"id": "acme"}}}}, transport)
+	if err != nil {
+		t.Fatalf("Failed to build pipeline: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	pipeline.RoundTrip(req)
+	if received.Header.Get("X-Tenant") != "acme" {
+		t.Errorf("Expected the custom interceptor to apply, got %v", received.Header)
+	}
+}
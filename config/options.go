@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options holds the options of one Entry and gives typed access to them. Each
+// getter returns def when the option is not set. The first option of the
+// wrong type is reported as an error by Build after the factory returns, so
+// factories can read all their options before checking for errors.
+type Options struct {
+	values map[string]any
+	used   map[string]bool
+	err    error
+}
+
+// lookup returns the value of key and marks it as used.
+func (o *Options) lookup(key string) (any, bool) {
+	o.used[key] = true
+	v, ok := o.values[key]
+	return v, ok && v != nil
+}
+
+// fail records an error for key unless one was already recorded.
+func (o *Options) fail(key string, v any, want string) {
+	if o.err == nil {
+		o.err = fmt.Errorf("option %q: expected %s, got %v", key, want, v)
+	}
+}
+
+// String returns the value of key as a string.
+func (o *Options) String(key, def string) string {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	s, ok := v.(string)
+	if !ok {
+		o.fail(key, v, "a string")
+		return def
+	}
+	return s
+}
+
+// Float returns the value of key as a float64.
+func (o *Options) Float(key string, def float64) float64 {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	o.fail(key, v, "a number")
+	return def
+}
+
+// Int returns the value of key as an int.
+func (o *Options) Int(key string, def int) int {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		if n == float64(int(n)) {
+			return int(n)
+		}
+	}
+	o.fail(key, v, "an integer")
+	return def
+}
+
+// Bool returns the value of key as a bool.
+func (o *Options) Bool(key string, def bool) bool {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	b, ok := v.(bool)
+	if !ok {
+		o.fail(key, v, "a boolean")
+		return def
+	}
+	return b
+}
+
+// Duration returns the value of key as a time.Duration. The value is either a
+// string accepted by time.ParseDuration or a number of seconds.
+func (o *Options) Duration(key string, def time.Duration) time.Duration {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	switch d := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(d)
+		if err == nil {
+			return parsed
+		}
+	case float64:
+		return time.Duration(d * float64(time.Second))
+	case int:
+		return time.Duration(d) * time.Second
+	}
+	o.fail(key, v, "a duration")
+	return def
+}
+
+// check returns the first error recorded by a getter, or an error naming the
+// options that were set but never read.
+func (o *Options) check() error {
+	if o.err != nil {
+		return o.err
+	}
+	var unknown []string
+	for key := range o.values {
+		if !o.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
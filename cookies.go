@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+)
+
+type cookieJarKey struct{}
+
+// WithCookieJar returns a copy of ctx carrying jar. Requests made with the
+// returned context use jar instead of the one given to Cookies, which lets a
+// single Pipeline keep separate sessions, for example one per tenant.
+func WithCookieJar(ctx context.Context, jar http.CookieJar) context.Context {
+	return context.WithValue(ctx, cookieJarKey{}, jar)
+}
+
+// CookieJarFrom returns the cookie jar carried by ctx, if any.
+func CookieJarFrom(ctx context.Context) (http.CookieJar, bool) {
+	jar, ok := ctx.Value(cookieJarKey{}).(http.CookieJar)
+	return jar, ok && jar != nil
+}
+
+// Cookies returns an Interceptor that attaches the cookies in jar to each
+// request and stores the cookies set by each response in jar, like
+// http.Client does when its Jar is set. Handling cookies in the transport
+// gives every client sharing the Pipeline, and callers using it directly as an
+// http.RoundTripper, the same session.
+//
+// A jar carried by the request context, set with WithCookieJar, takes the
+// place of jar. If jar is nil, only requests carrying a jar in their context
+// are handled. The jar from net/http/cookiejar is a suitable implementation.
+// Clients using this interceptor should not also set http.Client.Jar, or
+// cookies are sent twice.
+func Cookies(jar http.CookieJar) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			jar := jar
+			if ctxJar, ok := CookieJarFrom(req.Context()); ok {
+				jar = ctxJar
+			}
+			if jar == nil {
+				return next.RoundTrip(req)
+			}
+
+			if cookies := jar.Cookies(req.URL); len(cookies) > 0 {
+				req = req.Clone(req.Context())
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if cookies := resp.Cookies(); len(cookies) > 0 {
+				jar.SetCookies(req.URL, cookies)
+			}
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+)
+
+// sessionTransport sets a session cookie on the first request and reports the
+// cookie sent with each later one.
+func sessionTransport(sent *[]string) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		cookie, err := req.Cookie("session")
+		if err != nil {
+			resp.Header.Add("Set-Cookie", "session=abc123; Path=/")
+			*sent = append(*sent, "")
+			return resp, nil
+		}
+		*sent = append(*sent, cookie.Value)
+		return resp, nil
+	})
+}
+
+func TestCookiesInterceptor(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	var sent []string
+	rt := Cookies(jar)(sessionTransport(&sent))
+
+	for range 2 {
+		req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+
+	if len(sent) != 2 || sent[0] != "" || sent[1] != "abc123" {
+		t.Errorf("Expected the session cookie to be sent on the second request, got %q", sent)
+	}
+}
+
+func TestCookiesInterceptorContextJar(t *testing.T) {
+	shared, _ := cookiejar.New(nil)
+	tenant, _ := cookiejar.New(nil)
+	var sent []string
+	rt := Cookies(shared)(sessionTransport(&sent))
+
+	ctx := WithCookieJar(context.Background(), tenant)
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	u := req.URL
+	if len(tenant.Cookies(u)) != 1 {
+		t.Errorf("Expected the cookie to be stored in the context jar")
+	}
+	if len(shared.Cookies(u)) != 0 {
+		t.Errorf("Expected the shared jar to be left untouched")
+	}
+}
@@ -0,0 +1,232 @@
+package interceptor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CurlOption configures the CurlOnError interceptor.
+type CurlOption func(*curlConfig)
+
+type curlConfig struct {
+	masked      map[string]bool
+	maskedQuery map[string]bool
+	showSecrets bool
+	maxBody     int64
+}
+
+// CurlMaskHeaders adds headers whose values are replaced with "***" in the
+// commands written by CurlOnError. Authorization, Proxy-Authorization, Cookie,
+// and X-API-Key are masked by default.
+func CurlMaskHeaders(names ...string) CurlOption {
+	return func(c *curlConfig) {
+		for _, name := range names {
+			c.masked[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// CurlMaskQuery adds query parameters whose values are replaced with "***" in
+// the commands written by CurlOnError. Names are compared case-insensitively.
+// access_token, api_key, apikey, client_secret, key, password, secret, sig,
+// signature, and token are masked by default.
+func CurlMaskQuery(names ...string) CurlOption {
+	return func(c *curlConfig) {
+		for _, name := range names {
+			c.maskedQuery[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// CurlShowSecrets disables masking, so that the commands written by
+// CurlOnError can be run as they are. They then contain credentials, including
+// passwords in URLs, and must not be written anywhere they could leak.
+func CurlShowSecrets() CurlOption {
+	return func(c *curlConfig) {
+		clear(c.masked)
+		clear(c.maskedQuery)
+		c.showSecrets = true
+	}
+}
+
+// CurlMaxBody sets how many bytes of a request body are kept for the command.
+// Larger bodies are left out of it. The default is 64 KiB, and 0 leaves out
+// all bodies.
+func CurlMaxBody(n int64) CurlOption {
+	return func(c *curlConfig) {
+		if n >= 0 {
+			c.maxBody = n
+		}
+	}
+}
+
+// CurlOnError returns an Interceptor that writes an equivalent curl command to
+// w for every request that fails or receives a 5xx response, so that it can be
+// reproduced by hand. Each command is written on its own line in a single call
+// to w, and writes are serialized, so w can be a log file or log.Writer().
+// Credentials in masked headers and query parameters, and passwords in the
+// URL, are replaced unless CurlShowSecrets is given.
+//
+// To include the body in the command, the body of a failed request is read
+// again through req.GetBody. Requests with a body but no GetBody can only be
+// read once, so up to the CurlMaxBody limit of their body is buffered in
+// memory before they are sent; the rest is streamed. The bodies of streaming
+// requests, as reported by IsStreamingRequest, are never buffered and are left
+// out of the command unless they have a GetBody.
+func CurlOnError(w io.Writer, opts ...CurlOption) Interceptor {
+	cfg := curlConfig{
+		masked: map[string]bool{
+			"Authorization":       true,
+			"Proxy-Authorization": true,
+			"Cookie":              true,
+			"X-Api-Key":           true,
+		},
+		maskedQuery: map[string]bool{
+			"access_token":  true,
+			"api_key":       true,
+			"apikey":        true,
+			"client_secret": true,
+			"key":           true,
+			"password":      true,
+			"secret":        true,
+			"sig":           true,
+			"signature":     true,
+			"token":         true,
+		},
+		maxBody: 64 << 10,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var mu sync.Mutex
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			getBody := req.GetBody
+			var body []byte
+			if getBody == nil && req.Body != nil && req.Body != http.NoBody && !IsStreamingRequest(req) {
+				head, err := io.ReadAll(io.LimitReader(req.Body, cfg.maxBody+1))
+				if err != nil {
+					req.Body.Close()
+					return nil, newError("CurlOnError", req, err)
+				}
+				original := req.Body
+				req = req.Clone(req.Context())
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(head), original), original}
+				body = head
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err == nil && resp.StatusCode < http.StatusInternalServerError {
+				return resp, nil
+			}
+			if getBody != nil {
+				if r, err := getBody(); err == nil {
+					body, _ = io.ReadAll(io.LimitReader(r, cfg.maxBody+1))
+					r.Close()
+				}
+			}
+			command := curlCommand(req, body, &cfg) + "\n"
+			mu.Lock()
+			io.WriteString(w, command)
+			mu.Unlock()
+			return resp, err
+		})
+	}
+}
+
+// ToCurl returns a curl command equivalent to req, including its credentials.
+// The body is included only if it can be read without consuming req.Body,
+// through req.GetBody.
+func ToCurl(req *http.Request) string {
+	var body []byte
+	if req.GetBody != nil {
+		if r, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(r)
+			r.Close()
+		}
+	}
+	return curlCommand(req, body, nil)
+}
+
+// curlCommand formats req and its body as a curl command, masking credentials
+// as configured by cfg. If cfg is nil, nothing is masked and the body is
+// always included.
+func curlCommand(req *http.Request, body []byte, cfg *curlConfig) string {
+	var masked map[string]bool
+	target := req.URL.String()
+	if cfg != nil {
+		masked = cfg.masked
+		target = cfg.maskURL(req.URL)
+	}
+
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != "" && req.Method != http.MethodGet {
+		fmt.Fprintf(&b, " -X %s", req.Method)
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(target))
+
+	header := req.Header.Clone()
+	if req.Host != "" && req.Host != req.URL.Host {
+		header.Set("Host", req.Host)
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			if masked[name] {
+				value = "***"
+			}
+			fmt.Fprintf(&b, " -H %s", shellQuote(name+": "+value))
+		}
+	}
+
+	switch {
+	case cfg != nil && int64(len(body)) > cfg.maxBody:
+		fmt.Fprintf(&b, " # request body over %d bytes left out", cfg.maxBody)
+	case len(body) > 0:
+		fmt.Fprintf(&b, " --data-binary %s", shellQuote(string(body)))
+	}
+	return b.String()
+}
+
+// maskURL returns u with its password redacted, unless secrets are shown, and
+// the values of masked query parameters replaced with "***". The order of the
+// query parameters is kept.
+func (c *curlConfig) maskURL(u *url.URL) string {
+	masked := *u
+	if !c.showSecrets {
+		if _, ok := masked.User.Password(); ok {
+			masked.User = url.UserPassword(masked.User.Username(), "xxxxx")
+		}
+	}
+	if len(c.maskedQuery) > 0 && masked.RawQuery != "" {
+		params := strings.Split(masked.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && c.maskedQuery[strings.ToLower(name)] {
+				params[i] = key + "=***"
+			}
+		}
+		masked.RawQuery = strings.Join(params, "&")
+	}
+	return masked.String()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
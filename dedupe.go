@@ -0,0 +1,115 @@
+package interceptor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Dedupe returns an Interceptor that collapses concurrent identical GET and
+// HEAD requests into a single upstream call. The first request for a key is
+// sent, and requests with the same key that arrive while it is in flight wait
+// for its response instead of being sent themselves. The response body is read
+// into memory once and every caller receives its own copy of the response.
+//
+// keyFunc identifies identical requests, and requests for which it returns an
+// empty string are never collapsed. If keyFunc is nil, requests are keyed by
+// method and URL, so a custom keyFunc should be used when headers such as
+// Authorization change the response.
+//
+// Waiting callers share the outcome of the request that was sent, including an
+// error caused by the cancellation of its context, but stop waiting as soon as
+// their own context is done.
+//
+// Streaming requests, as reported by IsStreamingRequest, are never collapsed.
+// If the response turns out to be a stream, as reported by
+// IsStreamingResponse, it is not buffered, and the waiting callers send their
+// own requests instead.
+func Dedupe(keyFunc func(*http.Request) string) Interceptor {
+	if keyFunc == nil {
+		keyFunc = func(req *http.Request) string {
+			return req.Method + " " + req.URL.String()
+		}
+	}
+
+	var mu sync.Mutex
+	calls := make(map[string]*dedupeCall)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			if req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next.RoundTrip(req)
+			}
+			key := keyFunc(req)
+			if key == "" || IsStreamingRequest(req) {
+				return next.RoundTrip(req)
+			}
+
+			mu.Lock()
+			if call, ok := calls[key]; ok {
+				mu.Unlock()
+				select {
+				case <-call.done:
+					if call.streaming {
+						return next.RoundTrip(req)
+					}
+					return call.response(req)
+				case <-req.Context().Done():
+					return nil, newError("Dedupe", req, req.Context().Err())
+				}
+			}
+			call := &dedupeCall{done: make(chan struct{})}
+			calls[key] = call
+			mu.Unlock()
+
+			call.resp, call.err = next.RoundTrip(req)
+			call.streaming = call.err == nil && IsStreamingResponse(call.resp)
+			if call.err == nil && !call.streaming {
+				if call.body, call.err = io.ReadAll(call.resp.Body); call.err != nil {
+					call.err = newError("Dedupe", req, call.err)
+				}
+				call.resp.Body.Close()
+			}
+
+			mu.Lock()
+			delete(calls, key)
+			mu.Unlock()
+			close(call.done)
+
+			if call.streaming {
+				return call.resp, nil
+			}
+			return call.response(req)
+		})
+	}
+}
+
+// dedupeCall is a request in flight whose outcome is shared by every caller
+// that asked for the same key while it was running.
+type dedupeCall struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+	// streaming is set if resp is a stream, which is given to the caller that
+	// sent the request, while the other callers send their own.
+	streaming bool
+}
+
+// response returns a copy of the shared response for req, with its own header
+// and body, so that callers cannot affect each other.
+func (c *dedupeCall) response(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Trailer = c.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	resp.Request = req
+	return &resp, nil
+}
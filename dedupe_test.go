@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupeInterceptor(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Test": {"value"}},
+			Body:       io.NopCloser(strings.NewReader("body")),
+		}, nil
+	})
+	rt := Dedupe(nil)(transport)
+
+	const callers = 5
+	bodies := make([]string, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "http://example.com/resource", nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Errorf("Failed to perform request: %v", err)
+				return
+			}
+			resp.Header.Set("X-Test", "changed")
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			bodies[i] = string(body)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected concurrent requests to share 1 upstream call, got %d", n)
+	}
+	for i, body := range bodies {
+		if body != "body" {
+			t.Errorf("Expected caller %d to read the full body, got %q", i, body)
+		}
+	}
+}
+
+func TestDedupeInterceptorSkipsRequests(t *testing.T) {
+	var calls atomic.Int32
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := Dedupe(func(req *http.Request) string {
+		if req.Header.Get("Cache-Control") == "no-cache" {
+			return ""
+		}
+		return req.URL.String()
+	})(transport)
+
+	post, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+	if _, err := rt.RoundTrip(post); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	get, _ := http.NewRequest("GET", "http://example.com", nil)
+	get.Header.Set("Cache-Control", "no-cache")
+	if _, err := rt.RoundTrip(get); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected 2 upstream calls, got %d", n)
+	}
+}
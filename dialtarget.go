@@ -0,0 +1,175 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type dialTargetKey struct{}
+
+// WithDialTarget returns a copy of ctx carrying target. Requests made with the
+// returned context are connected to target by the DialTarget interceptor,
+// whatever the host of their URL.
+func WithDialTarget(ctx context.Context, target string) context.Context {
+	return context.WithValue(ctx, dialTargetKey{}, target)
+}
+
+// DialTargetFrom returns the dial target carried by ctx, if any.
+func DialTargetFrom(ctx context.Context) (string, bool) {
+	target, ok := ctx.Value(dialTargetKey{}).(string)
+	return target, ok && target != ""
+}
+
+// DialTargetOption configures the DialTarget interceptor.
+type DialTargetOption func(*dialTargetConfig)
+
+type dialTargetConfig struct {
+	base          *http.Transport
+	selector      func(*http.Request) (string, error)
+	dialer        *net.Dialer
+	schemes       map[string]func(ctx context.Context, address string) (net.Conn, error)
+	maxTransports int
+}
+
+// DialTargetTransport sets the transport that the transports for each dial
+// target are cloned from, so that they share its timeouts and TLS settings.
+// The default is http.DefaultTransport.
+func DialTargetTransport(base *http.Transport) DialTargetOption {
+	return func(c *dialTargetConfig) {
+		if base != nil {
+			c.base = base
+		}
+	}
+}
+
+// DialTargetFunc sets a function choosing the dial target of requests whose
+// context carries none. If it returns an empty target, the request is passed
+// down the chain unchanged.
+func DialTargetFunc(selector func(*http.Request) (string, error)) DialTargetOption {
+	return func(c *dialTargetConfig) {
+		c.selector = selector
+	}
+}
+
+// DialTargetDialer sets the dialer used for the network schemes, such as unix
+// and tcp, for example to set timeouts or a local address.
+func DialTargetDialer(d *net.Dialer) DialTargetOption {
+	return func(c *dialTargetConfig) {
+		if d != nil {
+			c.dialer = d
+		}
+	}
+}
+
+// DialTargetScheme makes targets with the given scheme, such as
+// "vsock://3:1024", connect with dial, which receives the rest of the target
+// as address.
+func DialTargetScheme(scheme string, dial func(ctx context.Context, address string) (net.Conn, error)) DialTargetOption {
+	return func(c *dialTargetConfig) {
+		if c.schemes == nil {
+			c.schemes = make(map[string]func(context.Context, string) (net.Conn, error))
+		}
+		c.schemes[scheme] = dial
+	}
+}
+
+// DialTargetMaxTransports sets the number of transports, one per dial target,
+// that DialTarget keeps. Once it holds more, the least recently used one is
+// dropped and its idle connections are closed. The default is 64, and zero or
+// less means no limit.
+func DialTargetMaxTransports(n int) DialTargetOption {
+	return func(c *dialTargetConfig) {
+		c.maxTransports = n
+	}
+}
+
+// DialTarget returns an Interceptor that connects requests to a dial target
+// chosen per request, with WithDialTarget or DialTargetFunc, instead of the
+// host of their URL, which is left intact and still sent in the Host header.
+// This lets a Pipeline talk to services on Unix sockets, such as the Docker
+// daemon:
+//
+//	ctx = interceptor.WithDialTarget(ctx, "unix:///var/run/docker.sock")
+//	req, _ := http.NewRequestWithContext(ctx, "GET", "http://docker/v1.41/containers/json", nil)
+//
+// Targets are written as network://address, where network is unix, tcp,
+// tcp4, tcp6, or a scheme registered with DialTargetScheme. An invalid target
+// fails the request.
+//
+// The dialer of an http.Transport cannot vary per request, so DialTarget keeps
+// one transport per distinct target, without a proxy, and sends each request
+// through the transport for its target. Like ClientCert, it should be the last
+// interceptor in the chain, it keeps at most DialTargetMaxTransports
+// transports, and Pipeline.Shutdown closes the idle connections of those it
+// holds.
+func DialTarget(opts ...DialTargetOption) Interceptor {
+	cfg := dialTargetConfig{dialer: &net.Dialer{}, maxTransports: defaultMaxTransports}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.base == nil {
+		cfg.base = defaultTransport()
+	}
+
+	transports := newTransportPool[string](cfg.maxTransports)
+	transportFor := func(target string) (*http.Transport, error) {
+		return transports.get(target, func() (*http.Transport, error) {
+			dial, err := cfg.dialFunc(target)
+			if err != nil {
+				return nil, err
+			}
+			transport := cfg.base.Clone()
+			transport.Proxy = nil
+			transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dial(ctx)
+			}
+			return transport, nil
+		})
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withClose(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			target, ok := DialTargetFrom(req.Context())
+			if !ok && cfg.selector != nil {
+				var err error
+				if target, err = cfg.selector(req); err != nil {
+					return nil, newError("DialTarget", req, err)
+				}
+			}
+			if target == "" {
+				return next.RoundTrip(req)
+			}
+			if IsPreparing(req.Context()) {
+				return nil, newError("DialTarget", req, ErrNotPrepared)
+			}
+			transport, err := transportFor(target)
+			if err != nil {
+				return nil, newError("DialTarget", req, err)
+			}
+			return transport.RoundTrip(req)
+		}), transports.Close)
+	}
+}
+
+// dialFunc returns a function connecting to target.
+func (c *dialTargetConfig) dialFunc(target string) (func(context.Context) (net.Conn, error), error) {
+	scheme, address, ok := strings.Cut(target, "://")
+	if !ok || address == "" {
+		return nil, fmt.Errorf("interceptor: invalid dial target %q", target)
+	}
+	if dial, ok := c.schemes[scheme]; ok {
+		return func(ctx context.Context) (net.Conn, error) {
+			return dial(ctx, address)
+		}, nil
+	}
+	switch scheme {
+	case "unix", "tcp", "tcp4", "tcp6":
+		return func(ctx context.Context) (net.Conn, error) {
+			return c.dialer.DialContext(ctx, scheme, address)
+		}, nil
+	}
+	return nil, fmt.Errorf("interceptor: unsupported dial target scheme %q", scheme)
+}
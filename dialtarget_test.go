@@ -0,0 +1,56 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDialTargetInterceptor(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets are unavailable: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host+r.URL.Path)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	var fallback bool
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		fallback = true
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := DialTarget()(transport)
+
+	ctx := WithDialTarget(context.Background(), "unix://"+socket)
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://docker/v1.41/containers/json", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request over the socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "docker/v1.41/containers/json" {
+		t.Errorf("Expected the logical URL to be kept, got %s", body)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	rt.RoundTrip(req)
+	if !fallback {
+		t.Error("Expected a request without a dial target to go down the chain")
+	}
+
+	req, _ = http.NewRequestWithContext(WithDialTarget(context.Background(), "carrier-pigeon://coop"), "GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "unsupported dial target") {
+		t.Errorf("Expected an unsupported dial target error, got %v", err)
+	}
+}
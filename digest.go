@@ -0,0 +1,274 @@
+package interceptor
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DigestAuth returns an Interceptor that authenticates requests with HTTP
+// Digest authentication as described in RFC 7616, using the MD5 and SHA-256
+// algorithms and their session variants with qop=auth.
+//
+// When the server responds with 401 Unauthorized and a Digest challenge in
+// WWW-Authenticate, the response is computed and the request is replayed once
+// with it. If the server offers several challenges, SHA-256 is preferred. The
+// challenge is cached per host, so later requests to the same host are
+// authorized up front without the extra round trip; if the server rejects them
+// because the nonce has expired, the new challenge replaces the cached one.
+//
+// Requests with a body can only be replayed if req.GetBody is set; otherwise
+// the 401 response is returned to the caller unchanged. If GetBody fails, the
+// request fails with its error.
+func DigestAuth(username, password string) Interceptor {
+	var mu sync.Mutex
+	challenges := make(map[string]*digestChallenge)
+	challengeFor := func(host string) *digestChallenge {
+		mu.Lock()
+		defer mu.Unlock()
+		return challenges[host]
+	}
+	setChallenge := func(host string, c *digestChallenge) {
+		mu.Lock()
+		defer mu.Unlock()
+		challenges[host] = c
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		authorize := func(req *http.Request, c *digestChallenge) (*http.Request, error) {
+			authorization, err := c.authorization(username, password, req)
+			if err != nil {
+				return nil, newError("DigestAuth", req, err)
+			}
+			clone := withHeader(req)
+			clone.Header.Set("Authorization", authorization)
+			return clone, nil
+		}
+
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent := req
+			if c := challengeFor(req.URL.Host); c != nil {
+				var err error
+				if sent, err = authorize(req, c); err != nil {
+					return nil, err
+				}
+			}
+
+			resp, err := next.RoundTrip(sent)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			c := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+			if c == nil {
+				return resp, nil
+			}
+			setChallenge(req.URL.Host, c)
+
+			// Only replay requests whose body can be read a second time.
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+			retry, err := authorize(req, c)
+			if err != nil {
+				discard(resp)
+				return nil, err
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					discard(resp)
+					return nil, newError("DigestAuth", req, err)
+				}
+				retry.Body = body
+			}
+			// Release the connection held by the rejected response before replaying.
+			discard(resp)
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+// digestChallenge holds the parameters of a Digest challenge and the number
+// of requests authorized with its nonce so far.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       bool
+
+	mu    sync.Mutex
+	count uint32
+}
+
+// digestAlgorithms lists the supported algorithms in order of preference.
+var digestAlgorithms = []string{"SHA-256", "SHA-256-SESS", "MD5", "MD5-SESS"}
+
+// parseDigestChallenge returns the preferred supported Digest challenge among
+// the WWW-Authenticate header values, or nil if there is none.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	rank := len(digestAlgorithms)
+	for _, params := range parseChallenges(values, "digest") {
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+		if qop, ok := params["qop"]; ok {
+			for _, option := range strings.Split(qop, ",") {
+				if strings.EqualFold(strings.TrimSpace(option), "auth") {
+					c.qop = true
+				}
+			}
+			// qop=auth-int alone requires hashing the body, which is not supported.
+			if !c.qop {
+				continue
+			}
+		}
+		for i, algorithm := range digestAlgorithms {
+			if strings.EqualFold(c.algorithm, algorithm) && i < rank && c.nonce != "" {
+				best, rank = c, i
+			}
+		}
+	}
+	return best
+}
+
+// authorization returns the Authorization header value answering c for req.
+func (c *digestChallenge) authorization(username, password string, req *http.Request) (string, error) {
+	algorithm := strings.ToUpper(c.algorithm)
+	var newHash func() hash.Hash = md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(random)
+	c.mu.Lock()
+	c.count++
+	nc := fmt.Sprintf("%08x", c.count)
+	c.mu.Unlock()
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	uri := req.URL.RequestURI()
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	params := []string{
+		"username=" + quote(username),
+		"realm=" + quote(c.realm),
+		"nonce=" + quote(c.nonce),
+		"uri=" + quote(uri),
+		"algorithm=" + c.algorithm,
+	}
+	if c.qop {
+		response := h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+		params = append(params, "response="+quote(response), "qop=auth", "nc="+nc, "cnonce="+quote(cnonce))
+	} else {
+		// RFC 2069 compatibility for servers that do not send qop.
+		params = append(params, "response="+quote(h(ha1+":"+c.nonce+":"+ha2)))
+	}
+	if c.opaque != "" {
+		params = append(params, "opaque="+quote(c.opaque))
+	}
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// parseChallenges returns the parameters of each challenge with the given
+// scheme in the WWW-Authenticate header values. Parameter names are lowercased
+// and quoted values unescaped.
+func parseChallenges(values []string, scheme string) []map[string]string {
+	var challenges []map[string]string
+	for _, value := range values {
+		var current map[string]string
+		s := value
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if s == "" {
+				break
+			}
+			end := strings.IndexAny(s, " \t,=")
+			if end < 0 {
+				end = len(s)
+			}
+			token := s[:end]
+			s = strings.TrimLeft(s[end:], " \t")
+
+			if !strings.HasPrefix(s, "=") {
+				// A token that is not followed by "=" starts a new challenge.
+				current = nil
+				if strings.EqualFold(token, scheme) {
+					current = make(map[string]string)
+					challenges = append(challenges, current)
+				}
+				continue
+			}
+
+			s = strings.TrimLeft(s[1:], " \t")
+			var param string
+			if strings.HasPrefix(s, `"`) {
+				param, s = unquote(s)
+			} else {
+				end := strings.IndexAny(s, " \t,")
+				if end < 0 {
+					end = len(s)
+				}
+				param, s = s[:end], s[end:]
+			}
+			if current != nil {
+				current[strings.ToLower(token)] = param
+			}
+		}
+	}
+	return challenges
+}
+
+// quote returns s as an HTTP quoted-string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// unquote returns the quoted string at the start of s, with backslash escapes
+// removed, and the rest of s after the closing quote.
+func unquote(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
@@ -0,0 +1,196 @@
+package interceptor
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// digestServer is a fake transport requiring Digest authentication with the
+// credentials alice:s3cret.
+type digestServer struct {
+	challenges []string
+	// nonce is the nonce the server currently accepts.
+	nonce    string
+	requests int
+	// nc is the nonce count of the last accepted request.
+	nc string
+}
+
+func (s *digestServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests++
+	if s.valid(req) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header), Body: http.NoBody}
+	for _, challenge := range s.challenges {
+		resp.Header.Add("WWW-Authenticate", challenge)
+	}
+	return resp, nil
+}
+
+func (s *digestServer) valid(req *http.Request) bool {
+	params := parseChallenges([]string{req.Header.Get("Authorization")}, "digest")
+	if len(params) != 1 || params[0]["nonce"] != s.nonce || params[0]["uri"] != req.URL.RequestURI() {
+		return false
+	}
+	p := params[0]
+	var newHash func() hash.Hash = md5.New
+	if strings.HasPrefix(p["algorithm"], "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+	ha1 := h("alice:" + p["realm"] + ":s3cret")
+	if strings.HasSuffix(p["algorithm"], "-sess") {
+		ha1 = h(ha1 + ":" + p["nonce"] + ":" + p["cnonce"])
+	}
+	ha2 := h(req.Method + ":" + p["uri"])
+	s.nc = p["nc"]
+	return p["qop"] == "auth" && p["opaque"] == "xyz" &&
+		p["response"] == h(ha1+":"+p["nonce"]+":"+p["nc"]+":"+p["cnonce"]+":auth:"+ha2)
+}
+
+func TestDigestAuthInterceptor(t *testing.T) {
+	server := &digestServer{
+		challenges: []string{`Digest realm="api@example.com", qop="auth, auth-int", algorithm=MD5, nonce="n1", opaque="xyz"`},
+		nonce:      "n1",
+	}
+	rt := DigestAuth("alice", "s3cret")(server)
+
+	req, _ := http.NewRequest("GET", "http://example.com/dir/index.html?x=1", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if server.requests != 2 {
+		t.Errorf("Expected the challenge to cost one extra request, got %d requests", server.requests)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("Expected original request to be left unmodified")
+	}
+
+	// The cached challenge authorizes the next request up front.
+	server.requests = 0
+	resp, err = rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v, %v", resp, err)
+	}
+	if server.requests != 1 {
+		t.Errorf("Expected a single request with the cached challenge, got %d", server.requests)
+	}
+	if server.nc != "00000002" {
+		t.Errorf("Expected nonce count 00000002, got %s", server.nc)
+	}
+
+	// An expired nonce is replaced by the new challenge.
+	server.requests = 0
+	server.nonce = "n2"
+	server.challenges = []string{`Digest realm="api@example.com", qop="auth", nonce="n2", opaque="xyz", stale=true`}
+	resp, err = rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v, %v", resp, err)
+	}
+	if server.requests != 2 || server.nc != "00000001" {
+		t.Errorf("Expected a replay with a fresh nonce count, got %d requests and nc %s", server.requests, server.nc)
+	}
+}
+
+func TestDigestAuthPrefersSHA256(t *testing.T) {
+	server := &digestServer{
+		challenges: []string{
+			`Basic realm="api", Digest realm="api", qop="auth", algorithm=MD5, nonce="n1", opaque="xyz"`,
+			`Digest realm="api", qop="auth", algorithm=SHA-256-sess, nonce="n1", opaque="xyz"`,
+		},
+		nonce: "n1",
+	}
+	var algorithm string
+	rt := DigestAuth("alice", "s3cret")(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if params := parseChallenges([]string{req.Header.Get("Authorization")}, "digest"); len(params) == 1 {
+			algorithm = params[0]["algorithm"]
+		}
+		return server.RoundTrip(req)
+	}))
+
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v, %v", resp, err)
+	}
+	if algorithm != "SHA-256-sess" {
+		t.Errorf("Expected algorithm SHA-256-sess, got %q", algorithm)
+	}
+}
+
+func TestDigestAuthBody(t *testing.T) {
+	server := &digestServer{
+		challenges: []string{`Digest realm="api", qop="auth", nonce="n1", opaque="xyz"`},
+		nonce:      "n1",
+	}
+	rt := DigestAuth("alice", "s3cret")(server)
+
+	// A body that can be read again is replayed.
+	req, _ := http.NewRequest("POST", "http://example.com/", strings.NewReader("payload"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v, %v", resp, err)
+	}
+
+	// One that cannot is not, and the 401 is returned to the caller.
+	rt = DigestAuth("alice", "s3cret")(server)
+	req, _ = http.NewRequest("POST", "http://other.example.com/", strings.NewReader("payload"))
+	req.GetBody = nil
+	resp, err = rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+
+	// A body that fails to be read again fails the request.
+	failure := errors.New("body unavailable")
+	rt = DigestAuth("alice", "s3cret")(server)
+	req, _ = http.NewRequest("POST", "http://third.example.com/", strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, failure }
+	_, err = rt.RoundTrip(req)
+	var ierr *Error
+	if !errors.Is(err, failure) || !errors.As(err, &ierr) || ierr.Interceptor != "DigestAuth" {
+		t.Errorf("Expected an *Error from DigestAuth wrapping the GetBody error, got %v", err)
+	}
+}
+
+func TestDigestAuthWithoutChallenge(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Header:     http.Header{"Www-Authenticate": {`Bearer realm="api"`}},
+			Body:       http.NoBody,
+		},
+	}
+	rt := DigestAuth("alice", "s3cret")(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+	if mockRT.Request.Header.Get("Authorization") != "" {
+		t.Errorf("Expected no Authorization header without a Digest challenge")
+	}
+}
@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+type dumpKey struct{}
+
+// WithDump returns a copy of ctx that enables dumping for requests made with
+// it when the Dump interceptor is configured with DumpOnlyWhenEnabled.
+func WithDump(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dumpKey{}, true)
+}
+
+// DumpEnabled reports whether ctx was returned by WithDump.
+func DumpEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(dumpKey{}).(bool)
+	return enabled
+}
+
+// DumpOption configures the Dump interceptor.
+type DumpOption func(*dumpConfig)
+
+type dumpConfig struct {
+	body            bool
+	color           bool
+	onlyWhenEnabled bool
+}
+
+// DumpBody sets whether request and response bodies are included in the
+// transcript. Bodies are included by default. Dumping a body reads it into
+// memory, which may not be suitable for large or streaming responses.
+func DumpBody(include bool) DumpOption {
+	return func(c *dumpConfig) {
+		c.body = include
+	}
+}
+
+// DumpColor highlights requests and responses with ANSI escape codes, for
+// writing to a terminal. Responses with a 4xx or 5xx status are shown in red.
+func DumpColor() DumpOption {
+	return func(c *dumpConfig) {
+		c.color = true
+	}
+}
+
+// DumpOnlyWhenEnabled restricts dumping to requests whose context was returned
+// by WithDump, so that the interceptor can stay installed in production and be
+// switched on for a single call.
+func DumpOnlyWhenEnabled() DumpOption {
+	return func(c *dumpConfig) {
+		c.onlyWhenEnabled = true
+	}
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiCyan  = "\x1b[36m"
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+)
+
+// Dump returns an Interceptor that writes a wire-format transcript of every
+// request and response to w, using httputil.DumpRequestOut and
+// httputil.DumpResponse. Each request and each response is written in a single
+// call to w, and writes are serialized. The bodies of streaming responses, as
+// reported by IsStreamingRequest and IsStreamingResponse, are not dumped.
+func Dump(w io.Writer, opts ...DumpOption) Interceptor {
+	cfg := dumpConfig{body: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var mu sync.Mutex
+	write := func(color string, dump []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if cfg.color {
+			fmt.Fprintf(w, "%s%s%s\n", color, dump, ansiReset)
+		} else {
+			fmt.Fprintf(w, "%s\n", dump)
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if cfg.onlyWhenEnabled && !DumpEnabled(req.Context()) {
+				return next.RoundTrip(req)
+			}
+
+			// DumpRequestOut replaces the body it reads, so dump a copy.
+			req = req.Clone(req.Context())
+			dump, err := httputil.DumpRequestOut(req, cfg.body)
+			if err != nil {
+				dump = []byte(fmt.Sprintf("dump request: %v", err))
+			}
+			write(ansiCyan, dump)
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				write(ansiRed, []byte(fmt.Sprintf("error: %v", err)))
+				return nil, err
+			}
+
+			color := ansiGreen
+			if resp.StatusCode >= http.StatusBadRequest {
+				color = ansiRed
+			}
+			// Reading the body of a stream would hold it back from the caller
+			// until it ends, so only its header is dumped.
+			body := cfg.body && !IsStreamingRequest(req) && !IsStreamingResponse(resp)
+			dump, err = httputil.DumpResponse(resp, body)
+			if err != nil {
+				dump = []byte(fmt.Sprintf("dump response: %v", err))
+			}
+			write(color, dump)
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDumpInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"X-Reply": {"yes"}},
+			Body:       io.NopCloser(strings.NewReader("echo:" + string(body))),
+		}, nil
+	})
+
+	tests := []struct {
+		name       string
+		opts       []DumpOption
+		expected   []string
+		unexpected []string
+	}{
+		{
+			"with bodies", nil,
+			[]string{"POST /submit HTTP/1.1", "Host: example.com", "request-body", "HTTP/1.1 200 OK", "X-Reply: yes", "echo:request-body"},
+			[]string{"\x1b["},
+		},
+		{
+			"without bodies", []DumpOption{DumpBody(false)},
+			[]string{"POST /submit HTTP/1.1", "HTTP/1.1 200 OK"},
+			[]string{"request-body", "echo:request-body"},
+		},
+		{
+			"color", []DumpOption{DumpColor()},
+			[]string{ansiCyan + "POST /submit", ansiGreen + "HTTP/1.1 200 OK", ansiReset},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		rt := Dump(&out, test.opts...)(transport)
+
+		req, _ := http.NewRequest("POST", "http://example.com/submit", strings.NewReader("request-body"))
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if body := readBody(t, resp); body != "echo:request-body" {
+			t.Errorf("%s: expected body to be intact after dumping, got '%s'", test.name, body)
+		}
+
+		for _, s := range test.expected {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("%s: expected transcript to contain %q\n%s", test.name, s, out.String())
+			}
+		}
+		for _, s := range test.unexpected {
+			if strings.Contains(out.String(), s) {
+				t.Errorf("%s: expected transcript not to contain %q\n%s", test.name, s, out.String())
+			}
+		}
+	}
+}
+
+func TestDumpInterceptorOnlyWhenEnabled(t *testing.T) {
+	var out bytes.Buffer
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := Dump(&out, DumpOnlyWhenEnabled())(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com/quiet", nil)
+	rt.RoundTrip(req)
+	if out.Len() != 0 {
+		t.Errorf("Expected no output without WithDump, got %s", out.String())
+	}
+
+	req, _ = http.NewRequestWithContext(WithDump(context.Background()), "GET", "http://example.com/loud", nil)
+	rt.RoundTrip(req)
+	if !strings.Contains(out.String(), "GET /loud") {
+		t.Errorf("Expected request enabled with WithDump to be dumped, got %s", out.String())
+	}
+}
+
+func TestDumpInterceptorError(t *testing.T) {
+	var out bytes.Buffer
+	rt := Dump(&out)(&mockRoundTripper{Err: errors.New("connection refused")})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatalf("Expected request to fail")
+	}
+	if !strings.Contains(out.String(), "error: connection refused") {
+		t.Errorf("Expected error to be dumped, got %s", out.String())
+	}
+}
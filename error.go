@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is returned by the built-in interceptors when they fail a request
+// themselves, for example because a rate limit is exceeded, a circuit is
+// open, or a token cannot be obtained. It records which interceptor failed
+// and for which request, and wraps the cause, so that callers can tell the
+// layers of a Pipeline apart with errors.As while still matching sentinel
+// errors such as ErrRateLimited with errors.Is:
+//
+//	var ierr *interceptor.Error
+//	if errors.As(err, &ierr) && ierr.Interceptor == "CircuitBreaker" {
+//		// ...
+//	}
+//
+// Errors returned by the next transport in the chain are passed on unchanged,
+// so an error without an *Error in its chain was not caused by a built-in
+// interceptor. Failed responses are reported by ErrorFromStatus as a
+// *StatusError, which carries the request itself.
+type Error struct {
+	// Interceptor is the name of the function that created the failing
+	// interceptor, such as "RateLimit".
+	Interceptor string
+	// Method is the method of the failed request.
+	Method string
+	// URL is the URL of the failed request, with any password redacted.
+	URL string
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s: %v", e.Method, e.URL, e.Interceptor, e.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError returns an *Error recording that the named interceptor failed req
+// with err. If req is nil, the Error records no method or URL.
+func newError(interceptor string, req *http.Request, err error) *Error {
+	e := &Error{Interceptor: interceptor, Err: err}
+	if req == nil {
+		return e
+	}
+	e.Method = req.Method
+	if e.Method == "" {
+		e.Method = http.MethodGet
+	}
+	if req.URL != nil {
+		e.URL = req.URL.Redacted()
+	}
+	return e
+}
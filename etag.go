@@ -0,0 +1,166 @@
+package interceptor
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrPreconditionFailed is returned by the OptimisticConcurrency interceptor
+// when the server rejects a modification with 412 Precondition Failed because
+// the resource changed since it was last read.
+var ErrPreconditionFailed = errors.New("interceptor: precondition failed")
+
+// OptimisticConcurrencyOption configures the OptimisticConcurrency interceptor.
+type OptimisticConcurrencyOption func(*optimisticConcurrencyConfig)
+
+type optimisticConcurrencyConfig struct {
+	maxEntries int
+}
+
+// OptimisticConcurrencyMaxEntries sets the number of ETags, one per URL, that
+// OptimisticConcurrency remembers. Once it holds more, the least recently used
+// one is forgotten. The default is 10000, and zero or less means no limit.
+func OptimisticConcurrencyMaxEntries(n int) OptimisticConcurrencyOption {
+	return func(c *optimisticConcurrencyConfig) {
+		c.maxEntries = n
+	}
+}
+
+// OptimisticConcurrency returns an Interceptor that implements optimistic
+// concurrency control with ETags. It remembers the ETag of each successful GET
+// response, keyed by URL, and sends it in an If-Match header with later PUT,
+// PATCH, and DELETE requests to the same URL, so that the server rejects
+// modifications of a resource that changed since it was read.
+//
+// A 412 Precondition Failed response is returned as an error wrapping
+// ErrPreconditionFailed, and the remembered ETag is forgotten so that the
+// resource can be read again. The ETag returned by a successful modification
+// replaces the remembered one. Requests that already carry If-Match are sent
+// unchanged. At most OptimisticConcurrencyMaxEntries ETags are remembered.
+func OptimisticConcurrency(opts ...OptimisticConcurrencyOption) Interceptor {
+	cfg := optimisticConcurrencyConfig{maxEntries: 10000}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	etags := newETagStore(cfg.maxEntries)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := etagKey(req)
+			switch req.Method {
+			case "", http.MethodGet:
+				resp, err := next.RoundTrip(req)
+				if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+					if etag := resp.Header.Get("ETag"); etag != "" {
+						etags.set(key, etag)
+					} else {
+						etags.delete(key)
+					}
+				}
+				return resp, err
+
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				return next.RoundTrip(req)
+			}
+
+			if req.Header.Get("If-Match") == "" {
+				if etag, ok := etags.get(key); ok {
+					req = req.Clone(req.Context())
+					req.Header.Set("If-Match", etag)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case resp.StatusCode == http.StatusPreconditionFailed:
+				resp.Body.Close()
+				etags.delete(key)
+				return nil, newError("OptimisticConcurrency", req, ErrPreconditionFailed)
+			case resp.StatusCode >= 200 && resp.StatusCode < 300:
+				if etag := resp.Header.Get("ETag"); etag != "" && req.Method != http.MethodDelete {
+					etags.set(key, etag)
+				} else {
+					etags.delete(key)
+				}
+			}
+			return resp, nil
+		})
+	}
+}
+
+// etagKey identifies the resource a request addresses.
+func etagKey(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// etagStore holds the ETags remembered by OptimisticConcurrency, dropping the
+// least recently used one once it holds more than max.
+type etagStore struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List
+	etags map[string]*list.Element
+}
+
+type etagStoreEntry struct {
+	key  string
+	etag string
+}
+
+func newETagStore(max int) *etagStore {
+	return &etagStore{
+		max:   max,
+		order: list.New(),
+		etags: make(map[string]*list.Element),
+	}
+}
+
+// get returns the ETag remembered for key.
+func (s *etagStore) get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.etags[key]
+	if !ok {
+		return "", false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*etagStoreEntry).etag, true
+}
+
+// set remembers etag for key.
+func (s *etagStore) set(key, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.etags[key]; ok {
+		e.Value.(*etagStoreEntry).etag = etag
+		s.order.MoveToFront(e)
+		return
+	}
+	s.etags[key] = s.order.PushFront(&etagStoreEntry{key: key, etag: etag})
+	if s.max > 0 && s.order.Len() > s.max {
+		oldest := s.order.Remove(s.order.Back()).(*etagStoreEntry)
+		delete(s.etags, oldest.key)
+	}
+}
+
+// delete forgets the ETag remembered for key.
+func (s *etagStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.etags[key]; ok {
+		s.order.Remove(e)
+		delete(s.etags, key)
+	}
+}
@@ -0,0 +1,91 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestOptimisticConcurrencyInterceptor(t *testing.T) {
+	version := 1
+	var ifMatch []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		etag := `"v` + strconv.Itoa(version) + `"`
+		if req.Method == http.MethodGet {
+			resp.Header.Set("ETag", etag)
+			return resp, nil
+		}
+		ifMatch = append(ifMatch, req.Header.Get("If-Match"))
+		if match := req.Header.Get("If-Match"); match != "" && match != etag {
+			resp.StatusCode = http.StatusPreconditionFailed
+			return resp, nil
+		}
+		version++
+		resp.Header.Set("ETag", `"v`+strconv.Itoa(version)+`"`)
+		return resp, nil
+	})
+	rt := OptimisticConcurrency()(transport)
+
+	get, _ := http.NewRequest("GET", "http://example.com/users/1", nil)
+	put, _ := http.NewRequest("PUT", "http://example.com/users/1", nil)
+	if _, err := rt.RoundTrip(get); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if _, err := rt.RoundTrip(put); err != nil {
+		t.Fatalf("Expected the update to succeed, got %v", err)
+	}
+	// The ETag of the update is used for the next one.
+	if _, err := rt.RoundTrip(put); err != nil {
+		t.Fatalf("Expected the second update to succeed, got %v", err)
+	}
+	if len(ifMatch) != 2 || ifMatch[0] != `"v1"` || ifMatch[1] != `"v2"` {
+		t.Errorf("Expected If-Match to follow the remembered ETags, got %q", ifMatch)
+	}
+
+	// Another client changes the resource.
+	version++
+	if _, err := rt.RoundTrip(put); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+	if _, err := rt.RoundTrip(put); err != nil {
+		t.Errorf("Expected the ETag to be forgotten after a 412, got %v", err)
+	}
+}
+
+func TestOptimisticConcurrencyInterceptorMaxEntries(t *testing.T) {
+	ifMatch := make(map[string]string)
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		if req.Method == http.MethodGet {
+			resp.Header.Set("ETag", `"`+req.URL.Path+`"`)
+		} else {
+			ifMatch[req.URL.Path] = req.Header.Get("If-Match")
+		}
+		return resp, nil
+	})
+	rt := OptimisticConcurrency(OptimisticConcurrencyMaxEntries(2))(transport)
+
+	do := func(method, path string) {
+		req, _ := http.NewRequest(method, "http://example.com"+path, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+	// Reading /1 again makes /2 the least recently used when /3 is read.
+	do("GET", "/1")
+	do("GET", "/2")
+	do("GET", "/1")
+	do("GET", "/3")
+	do("DELETE", "/1")
+	do("DELETE", "/2")
+	do("DELETE", "/3")
+
+	if ifMatch["/1"] != `"/1"` || ifMatch["/3"] != `"/3"` {
+		t.Errorf("Expected the recently used ETags to be sent, got %q", ifMatch)
+	}
+	if ifMatch["/2"] != "" {
+		t.Errorf("Expected the least recently used ETag to be forgotten, got %q", ifMatch["/2"])
+	}
+}
@@ -0,0 +1,157 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// FailoverOption configures the Failover interceptor.
+type FailoverOption func(*failoverConfig)
+
+type failoverConfig struct {
+	statusCodes map[int]bool
+	cooldown    time.Duration
+	now         func() time.Time
+}
+
+// FailoverStatusCodes sets the response status codes that cause the next target
+// to be tried. The default is 502, 503, and 504.
+func FailoverStatusCodes(codes ...int) FailoverOption {
+	return func(c *failoverConfig) {
+		c.statusCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			c.statusCodes[code] = true
+		}
+	}
+}
+
+// FailoverCooldown sets how long a target is skipped after it fails. The
+// default is 30 seconds.
+func FailoverCooldown(d time.Duration) FailoverOption {
+	return func(c *failoverConfig) {
+		if d > 0 {
+			c.cooldown = d
+		}
+	}
+}
+
+// Failover returns an Interceptor that sends each request to the first of
+// targets and, if it fails with a transport error or one of the configured
+// status codes, tries the following targets in order. The scheme and host of
+// the request URL are replaced with the target's, and the request path is
+// joined to the target's path as with BaseURL.
+//
+// A target that fails is skipped for the cooldown period, so later requests go
+// straight to a healthy target. If every target is cooling down they are all
+// tried anyway, in order. The response or error from the last attempt is
+// returned.
+//
+// Requests with a body can only be sent to more than one target if
+// req.GetBody is set. Failover panics if targets is empty.
+func Failover(targets []url.URL, opts ...FailoverOption) Interceptor {
+	if len(targets) == 0 {
+		panic("interceptor: Failover requires at least one target")
+	}
+	targets = append([]url.URL(nil), targets...)
+	cfg := failoverConfig{
+		statusCodes: map[int]bool{
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+		cooldown: 30 * time.Second,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	downUntil := make([]time.Time, len(targets))
+	// order returns the indexes of the targets to try, healthy ones first.
+	order := func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		now := cfg.now()
+		var healthy []int
+		for i := range targets {
+			if !now.Before(downUntil[i]) {
+				healthy = append(healthy, i)
+			}
+		}
+		if len(healthy) > 0 {
+			return healthy
+		}
+		all := make([]int, len(targets))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	record := func(i int, failed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed {
+			downUntil[i] = cfg.now().Add(cfg.cooldown)
+		} else {
+			downUntil[i] = time.Time{}
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts := order()
+			// Only send requests whose body can be read a second time to more
+			// than one target.
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				attempts = attempts[:1]
+			}
+
+			var resp *http.Response
+			var err error
+			for n, i := range attempts {
+				attempt := retarget(req, &targets[i])
+				if n > 0 && req.GetBody != nil {
+					if attempt.Body, err = req.GetBody(); err != nil {
+						return nil, newError("Failover", req, err)
+					}
+				}
+
+				resp, err = next.RoundTrip(attempt)
+				if err != nil && (errors.Is(err, context.Canceled) || req.Context().Err() != nil) {
+					return nil, err
+				}
+				failed := err != nil || cfg.statusCodes[resp.StatusCode]
+				record(i, failed)
+				if !failed || n == len(attempts)-1 {
+					break
+				}
+				if resp != nil {
+					// Release the connection held by the failed response.
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}
+			return resp, err
+		})
+	}
+}
+
+// retarget returns a copy of req addressed to target: the URL takes the
+// target's scheme, user info, and host, and its path is joined to the target's
+// path.
+func retarget(req *http.Request, target *url.URL) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = target.Scheme
+	clone.URL.User = target.User
+	clone.URL.Host = target.Host
+	clone.URL.Path = target.JoinPath(req.URL.Path).Path
+	clone.URL.RawPath = ""
+	clone.Host = ""
+	return clone
+}
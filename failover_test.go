@@ -0,0 +1,134 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostTransport answers requests according to their host and records the
+// hosts it was sent to.
+type hostTransport struct {
+	status map[string]int
+	hosts  []string
+	paths  []string
+}
+
+func (h *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.hosts = append(h.hosts, req.URL.Host)
+	h.paths = append(h.paths, req.URL.Path)
+	status, ok := h.status[req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(req.URL.Host))}, nil
+}
+
+func mustParseURLs(t *testing.T, raw ...string) []url.URL {
+	urls := make([]url.URL, len(raw))
+	for i, s := range raw {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls[i] = *u
+	}
+	return urls
+}
+
+func TestFailoverInterceptor(t *testing.T) {
+	now := time.Unix(0, 0)
+	transport := &hostTransport{status: map[string]int{
+		"secondary.example.com": http.StatusServiceUnavailable,
+		"tertiary.example.com":  http.StatusOK,
+	}}
+	targets := mustParseURLs(t,
+		"https://primary.example.com/v1",
+		"https://secondary.example.com/v1",
+		"https://tertiary.example.com/v1",
+	)
+	rt := Failover(targets, func(c *failoverConfig) { c.now = func() time.Time { return now } })(transport)
+
+	send := func() string {
+		req, _ := http.NewRequest("GET", "/users", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if host := send(); host != "tertiary.example.com" {
+		t.Errorf("Expected the response from the tertiary target, got '%s'", host)
+	}
+	expected := []string{"primary.example.com", "secondary.example.com", "tertiary.example.com"}
+	if !slices.Equal(transport.hosts, expected) {
+		t.Errorf("Expected targets to be tried in order %v, got %v", expected, transport.hosts)
+	}
+	if transport.paths[0] != "/v1/users" {
+		t.Errorf("Expected path to be joined with the target's, got '%s'", transport.paths[0])
+	}
+
+	// Failed targets are skipped during the cooldown.
+	transport.hosts = nil
+	send()
+	if expected := []string{"tertiary.example.com"}; !slices.Equal(transport.hosts, expected) {
+		t.Errorf("Expected failed targets to be skipped, got %v", transport.hosts)
+	}
+
+	// Once the cooldown has elapsed the primary is tried again.
+	now = now.Add(time.Minute)
+	transport.status["primary.example.com"] = http.StatusOK
+	transport.hosts = nil
+	if host := send(); host != "primary.example.com" {
+		t.Errorf("Expected the recovered primary to be used, got '%s'", host)
+	}
+}
+
+func TestFailoverInterceptorAllFailing(t *testing.T) {
+	transport := &hostTransport{status: map[string]int{
+		"secondary.example.com": http.StatusBadGateway,
+	}}
+	targets := mustParseURLs(t, "https://primary.example.com", "https://secondary.example.com")
+	rt := Failover(targets)(transport)
+
+	for i := 0; i < 2; i++ {
+		transport.hosts = nil
+		req, _ := http.NewRequest("GET", "/", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("Expected the last target's response, got %d", resp.StatusCode)
+		}
+		if len(transport.hosts) != 2 {
+			t.Errorf("Expected every target to be tried, got %v", transport.hosts)
+		}
+	}
+}
+
+func TestFailoverInterceptorBody(t *testing.T) {
+	transport := &hostTransport{status: map[string]int{"secondary.example.com": http.StatusOK}}
+	targets := mustParseURLs(t, "https://primary.example.com", "https://secondary.example.com")
+
+	req, _ := http.NewRequest("POST", "/", io.NopCloser(strings.NewReader("payload")))
+	if _, err := Failover(targets)(transport).RoundTrip(req); err == nil {
+		t.Errorf("Expected a request without GetBody to be sent to one target only")
+	}
+
+	transport.hosts = nil
+	req, _ = http.NewRequest("POST", "/", strings.NewReader("payload"))
+	if _, err := Failover(targets)(transport).RoundTrip(req); err != nil {
+		t.Errorf("Expected a replayable request to fail over, got %v", err)
+	}
+	if len(transport.hosts) != 2 {
+		t.Errorf("Expected both targets to be tried, got %v", transport.hosts)
+	}
+}
@@ -0,0 +1,383 @@
+package interceptor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// HARRecorder collects traffic recorded by the HAR interceptor and writes it as
+// an HTTP Archive (HAR) 1.2 document, which browser developer tools and many
+// other tools can open. It is safe for concurrent use.
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewHARRecorder returns an empty HARRecorder.
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// Len returns the number of entries recorded.
+func (r *HARRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// WriteTo writes the recorded entries to w as a HAR document. It implements
+// io.WriterTo.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	entries := append([]harEntry(nil), r.entries...)
+	r.mu.Unlock()
+	return writeHAR(w, entries)
+}
+
+// Flush writes the recorded entries to w like WriteTo and removes them from
+// the recorder, so that a long session can be saved in parts.
+func (r *HARRecorder) Flush(w io.Writer) error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = nil
+	r.mu.Unlock()
+	_, err := writeHAR(w, entries)
+	return err
+}
+
+func (r *HARRecorder) add(entry harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func writeHAR(w io.Writer, entries []harEntry) (int64, error) {
+	if entries == nil {
+		entries = []harEntry{}
+	}
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "github.com/brain-hol/http-interceptors-go", Version: "1.0"},
+		Entries: entries,
+	}}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// HAROption configures the HAR interceptor.
+type HAROption func(*harConfig)
+
+type harConfig struct {
+	bodyLimit int64
+	flushTo   io.Writer
+}
+
+// HARBodyLimit sets how many bytes of each request and response body are
+// recorded. Larger bodies are truncated, though their full size is still
+// recorded. The default is 1 MiB, and 0 records no bodies.
+func HARBodyLimit(n int64) HAROption {
+	return func(c *harConfig) {
+		if n >= 0 {
+			c.bodyLimit = n
+		}
+	}
+}
+
+// HARFlushOnClose makes Pipeline.Shutdown flush the entries recorded by the
+// HAR interceptor to w with HARRecorder.Flush, so that traffic recorded until
+// the Pipeline is shut down is saved.
+func HARFlushOnClose(w io.Writer) HAROption {
+	return func(c *harConfig) {
+		c.flushTo = w
+	}
+}
+
+// HAR returns an Interceptor that records each request and its response in
+// recorder, with their headers, bodies up to a size limit, and timings. An
+// entry is added once the response body has been read to the end or closed,
+// or the request has failed. Failed requests are recorded with status 0 and
+// the error in the entry's _error field.
+//
+// Only the recorded part of a request body is held in memory; the rest is
+// streamed to the transport. Bodies of streaming requests, as reported by
+// IsStreamingRequest, are not recorded.
+//
+// Recorded traffic includes headers such as Authorization and cookies, so HAR
+// files should be handled as secrets.
+func HAR(recorder *HARRecorder, opts ...HAROption) Interceptor {
+	cfg := harConfig{bodyLimit: 1 << 20}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			var head []byte
+			var sent *harRequestBody
+			if req.Body != nil && req.Body != http.NoBody && !IsStreamingRequest(req) {
+				var err error
+				head, err = io.ReadAll(io.LimitReader(req.Body, cfg.bodyLimit))
+				if err != nil {
+					req.Body.Close()
+					return nil, newError("HAR", req, err)
+				}
+				sent = &harRequestBody{Reader: io.MultiReader(bytes.NewReader(head), req.Body), Closer: req.Body}
+				req.Body = sent
+			}
+
+			entry := harEntry{
+				StartedDateTime: time.Now().Format(time.RFC3339Nano),
+				Request:         harRequestOf(req, head),
+				Cache:           struct{}{},
+			}
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			entry.Timings = harTimings{Wait: millis(time.Since(start))}
+			switch {
+			case sent != nil:
+				entry.Request.BodySize = sent.n.Load()
+			case req.Body != nil && req.Body != http.NoBody:
+				entry.Request.BodySize = -1
+			}
+			if err != nil {
+				entry.Time = entry.Timings.Wait
+				entry.Response = harResponse{Cookies: []harPair{}, Headers: []harPair{}, HeadersSize: -1, BodySize: -1}
+				entry.Error = err.Error()
+				recorder.add(entry)
+				return nil, err
+			}
+
+			entry.Response = harResponseOf(resp)
+			received := time.Now()
+			finish := func(content []byte, size int64) {
+				entry.Timings.Receive = millis(time.Since(received))
+				entry.Time = entry.Timings.Wait + entry.Timings.Receive
+				entry.Response.Content.Size = size
+				entry.Response.BodySize = size
+				entry.Response.Content.Text, entry.Response.Content.Encoding = harText(content)
+				recorder.add(entry)
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				finish(nil, 0)
+				return resp, nil
+			}
+			resp.Body = &harBody{ReadCloser: resp.Body, limit: cfg.bodyLimit, finish: finish}
+			return resp, nil
+		})
+		if cfg.flushTo != nil {
+			return withClose(transport, func() error {
+				return recorder.Flush(cfg.flushTo)
+			})
+		}
+		return transport
+	}
+}
+
+// harRequestBody counts the bytes of a request body sent by the transport,
+// which may read it from another goroutine.
+type harRequestBody struct {
+	io.Reader
+	io.Closer
+	n atomic.Int64
+}
+
+func (b *harRequestBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// harBody records a response body as it is read and reports it once the body
+// is consumed or closed.
+type harBody struct {
+	io.ReadCloser
+	content bytes.Buffer
+	size    int64
+	limit   int64
+	once    sync.Once
+	finish  func(content []byte, size int64)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if keep := min(int64(n), b.limit-int64(b.content.Len())); keep > 0 {
+		b.content.Write(p[:keep])
+	}
+	b.size += int64(n)
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *harBody) done() {
+	b.once.Do(func() {
+		b.finish(b.content.Bytes(), b.size)
+	})
+}
+
+// millis returns d in fractional milliseconds, the unit of HAR timings.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harText returns body as HAR content text and its encoding, which is "base64"
+// for bodies that are not valid UTF-8.
+func harText(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// harRequestOf returns the HAR form of req with the recorded part of its body.
+// The body size is filled in once the request has been sent.
+func harRequestOf(req *http.Request, body []byte) harRequest {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	r := harRequest{
+		Method:      method,
+		URL:         req.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harPair{},
+		Headers:     harPairs(req.Header),
+		QueryString: harPairs(req.URL.Query()),
+		HeadersSize: -1,
+	}
+	for _, cookie := range req.Cookies() {
+		r.Cookies = append(r.Cookies, harPair{Name: cookie.Name, Value: cookie.Value})
+	}
+	if len(body) > 0 {
+		r.PostData = &harPostData{MimeType: req.Header.Get("Content-Type")}
+		r.PostData.Text, _ = harText(body)
+	}
+	return r
+}
+
+func harResponseOf(resp *http.Response) harResponse {
+	r := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harPair{},
+		Headers:     harPairs(resp.Header),
+		Content:     harContent{MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	if r.HTTPVersion == "" {
+		r.HTTPVersion = "HTTP/1.1"
+	}
+	for _, cookie := range resp.Cookies() {
+		r.Cookies = append(r.Cookies, harPair{Name: cookie.Name, Value: cookie.Value})
+	}
+	return r
+}
+
+// harPairs flattens a header or query map into HAR name/value pairs.
+func harPairs(values map[string][]string) []harPair {
+	pairs := []harPair{}
+	for name, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, harPair{Name: name, Value: v})
+		}
+	}
+	return pairs
+}
+
+// The types below mirror the HAR 1.2 format.
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int64      `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
@@ -0,0 +1,171 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHARInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/fail" {
+			return nil, errors.New("connection refused")
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != `{"name":"alice"}` {
+			t.Errorf("Expected the request body to reach the transport, got %q", body)
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Proto:      "HTTP/1.1",
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id":1,"name":"alice"}`)),
+		}, nil
+	})
+	recorder := NewHARRecorder()
+	rt := HAR(recorder, HARBodyLimit(8))(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com/users?source=test", strings.NewReader(`{"name":"alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if recorder.Len() != 0 {
+		t.Errorf("Expected the entry to be recorded only once the body is consumed")
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	failing, _ := http.NewRequest("GET", "http://example.com/fail", nil)
+	rt.RoundTrip(failing)
+
+	var out bytes.Buffer
+	if err := recorder.Flush(&out); err != nil {
+		t.Fatalf("Failed to write HAR: %v", err)
+	}
+	if recorder.Len() != 0 {
+		t.Errorf("Expected Flush to remove the entries")
+	}
+
+	var doc struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					Method      string `json:"method"`
+					QueryString []struct {
+						Name, Value string
+					} `json:"queryString"`
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						Size int64  `json:"size"`
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+				Error string `json:"_error"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse HAR: %v", err)
+	}
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 2 {
+		t.Fatalf("Expected a HAR 1.2 log with 2 entries, got %s", out.String())
+	}
+
+	entry := doc.Log.Entries[0]
+	if entry.Request.Method != "POST" || entry.Request.PostData.Text != `{"name":` {
+		t.Errorf("Expected the request with a truncated body, got %+v", entry.Request)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0].Value != "test" {
+		t.Errorf("Expected the query string to be recorded, got %+v", entry.Request.QueryString)
+	}
+	if entry.Response.Status != http.StatusCreated || entry.Response.Content.Size != 23 || entry.Response.Content.Text != `{"id":1,` {
+		t.Errorf("Expected the response with its full size and a truncated body, got %+v", entry.Response)
+	}
+	if failed := doc.Log.Entries[1]; failed.Response.Status != 0 || failed.Error != "connection refused" {
+		t.Errorf("Expected the failed request to be recorded with its error, got %+v", failed)
+	}
+}
+
+// readCounter counts the bytes read from a request body.
+type readCounter struct {
+	io.Reader
+	n int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestHARInterceptorStreamsLargeRequestBodies(t *testing.T) {
+	large := strings.Repeat("a", 64<<10)
+	var body *readCounter
+	var readBefore int
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		readBefore = body.n
+		sent, _ := io.ReadAll(req.Body)
+		if string(sent) != large {
+			t.Errorf("Expected the whole request body to reach the transport, got %d bytes", len(sent))
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	recorder := NewHARRecorder()
+	rt := HAR(recorder, HARBodyLimit(16))(transport)
+
+	body = &readCounter{Reader: strings.NewReader(large)}
+	req, _ := http.NewRequest("POST", "http://example.com/upload", body)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if readBefore > 16 {
+		t.Errorf("Expected at most 16 bytes to be read before sending, got %d", readBefore)
+	}
+
+	body = &readCounter{Reader: strings.NewReader(large)}
+	streaming, _ := http.NewRequestWithContext(WithStreaming(context.Background()), "POST", "http://example.com/upload", body)
+	if _, err := rt.RoundTrip(streaming); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if readBefore != 0 {
+		t.Errorf("Expected the streaming request body not to be read before sending, got %d bytes", readBefore)
+	}
+
+	var out bytes.Buffer
+	recorder.WriteTo(&out)
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					BodySize int64 `json:"bodySize"`
+					PostData *struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse HAR: %v", err)
+	}
+	recorded := doc.Log.Entries[0].Request
+	if recorded.BodySize != int64(len(large)) || recorded.PostData == nil || recorded.PostData.Text != large[:16] {
+		t.Errorf("Expected the full size and a truncated body, got %+v", recorded)
+	}
+	if stream := doc.Log.Entries[1].Request; stream.BodySize != -1 || stream.PostData != nil {
+		t.Errorf("Expected the streaming request body not to be recorded, got %+v", stream)
+	}
+}
@@ -0,0 +1,141 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HedgeOption configures the Hedge interceptor.
+type HedgeOption func(*hedgeConfig)
+
+type hedgeConfig struct {
+	allMethods bool
+}
+
+// HedgeAllMethods allows requests that are not idempotent to be hedged. Only
+// use it when the server tolerates receiving the same request more than once.
+func HedgeAllMethods() HedgeOption {
+	return func(c *hedgeConfig) {
+		c.allMethods = true
+	}
+}
+
+// Hedge returns an Interceptor that reduces tail latency by sending up to
+// maxHedges duplicates of a request, each one delay after the previous, while
+// no response has arrived. The first successful response, one without a
+// transport error or 5xx status, is returned and the other attempts are
+// canceled. If every attempt fails, the last failure is returned.
+//
+// Only requests for which IsIdempotent reports true are hedged, unless
+// HedgeAllMethods is given, and requests with a body must set req.GetBody.
+// Other requests are sent once.
+func Hedge(delay time.Duration, maxHedges int, opts ...HedgeOption) Interceptor {
+	var cfg hedgeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			if maxHedges < 1 || (!cfg.allMethods && !IsIdempotent(req)) ||
+				(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+				return next.RoundTrip(req)
+			}
+			return hedge(next, req, delay, maxHedges)
+		})
+	}
+}
+
+// hedgeResult is the outcome of one attempt made by Hedge.
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func hedge(next http.RoundTripper, req *http.Request, delay time.Duration, maxHedges int) (*http.Response, error) {
+	results := make(chan hedgeResult, maxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		attempt := req.Clone(ctx)
+		if len(cancels) > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			attempt.Body = body
+		}
+		n := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := next.RoundTrip(attempt)
+			results <- hedgeResult{n, resp, err}
+		}()
+		return nil
+	}
+	// finish returns r to the caller, canceling every other attempt and
+	// discarding the results still pending.
+	finish := func(r hedgeResult, pending int) (*http.Response, error) {
+		for n, cancel := range cancels {
+			if n != r.attempt {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				if late := <-results; late.err == nil {
+					late.resp.Body.Close()
+				}
+			}
+		}()
+
+		cancel := cancels[r.attempt]
+		if r.err != nil {
+			cancel()
+			return nil, r.err
+		}
+		if r.resp.Body == nil || r.resp.Body == http.NoBody {
+			cancel()
+			return r.resp, nil
+		}
+		r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: cancel}
+		return r.resp, nil
+	}
+
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var failed *hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) <= maxHedges && launch() == nil {
+				pending++
+				timer.Reset(delay)
+			}
+		case r := <-results:
+			pending--
+			// Keep only the latest failure, in case every attempt fails.
+			if failed != nil && failed.err == nil {
+				failed.resp.Body.Close()
+			}
+			if r.err == nil && r.resp.StatusCode < http.StatusInternalServerError {
+				return finish(r, pending)
+			}
+			if pending == 0 {
+				return finish(r, 0)
+			}
+			failed = &r
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// attemptTransport answers the nth attempt after delays[n], with the attempt
+// number as the body, and reports attempts that were canceled.
+type attemptTransport struct {
+	delays   []time.Duration
+	status   int
+	attempts atomic.Int32
+	canceled chan int
+}
+
+func (a *attemptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := int(a.attempts.Add(1)) - 1
+	select {
+	case <-time.After(a.delays[n]):
+		return &http.Response{StatusCode: a.status, Body: io.NopCloser(strings.NewReader(strconv.Itoa(n)))}, nil
+	case <-req.Context().Done():
+		a.canceled <- n
+		return nil, req.Context().Err()
+	}
+}
+
+func TestHedgeInterceptor(t *testing.T) {
+	transport := &attemptTransport{
+		delays:   []time.Duration{time.Second, 0},
+		status:   http.StatusOK,
+		canceled: make(chan int, 2),
+	}
+	rt := Hedge(10*time.Millisecond, 2)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "1" {
+		t.Errorf("Expected the response of the hedged attempt, got attempt %s", body)
+	}
+	select {
+	case n := <-transport.canceled:
+		if n != 0 {
+			t.Errorf("Expected the slow attempt to be canceled, got attempt %d", n)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("Expected the slow attempt to be canceled")
+	}
+	if attempts := transport.attempts.Load(); attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestHedgeInterceptorFastResponse(t *testing.T) {
+	transport := &attemptTransport{delays: []time.Duration{0}, status: http.StatusOK}
+	rt := Hedge(100*time.Millisecond, 2)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if attempts := transport.attempts.Load(); attempts != 1 {
+		t.Errorf("Expected a fast response not to be hedged, got %d attempts", attempts)
+	}
+}
+
+func TestHedgeInterceptorAllFailing(t *testing.T) {
+	transport := &attemptTransport{
+		delays: []time.Duration{30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond},
+		status: http.StatusServiceUnavailable,
+	}
+	rt := Hedge(5*time.Millisecond, 2)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last failure to be returned, got %d", resp.StatusCode)
+	}
+	if attempts := transport.attempts.Load(); attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestHedgeInterceptorMethods(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []HedgeOption
+		expected int32
+	}{
+		{"not idempotent", nil, 1},
+		{"all methods", []HedgeOption{HedgeAllMethods()}, 2},
+	}
+
+	for _, test := range tests {
+		transport := &attemptTransport{
+			delays:   []time.Duration{30 * time.Millisecond, 0},
+			status:   http.StatusOK,
+			canceled: make(chan int, 2),
+		}
+		rt := Hedge(5*time.Millisecond, 1, test.opts...)(transport)
+
+		req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if attempts := transport.attempts.Load(); attempts != test.expected {
+			t.Errorf("%s: expected %d attempts, got %d", test.name, test.expected, attempts)
+		}
+	}
+}
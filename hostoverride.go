@@ -0,0 +1,93 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/tls"
+	"maps"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type overriddenHostKey struct{}
+
+// OverriddenHostFrom returns the host a request was addressed to before
+// HostOverride redirected it, if it was.
+func OverriddenHostFrom(ctx context.Context) (string, bool) {
+	host, ok := ctx.Value(overriddenHostKey{}).(string)
+	return host, ok
+}
+
+// HostOverride returns an Interceptor that sends requests for the hosts in
+// overrides to other addresses, such as "api.example.com" to
+// "localhost:8443", without changing DNS or running a proxy. Keys match either
+// the request's host and port or just its host name, and a value without a
+// port keeps the request's port.
+//
+// Only the network address changes: the Host header still names the original
+// host, and the original host is stored in the request context, where
+// OverriddenHostFrom can read it. An http.Transport sets the TLS server name
+// from the URL it connects to, so to send the original host as SNI and verify
+// the server's certificate against it, use HostOverrideDialTLSContext as the
+// transport's DialTLSContext.
+func HostOverride(overrides map[string]string) Interceptor {
+	overrides = maps.Clone(overrides)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			target, ok := overrides[req.URL.Host]
+			if !ok {
+				if target, ok = overrides[req.URL.Hostname()]; !ok {
+					return next.RoundTrip(req)
+				}
+			}
+			if port := req.URL.Port(); port != "" && !hasPort(target) {
+				target = net.JoinHostPort(target, port)
+			}
+
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			req = req.Clone(context.WithValue(req.Context(), overriddenHostKey{}, host))
+			req.URL.Host = target
+			req.Host = host
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// HostOverrideDialTLSContext returns a function for http.Transport's
+// DialTLSContext that, for requests redirected by HostOverride, uses the
+// original host as the TLS server name and verifies the server's certificate
+// against it. Other connections use the address being dialed, like the
+// transport's default. config may be nil.
+//
+// The transport pools connections by the address dialed, so a target address
+// should only be used for one original host.
+func HostOverrideDialTLSContext(config *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		cfg := config.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			serverName := addr
+			if host, ok := OverriddenHostFrom(ctx); ok {
+				serverName = host
+			}
+			if h, _, err := net.SplitHostPort(serverName); err == nil {
+				serverName = h
+			}
+			cfg.ServerName = serverName
+		}
+		dialer := &tls.Dialer{Config: cfg}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// hasPort reports whether hostport ends in a port, allowing for bracketed IPv6
+// addresses.
+func hasPort(hostport string) bool {
+	colon := strings.LastIndexByte(hostport, ':')
+	return colon >= 0 && colon > strings.LastIndexByte(hostport, ']')
+}
@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostOverrideInterceptor(t *testing.T) {
+	var originalHost string
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		originalHost, _ = OverriddenHostFrom(req.Context())
+		return mockRT.RoundTrip(req)
+	})
+	rt := HostOverride(map[string]string{
+		"api.example.com":      "localhost:8443",
+		"auth.example.com:444": "127.0.0.1:9000",
+		"cdn.example.com":      "localhost",
+	})(transport)
+
+	tests := []struct {
+		url          string
+		expectedHost string
+		expectedURL  string
+	}{
+		{"https://api.example.com/users", "api.example.com", "https://localhost:8443/users"},
+		{"https://auth.example.com:444/token", "auth.example.com:444", "https://127.0.0.1:9000/token"},
+		{"http://cdn.example.com:8080/logo.png", "cdn.example.com:8080", "http://localhost:8080/logo.png"},
+		{"https://other.example.com/", "", "https://other.example.com/"},
+	}
+
+	for _, test := range tests {
+		originalHost = ""
+		req, _ := http.NewRequest("GET", test.url, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if sent := mockRT.Request.URL.String(); sent != test.expectedURL {
+			t.Errorf("Expected URL to be '%s', got '%s'", test.expectedURL, sent)
+		}
+		if test.expectedHost != "" && mockRT.Request.Host != test.expectedHost {
+			t.Errorf("Expected Host header '%s', got '%s'", test.expectedHost, mockRT.Request.Host)
+		}
+		if originalHost != test.expectedHost {
+			t.Errorf("Expected original host '%s' in the context, got '%s'", test.expectedHost, originalHost)
+		}
+		if req.URL.String() != test.url {
+			t.Errorf("Expected original request URL to be left as '%s', got '%s'", test.url, req.URL.String())
+		}
+	}
+}
+
+func TestHostOverrideDialTLSContext(t *testing.T) {
+	var serverName, hostHeader string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+		hostHeader = r.Host
+		io.WriteString(w, "ok")
+	}))
+	server.StartTLS()
+	defer server.Close()
+
+	// The test server's certificate is valid for *.example.com.
+	roots := server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	transport := &http.Transport{
+		DialTLSContext: HostOverrideDialTLSContext(&tls.Config{RootCAs: roots}),
+	}
+	addr := strings.TrimPrefix(server.URL, "https://")
+	client := NewClient(
+		ClientTransport(transport),
+		ClientInterceptors(HostOverride(map[string]string{"api.example.com": addr})),
+	)
+
+	resp, err := client.Get("https://api.example.com/")
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	resp.Body.Close()
+
+	if serverName != "api.example.com" {
+		t.Errorf("Expected SNI 'api.example.com', got '%s'", serverName)
+	}
+	if hostHeader != "api.example.com" {
+		t.Errorf("Expected Host header 'api.example.com', got '%s'", hostHeader)
+	}
+}
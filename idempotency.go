@@ -0,0 +1,146 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// DefaultIdempotencyKeyHeader is the header used by IdempotencyKey unless
+// configured otherwise.
+const DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyKey struct{}
+
+// idempotencyHeaderKey is the context key of the header name in which the
+// IdempotencyKey interceptor sent a request's key, for IsIdempotent.
+type idempotencyHeaderKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx carrying key. Requests made with the
+// returned context are sent by IdempotencyKey with this key instead of a
+// derived one.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKeyFrom returns the idempotency key carried by ctx, if any.
+func IdempotencyKeyFrom(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyKey{}).(string)
+	return key, ok && key != ""
+}
+
+// IdempotencyKeyOption configures the IdempotencyKey interceptor.
+type IdempotencyKeyOption func(*idempotencyKeyConfig)
+
+type idempotencyKeyConfig struct {
+	header   string
+	methods  map[string]bool
+	generate func(req *http.Request, body []byte) string
+}
+
+// IdempotencyKeyHeader sets the header the key is sent in. The default is
+// DefaultIdempotencyKeyHeader.
+func IdempotencyKeyHeader(name string) IdempotencyKeyOption {
+	return func(c *idempotencyKeyConfig) {
+		if name != "" {
+			c.header = name
+		}
+	}
+}
+
+// IdempotencyKeyMethods sets the methods that are given a key. The default is
+// POST and PATCH, the methods that are not idempotent by definition.
+func IdempotencyKeyMethods(methods ...string) IdempotencyKeyOption {
+	return func(c *idempotencyKeyConfig) {
+		c.methods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			c.methods[method] = true
+		}
+	}
+}
+
+// IdempotencyKeyGenerator sets the function used to derive a key when none is
+// carried by the context or already set on the request. It receives the
+// request and its body, which is read into memory for it. The default
+// generates a random (version 4) UUID for each request without reading its
+// body.
+func IdempotencyKeyGenerator(f func(req *http.Request, body []byte) string) IdempotencyKeyOption {
+	return func(c *idempotencyKeyConfig) {
+		if f != nil {
+			c.generate = f
+		}
+	}
+}
+
+// IdempotencyKeyHashBody derives keys from a SHA-256 hash of the method, URL,
+// and body of requests instead of generating random ones. Every attempt at a
+// request then gets the same key wherever IdempotencyKey is placed in the
+// chain, but so do distinct requests that happen to be identical, which the
+// server will take for duplicates.
+func IdempotencyKeyHashBody() IdempotencyKeyOption {
+	return IdempotencyKeyGenerator(hashIdempotencyKey)
+}
+
+// IdempotencyKey returns an Interceptor that attaches an idempotency key to
+// requests with unsafe methods, so that servers supporting the header can
+// detect and discard duplicates. The key is taken from the request context if
+// it was set with WithIdempotencyKey, then from the request's header if the
+// caller already set it, and is generated otherwise.
+//
+// The key is stored in the context of the request passed down the chain, and
+// requests carrying a key are reported as safe to replay by IsIdempotent.
+// IdempotencyKey should come before interceptors that retry requests, such as
+// Retry and Hedge, so that all their attempts carry the key of the request
+// they repeat; after them, each attempt would be given a new random key.
+func IdempotencyKey(opts ...IdempotencyKeyOption) Interceptor {
+	cfg := idempotencyKeyConfig{
+		header:  DefaultIdempotencyKeyHeader,
+		methods: map[string]bool{http.MethodPost: true, http.MethodPatch: true},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !cfg.methods[req.Method] {
+				return next.RoundTrip(req)
+			}
+
+			key, ok := IdempotencyKeyFrom(req.Context())
+			if !ok {
+				key = req.Header.Get(cfg.header)
+			}
+
+			req = req.Clone(req.Context())
+			switch {
+			case key != "":
+			case cfg.generate == nil:
+				key = NewUUID()
+			default:
+				body, err := readRequestBody(req)
+				if err != nil {
+					return nil, newError("IdempotencyKey", req, err)
+				}
+				key = cfg.generate(req, body)
+			}
+
+			ctx := context.WithValue(WithIdempotencyKey(req.Context(), key), idempotencyHeaderKey{}, cfg.header)
+			req = req.WithContext(ctx)
+			req.Header.Set(cfg.header, key)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// hashIdempotencyKey derives a key from the request method, URL, and body.
+func hashIdempotencyKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,142 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyInterceptor(t *testing.T) {
+	var ctxKey string
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctxKey, _ = IdempotencyKeyFrom(req.Context())
+		return mockRT.RoundTrip(req)
+	})
+
+	tests := []struct {
+		name      string
+		opts      []IdempotencyKeyOption
+		method    string
+		ctxKey    string
+		headerKey string
+		header    string
+		expected  string
+	}{
+		{"from context", nil, "POST", "ctx-key", "", DefaultIdempotencyKeyHeader, "ctx-key"},
+		{"from header", nil, "PATCH", "", "header-key", DefaultIdempotencyKeyHeader, "header-key"},
+		{"context wins", nil, "POST", "ctx-key", "header-key", DefaultIdempotencyKeyHeader, "ctx-key"},
+		{"safe method", nil, "GET", "ctx-key", "", DefaultIdempotencyKeyHeader, ""},
+		{"custom methods", []IdempotencyKeyOption{IdempotencyKeyMethods("DELETE")}, "DELETE", "ctx-key", "", DefaultIdempotencyKeyHeader, "ctx-key"},
+		{"custom header", []IdempotencyKeyOption{IdempotencyKeyHeader("X-Idempotency-Key")}, "POST", "ctx-key", "", "X-Idempotency-Key", "ctx-key"},
+		{"custom generator", []IdempotencyKeyOption{IdempotencyKeyGenerator(func(*http.Request, []byte) string { return "fixed" })}, "POST", "", "", DefaultIdempotencyKeyHeader, "fixed"},
+	}
+
+	for _, test := range tests {
+		ctxKey = ""
+		rt := IdempotencyKey(test.opts...)(transport)
+		ctx := context.Background()
+		if test.ctxKey != "" {
+			ctx = WithIdempotencyKey(ctx, test.ctxKey)
+		}
+		req, _ := http.NewRequestWithContext(ctx, test.method, "http://example.com", nil)
+		if test.headerKey != "" {
+			req.Header.Set(test.header, test.headerKey)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+
+		if sent := mockRT.Request.Header.Get(test.header); sent != test.expected {
+			t.Errorf("%s: expected key '%s', got '%s'", test.name, test.expected, sent)
+		}
+		if test.expected != "" && ctxKey != test.expected {
+			t.Errorf("%s: expected context key '%s', got '%s'", test.name, test.expected, ctxKey)
+		}
+	}
+}
+
+func TestIdempotencyKeyInterceptorRandom(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := IdempotencyKey()(mockRT)
+
+	var keys []string
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", "http://example.com/orders", strings.NewReader(`{"item":1}`))
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		keys = append(keys, mockRT.Request.Header.Get(DefaultIdempotencyKeyHeader))
+	}
+	if !uuidPattern.MatchString(keys[0]) {
+		t.Errorf("Expected a generated UUID, got '%s'", keys[0])
+	}
+	if keys[0] == keys[1] {
+		t.Errorf("Expected identical requests to get distinct keys, got '%s' twice", keys[0])
+	}
+}
+
+func TestIdempotencyKeyInterceptorRetried(t *testing.T) {
+	var keys []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get("X-Request-Key"))
+		if len(keys) < 3 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	// The custom header makes the POST safe to retry.
+	rt := Chain(
+		IdempotencyKey(IdempotencyKeyHeader("X-Request-Key")),
+		Retry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}),
+	)(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com/orders", strings.NewReader(`{"item":1}`))
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if len(keys) != 3 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("Expected every attempt to carry the same key, got %q", keys)
+	}
+}
+
+func TestIdempotencyKeyInterceptorHashBody(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := IdempotencyKey(IdempotencyKeyHashBody())(mockRT)
+
+	send := func(url, body string) (string, string) {
+		req, _ := http.NewRequest("POST", url, io.NopCloser(strings.NewReader(body)))
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		sent, _ := io.ReadAll(mockRT.Request.Body)
+		return mockRT.Request.Header.Get(DefaultIdempotencyKeyHeader), string(sent)
+	}
+
+	first, body := send("http://example.com/orders", `{"item":1}`)
+	if len(first) != 64 {
+		t.Errorf("Expected a hex SHA-256 key, got '%s'", first)
+	}
+	if body != `{"item":1}` {
+		t.Errorf("Expected body to be forwarded intact, got '%s'", body)
+	}
+	if again, _ := send("http://example.com/orders", `{"item":1}`); again != first {
+		t.Errorf("Expected the same request to get the same key, got '%s' and '%s'", first, again)
+	}
+	if other, _ := send("http://example.com/orders", `{"item":2}`); other == first {
+		t.Errorf("Expected a different body to get a different key")
+	}
+	if other, _ := send("http://example.com/refunds", `{"item":1}`); other == first {
+		t.Errorf("Expected a different URL to get a different key")
+	}
+}
@@ -1,46 +1,375 @@
 package interceptor
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 )
 
+// ErrNotPrepared is returned by Pipeline.Prepare when an interceptor answers
+// the request without passing it down the chain, or would send it through a
+// transport of its own.
+var ErrNotPrepared = errors.New("interceptor: request did not reach the transport")
+
 // Pipeline is a wrapper around an http.RoundTripper (also known as a transport)
 // that executes a series of Interceptors added via the Use method.
+//
+// A Pipeline is safe for concurrent use, and its interceptors and hooks may be
+// changed while requests are in flight. Each request runs with the chain as it
+// was when the request started.
 type Pipeline struct {
-	// interceptors is a stack of interceptors that are called on every request.
-	interceptors []Interceptor
+	mu sync.RWMutex
+
+	// entries is a stack of interceptors that are called on every request.
+	entries []pipelineEntry
+	// chain is entries composed around Transport, or nil if it must be rebuilt.
+	chain http.RoundTripper
+	// closers are the layers of chain that implement io.Closer.
+	closers []io.Closer
+
+	// inflight counts the requests running through the chain, and shutdown is
+	// set once Shutdown has been called.
+	inflight sync.WaitGroup
+	shutdown bool
+	closed   bool
 
-	// Transport is the underlying http.RoundTripper. If nil, http.DefaultTransport is used.
+	// Hooks called around the whole chain on every request.
+	onRequest  []func(*http.Request)
+	onResponse []func(*http.Response)
+	onError    []func(*http.Request, error)
+
+	// Transport is the underlying http.RoundTripper. If nil, http.DefaultTransport
+	// is used. It must not be changed once the Pipeline is in use, because the
+	// chain built around it is reused across requests.
 	Transport http.RoundTripper
 }
 
+// pipelineEntry is an interceptor registered with a Pipeline and the name it
+// was registered under, which is empty for unnamed interceptors.
+type pipelineEntry struct {
+	name        string
+	interceptor Interceptor
+}
+
+// New returns a Pipeline that sends requests through the given interceptors, in
+// order, before passing them to transport. If transport is nil,
+// http.DefaultTransport is used.
+func New(transport http.RoundTripper, interceptors ...Interceptor) *Pipeline {
+	t := &Pipeline{Transport: transport}
+	t.Use(interceptors...)
+	return t
+}
+
 // RoundTrip executes the request using the Pipeline's interceptors and the
 // underlying Transport. It implements the http.RoundTripper interface.
+//
+// After Shutdown has been called, RoundTrip fails with ErrShutdown.
 func (t *Pipeline) RoundTrip(req *http.Request) (*http.Response, error) {
-	var transport http.RoundTripper = t.Transport
+	transport, onRequest, onResponse, onError := t.snapshot()
 	if transport == nil {
-		transport = http.DefaultTransport
+		err := newError("Pipeline", req, ErrShutdown)
+		for _, hook := range onError {
+			hook(req, err)
+		}
+		return nil, err
 	}
+	defer t.inflight.Done()
 
-	// Wrap transport in reverse order so that execution is in original order
-	for i := len(t.interceptors) - 1; i >= 0; i-- {
-		transport = t.interceptors[i](transport)
+	for _, hook := range onRequest {
+		hook(req)
 	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		for _, hook := range onError {
+			hook(req, err)
+		}
+		return resp, err
+	}
+	for _, hook := range onResponse {
+		hook(resp)
+	}
+	return resp, nil
+}
 
-	return transport.RoundTrip(req)
+// snapshot returns the composed chain and the registered hooks. The chain is
+// built on first use after each change to the interceptors and then reused, so
+// that requests do not pay for wrapping every interceptor. The hook slices are
+// never modified in place, so they stay valid after the lock is released.
+//
+// Unless the Pipeline has been shut down, in which case the chain is nil, the
+// request is counted as in flight and the caller must call t.inflight.Done.
+func (t *Pipeline) snapshot() (http.RoundTripper, []func(*http.Request), []func(*http.Response), []func(*http.Request, error)) {
+	t.mu.RLock()
+	chain, shutdown := t.chain, t.shutdown
+	onRequest, onResponse, onError := t.onRequest, t.onResponse, t.onError
+	if chain != nil && !shutdown {
+		t.inflight.Add(1)
+	}
+	t.mu.RUnlock()
+	if shutdown {
+		return nil, onRequest, onResponse, onError
+	}
+	if chain != nil {
+		return chain, onRequest, onResponse, onError
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.shutdown {
+		return nil, t.onRequest, t.onResponse, t.onError
+	}
+	t.inflight.Add(1)
+	return t.build(), t.onRequest, t.onResponse, t.onError
+}
+
+// build returns the composed chain, building it if needed. The caller must
+// hold t.mu for writing.
+func (t *Pipeline) build() http.RoundTripper {
+	if t.chain == nil {
+		var transport http.RoundTripper = t.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		t.chain, t.closers = compose(transport, t.entries)
+	}
+	return t.chain
+}
+
+// compose wraps the interceptors of entries around transport, the first
+// outermost, and returns the layers that implement io.Closer, outermost first.
+// Named interceptors can be bypassed with Skip and Only.
+func compose(transport http.RoundTripper, entries []pipelineEntry) (http.RoundTripper, []io.Closer) {
+	var closers []io.Closer
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		wrapped := entry.interceptor(transport)
+		if closer, ok := layerCloser(wrapped, transport); ok {
+			closers = append(closers, closer)
+		}
+		if entry.name != "" {
+			transport = skippable(entry.name, wrapped, transport)
+		} else {
+			transport = wrapped
+		}
+	}
+	slices.Reverse(closers)
+	return transport, closers
+}
+
+// Prepare runs req through the Pipeline's interceptors without sending it, and
+// returns the request as it would have reached the Transport, with headers,
+// base URL, and signatures applied. The prepared request can be handed to
+// other systems, such as a browser or a job queue, to be sent later. It
+// carries the context of req, and hooks are not called.
+//
+// Interceptors receive an empty 200 OK response in place of the server's. The
+// request passed down the chain carries a context for which IsPreparing
+// reports true, and built-in interceptors that keep state or act on traffic,
+// such as Cache, CircuitBreaker, Metrics, HAR, RateLimit, and Chaos, pass it
+// on untouched so that their state is unchanged. Interceptors that would send
+// it through transports of their own, ClientCert, DialTarget, and TLSPin, make
+// Prepare fail with ErrNotPrepared instead, as does any interceptor that
+// answers the request itself. After Shutdown, Prepare fails with ErrShutdown.
+func (t *Pipeline) Prepare(req *http.Request) (*http.Request, error) {
+	t.mu.RLock()
+	entries, shutdown := t.entries, t.shutdown
+	t.mu.RUnlock()
+	if shutdown {
+		return nil, newError("Pipeline", req, ErrShutdown)
+	}
+
+	var mu sync.Mutex
+	var prepared *http.Request
+	capture := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		if prepared == nil {
+			prepared = r
+		}
+		mu.Unlock()
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+			Request:    r,
+		}, nil
+	})
+
+	chain, _ := compose(capture, entries)
+	resp, err := chain.RoundTrip(req.WithContext(context.WithValue(req.Context(), preparingKey{}, true)))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if prepared == nil {
+		return nil, newError("Pipeline", req, ErrNotPrepared)
+	}
+	return prepared.WithContext(req.Context()), nil
+}
+
+type preparingKey struct{}
+
+// IsPreparing reports whether ctx is the context of a request being run
+// through a Pipeline by Prepare rather than sent. Interceptors that keep state
+// or send requests themselves can check it to leave such requests alone.
+func IsPreparing(ctx context.Context) bool {
+	preparing, _ := ctx.Value(preparingKey{}).(bool)
+	return preparing
 }
 
 // Use appends one or more Interceptors to the Pipeline, allowing them to
 // modify or inspect requests before passing them to the underlying transport.
 func (t *Pipeline) Use(interceptors ...Interceptor) {
-	t.interceptors = append(t.interceptors, interceptors...)
+	entries := make([]pipelineEntry, len(interceptors))
+	for i, interceptor := range interceptors {
+		entries[i] = pipelineEntry{interceptor: interceptor}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(slices.Clip(t.entries), entries...)
+	t.chain = nil
+}
+
+// UseNamed appends an Interceptor to the Pipeline under name, so that it can
+// later be removed with Remove, or bypassed for a single request with Skip or
+// Only. Names need not be unique.
+func (t *Pipeline) UseNamed(name string, interceptor Interceptor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(slices.Clip(t.entries), pipelineEntry{name, interceptor})
+	t.chain = nil
+}
+
+// Insert adds an Interceptor at position index of the Pipeline, so that
+// Insert(0, i) makes i the outermost interceptor. It panics if index is
+// negative or greater than the number of interceptors.
+func (t *Pipeline) Insert(index int, interceptor Interceptor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = slices.Insert(slices.Clip(t.entries), index, pipelineEntry{interceptor: interceptor})
+	t.chain = nil
+}
+
+// Remove removes every interceptor registered under name from the Pipeline and
+// reports whether any were found.
+func (t *Pipeline) Remove(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := slices.DeleteFunc(slices.Clone(t.entries), func(e pipelineEntry) bool {
+		return e.name == name
+	})
+	if len(entries) == len(t.entries) {
+		return false
+	}
+	t.entries = entries
+	t.chain = nil
+	return true
+}
+
+// InterceptorInfo describes an interceptor registered with a Pipeline.
+type InterceptorInfo struct {
+	// Index is the interceptor's position in the chain, starting at 0 for the
+	// outermost.
+	Index int
+	// Name is the name the interceptor was registered under with UseNamed, or
+	// empty if it was added with Use, Insert, or New.
+	Name string
+}
+
+// Interceptors returns the Pipeline's interceptors in the order they run, so
+// that applications can print their effective chain and tests can check its
+// ordering. The returned slice is a snapshot and is not affected by later
+// changes to the Pipeline.
+func (t *Pipeline) Interceptors() []InterceptorInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	infos := make([]InterceptorInfo, len(t.entries))
+	for i, entry := range t.entries {
+		infos[i] = InterceptorInfo{Index: i, Name: entry.name}
+	}
+	return infos
+}
+
+// OnRequest registers a hook that is called with every request before it
+// enters the chain of interceptors. Hooks are for observation only and must not
+// modify the request.
+func (t *Pipeline) OnRequest(hook func(*http.Request)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onRequest = append(slices.Clip(t.onRequest), hook)
+}
+
+// OnResponse registers a hook that is called with every response returned by
+// the chain of interceptors, before it is returned to the caller. Hooks must not
+// read or close the response body.
+func (t *Pipeline) OnResponse(hook func(*http.Response)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onResponse = append(slices.Clip(t.onResponse), hook)
+}
+
+// OnError registers a hook that is called with the caller's request and the
+// error whenever the chain of interceptors fails.
+func (t *Pipeline) OnError(hook func(*http.Request, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = append(slices.Clip(t.onError), hook)
 }
 
 // Interceptor defines a function that wraps an http.RoundTripper,
 // allowing custom behavior to be injected into the request lifecycle.
+//
+// Like any http.RoundTripper, an Interceptor must not modify the request it
+// receives. To change a request, send a copy made with req.Clone(req.Context())
+// instead. CloneGuard can be used to check that a chain follows this rule.
 type Interceptor func(http.RoundTripper) http.RoundTripper
 
+// Chain composes interceptors into a single Interceptor that applies them in
+// the order given, so Chain(a, b)(transport) behaves like a(b(transport)). The
+// returned Interceptor is unaffected by later changes to the interceptors slice
+// and can be reused across Pipelines and clients. Closing the transport it
+// returns closes those of the interceptors that implement io.Closer.
+func Chain(interceptors ...Interceptor) Interceptor {
+	interceptors = append([]Interceptor(nil), interceptors...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		transport, closers := wrap(next, interceptors)
+		if len(closers) == 0 {
+			return transport
+		}
+		return withClose(transport, func() error {
+			return closeAll(closers)
+		})
+	}
+}
+
+// wrap applies interceptors around transport so that the first interceptor is
+// the outermost, and returns the layers that implement io.Closer, outermost
+// first.
+func wrap(transport http.RoundTripper, interceptors []Interceptor) (http.RoundTripper, []io.Closer) {
+	var closers []io.Closer
+	// Wrap transport in reverse order so that execution is in original order
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		wrapped := interceptors[i](transport)
+		if closer, ok := layerCloser(wrapped, transport); ok {
+			closers = append(closers, closer)
+		}
+		transport = wrapped
+	}
+	slices.Reverse(closers)
+	return transport, closers
+}
+
 // RoundTripperFunc is an adapter to allow the use of ordinary functions
 // as http.RoundTripper. If f is a function with the appropriate signature,
 // RoundTripperFunc(f) is an http.RoundTripper that calls f.
@@ -53,6 +382,7 @@ func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // BaseURL returns an Interceptor that ensures all outgoing requests use
 // the given baseURL. If the request URL already has a scheme, it is left unchanged.
+// The caller's request is not modified; a copy with the resolved URL is sent instead.
 func BaseURL(baseURL url.URL) func(http.RoundTripper) http.RoundTripper {
 	return func(next http.RoundTripper) http.RoundTripper {
 		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
@@ -60,7 +390,8 @@ func BaseURL(baseURL url.URL) func(http.RoundTripper) http.RoundTripper {
 			if req.URL.Scheme != "" {
 				return next.RoundTrip(req)
 			}
-			// Modify the request URL to include the base URL.
+			// Resolve a copy of the request URL against the base URL.
+			req = req.Clone(req.Context())
 			req.URL.Path = baseURL.JoinPath(req.URL.Path).Path
 			req.URL = baseURL.ResolveReference(req.URL)
 			return next.RoundTrip(req)
@@ -69,15 +400,113 @@ func BaseURL(baseURL url.URL) func(http.RoundTripper) http.RoundTripper {
 }
 
 // Header returns an Interceptor that adds or overrides a header with
-// the specified key and value on each request.
+// the specified key and value on each request. The caller's request is not
+// modified; a copy carrying the header is sent instead.
 func Header(key string, value string) func(http.RoundTripper) http.RoundTripper {
+	key = http.CanonicalHeaderKey(key)
 	return func(next http.RoundTripper) http.RoundTripper {
-		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
-			// Set the header if the key is not empty.
-			if key != "" {
+		// Ignore the header if the key is empty.
+		if key == "" {
+			return next
+		}
+		return withHeaders(func(req *http.Request) {
+			req.Header[key] = []string{value}
+		}, next)
+	}
+}
+
+// Headers returns an Interceptor that adds or overrides every header in
+// headers on each request. Empty keys are ignored.
+func Headers(headers map[string]string) Interceptor {
+	values := make(http.Header, len(headers))
+	for key, value := range headers {
+		if key != "" {
+			values.Set(key, value)
+		}
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(func(req *http.Request) {
+			// Copy the values into one slice, so that requests do not share
+			// them with each other.
+			copied := make([]string, 0, len(values))
+			for key, value := range values {
+				copied = append(copied, value[0])
+				req.Header[key] = copied[len(copied)-1 : len(copied) : len(copied)]
+			}
+		}, next)
+	}
+}
+
+// HeaderFunc returns an Interceptor that sets the header key to the value
+// returned by f for each request, allowing per-request values such as request
+// IDs or signatures. If f returns an empty string the header is left unchanged.
+func HeaderFunc(key string, f func(*http.Request) string) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if key == "" {
+			return next
+		}
+		return withHeaders(func(req *http.Request) {
+			if value := f(req); value != "" {
 				req.Header.Set(key, value)
 			}
-			return next.RoundTrip(req)
-		})
+		}, next)
+	}
+}
+
+// headerTransport is the http.RoundTripper of Header, Headers, and HeaderFunc.
+// Header interceptors wrapped directly around each other are merged into one
+// headerTransport, so that a request passing through them is copied once
+// rather than once per interceptor.
+type headerTransport struct {
+	// set holds the functions that change the headers of the copy, outermost
+	// interceptor first.
+	set  []func(*http.Request)
+	next http.RoundTripper
+}
+
+// withHeaders returns a headerTransport that calls set on a copy of each
+// request before passing it to next, merged with next if it is one too.
+func withHeaders(set func(*http.Request), next http.RoundTripper) http.RoundTripper {
+	if inner, ok := next.(*headerTransport); ok {
+		return &headerTransport{set: append([]func(*http.Request){set}, inner.set...), next: inner.next}
+	}
+	return &headerTransport{set: []func(*http.Request){set}, next: next}
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = withHeader(req)
+	for _, set := range t.set {
+		set(req)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// withHeader returns a shallow copy of req with its own copy of the header,
+// for interceptors that only change headers. It allocates less than
+// req.Clone, which also copies the URL, trailer, and other fields.
+func withHeader(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = req.Header.Clone()
+	if clone.Header == nil {
+		clone.Header = make(http.Header)
+	}
+	return clone
+}
+
+// UserAgent returns an Interceptor that identifies the client as product/version
+// in the User-Agent header. If the request already has a User-Agent, the
+// product is appended to it rather than replacing it, following the
+// product-list format of RFC 9110. If version is empty, only product is used.
+func UserAgent(product, version string) Interceptor {
+	token := product
+	if version != "" {
+		token += "/" + version
 	}
+	return HeaderFunc("User-Agent", func(req *http.Request) string {
+		if existing := req.Header.Get("User-Agent"); existing != "" {
+			return existing + " " + token
+		}
+		return token
+	})
 }
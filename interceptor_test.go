@@ -2,21 +2,41 @@ package interceptor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 	"testing"
 )
 
 type mockRoundTripper struct {
 	Response *http.Response
 	Err      error
+	// Request is the last request received.
+	Request *http.Request
 }
 
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.Request = req
 	return m.Response, m.Err
 }
 
+// recorder returns a function creating interceptors that append their name to
+// order when a request passes through them.
+func recorder(order *[]string) func(name string) Interceptor {
+	return func(name string) Interceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				*order = append(*order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+}
+
 func TestBaseURLInterceptor(t *testing.T) {
 	mockResp := &http.Response{
 		StatusCode: http.StatusOK,
@@ -66,8 +86,12 @@ func TestBaseURLInterceptor(t *testing.T) {
 			t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
 		}
 
-		if req.URL.String() != test.expectedURL {
-			t.Errorf("Expected URL to be '%s', got '%s'", test.expectedURL, req.URL.String())
+		if sent := mockRT.Request.URL.String(); sent != test.expectedURL {
+			t.Errorf("Expected URL to be '%s', got '%s'", test.expectedURL, sent)
+		}
+
+		if req.URL.String() != test.originalURL {
+			t.Errorf("Expected original request URL to be left as '%s', got '%s'", test.originalURL, req.URL.String())
 		}
 	}
 }
@@ -108,7 +132,7 @@ func TestHeaderInterceptor(t *testing.T) {
 			t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
 		}
 
-		got := req.Header.Get(test.headerKey)
+		got := mockRT.Request.Header.Get(test.headerKey)
 		if test.headerKey != "" {
 			if got != test.headerValue {
 				t.Errorf("Expected header to be '%s', got '%s'", test.headerValue, got)
@@ -118,5 +142,444 @@ func TestHeaderInterceptor(t *testing.T) {
 				t.Errorf("Expected header to not be set if no key was provided: got '%s'", got)
 			}
 		}
+
+		if test.headerKey != "" && req.Header.Get(test.headerKey) != "" {
+			t.Errorf("Expected original request headers to be left unmodified")
+		}
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	var order []string
+	record := recorder(&order)
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+
+	interceptors := []Interceptor{record("first"), record("second")}
+	pipeline := New(mockRT, interceptors...)
+	// Changing the caller's slice must not affect the Pipeline.
+	interceptors[0] = record("replaced")
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if _, err := pipeline.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected interceptors to run in order [first second], got %v", order)
+	}
+}
+
+func TestPipelineModification(t *testing.T) {
+	var order []string
+	record := recorder(&order)
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	pipeline := New(mockRT, record("a"))
+	pipeline.UseNamed("debug", record("debug"))
+	pipeline.Use(record("b"))
+	pipeline.Insert(0, record("first"))
+
+	run := func() []string {
+		order = nil
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if _, err := pipeline.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		return order
+	}
+
+	if got, expected := run(), []string{"first", "a", "debug", "b"}; !slices.Equal(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+	if !pipeline.Remove("debug") {
+		t.Errorf("Expected Remove to find the named interceptor")
+	}
+	if pipeline.Remove("debug") {
+		t.Errorf("Expected a second Remove to find nothing")
+	}
+	if got, expected := run(), []string{"first", "a", "b"}; !slices.Equal(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+}
+
+func TestPipelineChainReuse(t *testing.T) {
+	var wraps int
+	counting := func(next http.RoundTripper) http.RoundTripper {
+		wraps++
+		return next
+	}
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	pipeline := New(mockRT, counting)
+
+	send := func() {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if _, err := pipeline.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+
+	send()
+	send()
+	if wraps != 1 {
+		t.Errorf("Expected the chain to be built once, built %d times", wraps)
+	}
+	pipeline.Use(Header("X-Test", "value"))
+	send()
+	if wraps != 2 {
+		t.Errorf("Expected Use to rebuild the chain, built %d times", wraps)
+	}
+	if mockRT.Request.Header.Get("X-Test") != "value" {
+		t.Errorf("Expected the interceptor added by Use to run")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		pipeline.snapshot()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected reusing the chain not to allocate, got %v allocations", allocs)
+	}
+}
+
+func TestPipelineInterceptors(t *testing.T) {
+	pipeline := New(nil, Header("X-Test", "value"))
+	pipeline.UseNamed("tracing", Header("X-Trace", "value"))
+	pipeline.UseNamed("auth", Header("Authorization", "Bearer token"))
+
+	expected := []InterceptorInfo{{0, ""}, {1, "tracing"}, {2, "auth"}}
+	infos := pipeline.Interceptors()
+	if !slices.Equal(infos, expected) {
+		t.Errorf("Expected interceptors %v, got %v", expected, infos)
+	}
+
+	pipeline.Remove("tracing")
+	if !slices.Equal(infos, expected) {
+		t.Errorf("Expected the returned slice to be unaffected by Remove, got %v", infos)
+	}
+	expected = []InterceptorInfo{{0, ""}, {1, "auth"}}
+	if infos := pipeline.Interceptors(); !slices.Equal(infos, expected) {
+		t.Errorf("Expected interceptors %v, got %v", expected, infos)
+	}
+}
+
+func TestPipelineConcurrentUse(t *testing.T) {
+	mockRT := RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	pipeline := New(mockRT)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				req, _ := http.NewRequest("GET", "http://example.com", nil)
+				if _, err := pipeline.RoundTrip(req); err != nil {
+					t.Errorf("Failed to perform request: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pipeline.UseNamed("header", Header("X-Test", "value"))
+				pipeline.Insert(0, Header("X-First", "value"))
+				pipeline.OnRequest(func(*http.Request) {})
+				pipeline.Remove("header")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPipelineHooks(t *testing.T) {
+	var events []string
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{Status: "200 OK", StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	pipeline := New(mockRT, Header("X-Test", "value"))
+	pipeline.OnRequest(func(req *http.Request) {
+		events = append(events, "request "+req.Header.Get("X-Test"))
+	})
+	pipeline.OnResponse(func(resp *http.Response) {
+		events = append(events, "response "+resp.Status)
+	})
+	pipeline.OnError(func(req *http.Request, err error) {
+		events = append(events, "error "+err.Error())
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := pipeline.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	mockRT.Err = errors.New("connection refused")
+	mockRT.Response = nil
+	if _, err := pipeline.RoundTrip(req); err == nil {
+		t.Fatalf("Expected the transport error to be returned")
+	}
+
+	// Hooks see the caller's request, not the copies made inside the chain.
+	expected := []string{"request ", "response 200 OK", "request ", "error connection refused"}
+	if !slices.Equal(events, expected) {
+		t.Errorf("Expected hook events %q, got %q", expected, events)
+	}
+}
+
+func TestPipelinePrepare(t *testing.T) {
+	base, _ := url.Parse("https://api.example.com/v1")
+	var sent bool
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	pipeline := New(transport, BaseURL(*base), Header("Authorization", "Bearer token"))
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/reports", nil)
+	prepared, err := pipeline.Prepare(req)
+	if err != nil {
+		t.Fatalf("Failed to prepare request: %v", err)
+	}
+	if sent {
+		t.Error("Expected the request not to be sent")
+	}
+	if prepared.URL.String() != "https://api.example.com/v1/reports" || prepared.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected the interceptors to be applied, got %s %v", prepared.URL, prepared.Header)
+	}
+	if prepared.Context().Value(ctxKey{}) != "value" {
+		t.Error("Expected the prepared request to carry the caller's context")
+	}
+
+	pipeline.Use(func(http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+	})
+	if _, err := pipeline.Prepare(req); !errors.Is(err, ErrNotPrepared) {
+		t.Errorf("Expected ErrNotPrepared, got %v", err)
+	}
+}
+
+func TestPipelinePrepareLeavesStateUnchanged(t *testing.T) {
+	failing := true
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		header := http.Header{"Cache-Control": {"max-age=60"}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody}, nil
+	})
+	store := NewMemoryCacheStore(10)
+	pipeline := New(transport, CircuitBreaker(CircuitBreakerThreshold(2)), Cache(store))
+
+	get, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	post, _ := http.NewRequest("POST", "http://example.com/users", nil)
+
+	// A cached response is not invalidated by preparing an unsafe request.
+	failing = false
+	if _, err := pipeline.RoundTrip(get); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if _, err := pipeline.Prepare(post); err != nil {
+		t.Fatalf("Failed to prepare request: %v", err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("Expected the cached response to be kept, got %d entries", store.Len())
+	}
+
+	// A prepared request does not count as a success between two failures.
+	failing = true
+	pipeline.RoundTrip(post)
+	if _, err := pipeline.Prepare(post); err != nil {
+		t.Fatalf("Failed to prepare request: %v", err)
+	}
+	pipeline.RoundTrip(post)
+	if _, err := pipeline.RoundTrip(post); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the circuit to open after two failures, got %v", err)
+	}
+}
+
+func TestPipelinePrepareOwnTransport(t *testing.T) {
+	var sent bool
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	pipeline := New(transport, DialTarget(DialTargetFunc(func(*http.Request) (string, error) {
+		return "tcp://127.0.0.1:1", nil
+	})))
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := pipeline.Prepare(req); !errors.Is(err, ErrNotPrepared) {
+		t.Errorf("Expected ErrNotPrepared, got %v", err)
+	}
+	if sent {
+		t.Error("Expected the request not to be sent")
+	}
+
+	if err := pipeline.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	if _, err := pipeline.Prepare(req); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected ErrShutdown after Shutdown, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	record := recorder(&order)
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+
+	chain := Chain(record("a"), Chain(record("b"), record("c")), record("d"))
+	pipeline := New(mockRT, record("before"), chain, record("after"))
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if _, err := pipeline.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	expected := []string{"before", "a", "b", "c", "d", "after"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected order %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected order %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestHeadersInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	headers := map[string]string{"Accept": "application/json", "X-Realm": "alpha", "": "ignored"}
+	rt := Headers(headers)(mockRT)
+	// Changing the caller's map must not affect the Interceptor.
+	headers["X-Realm"] = "bravo"
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	sent := mockRT.Request.Header
+	if sent.Get("Accept") != "application/json" || sent.Get("X-Realm") != "alpha" {
+		t.Errorf("Expected headers to be set, got %v", sent)
+	}
+	if len(sent) != 2 {
+		t.Errorf("Expected exactly 2 headers, got %v", sent)
+	}
+	if len(req.Header) != 0 {
+		t.Errorf("Expected original request headers to be left unmodified")
+	}
+}
+
+func TestHeaderInterceptorsMerged(t *testing.T) {
+	var sent []*http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req)
+		// A transport changing a value in place must not affect other requests.
+		req.Header["X-A"][0] = "changed"
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	var seen string
+	rt := Chain(
+		Header("X-A", "a"),
+		Headers(map[string]string{"X-B": "b"}),
+		HeaderFunc("X-C", func(req *http.Request) string {
+			seen = req.Header.Get("X-A") + req.Header.Get("X-B")
+			return "c"
+		}),
+	)(transport)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if seen != "ab" {
+			t.Errorf("Expected inner interceptors to see the outer headers, got '%s'", seen)
+		}
+		if len(req.Header) != 0 {
+			t.Errorf("Expected original request headers to be left unmodified")
+		}
+	}
+	if got := sent[1].Header.Get("X-C"); got != "c" {
+		t.Errorf("Expected header to be 'c', got '%s'", got)
+	}
+}
+
+func TestHeaderFuncInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := HeaderFunc("X-Path", func(req *http.Request) string {
+		return req.URL.Path
+	})(mockRT)
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://example.com/users", "/users"},
+		{"http://example.com", ""},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", test.url, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if got := mockRT.Request.Header.Get("X-Path"); got != test.expected {
+			t.Errorf("Expected header to be '%s', got '%s'", test.expected, got)
+		}
+	}
+}
+
+func TestUserAgentInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+
+	tests := []struct {
+		existing   string
+		product    string
+		version    string
+		expectedUA string
+	}{
+		{"", "my-sdk", "1.2.0", "my-sdk/1.2.0"},
+		{"my-app/3.0", "my-sdk", "1.2.0", "my-app/3.0 my-sdk/1.2.0"},
+		{"", "my-sdk", "", "my-sdk"},
+	}
+
+	for _, test := range tests {
+		rt := UserAgent(test.product, test.version)(mockRT)
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if test.existing != "" {
+			req.Header.Set("User-Agent", test.existing)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if got := mockRT.Request.Header.Get("User-Agent"); got != test.expectedUA {
+			t.Errorf("Expected User-Agent to be '%s', got '%s'", test.expectedUA, got)
+		}
 	}
 }
@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrNotJSON is returned by the RequireJSON interceptor when a response does
+// not declare a JSON content type.
+var ErrNotJSON = errors.New("interceptor: response is not JSON")
+
+type jsonBodyKey struct{}
+
+// jsonBody wraps the value stored by WithJSONBody so that a nil value can be
+// told apart from no value.
+type jsonBody struct {
+	v any
+}
+
+// WithJSONBody returns a copy of ctx carrying v. Requests made with the
+// returned context and without a body of their own are sent by the JSON
+// interceptor with v marshaled as their body.
+func WithJSONBody(ctx context.Context, v any) context.Context {
+	return context.WithValue(ctx, jsonBodyKey{}, jsonBody{v})
+}
+
+// JSONBodyFrom returns the value carried by ctx to be sent as a JSON body, if
+// any.
+func JSONBodyFrom(ctx context.Context) (any, bool) {
+	body, ok := ctx.Value(jsonBodyKey{}).(jsonBody)
+	return body.v, ok
+}
+
+// JSON returns an Interceptor for JSON APIs. Requests without an Accept header
+// are sent with "Accept: application/json", and requests with a body but no
+// Content-Type are sent with "Content-Type: application/json".
+//
+// A request without a body whose context carries a value set with
+// WithJSONBody is sent with that value marshaled as its body, unless it is a
+// GET or HEAD request, such as the one an http.Client sends to follow a 303
+// See Other redirect with the same context. If marshaling fails, the request
+// fails with the error and is not sent.
+func JSON() Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if v, ok := JSONBodyFrom(req.Context()); ok && carriesBody(req) && (req.Body == nil || req.Body == http.NoBody) {
+				data, err := json.Marshal(v)
+				if err != nil {
+					return nil, newError("JSON", req, fmt.Errorf("marshaling JSON body: %w", err))
+				}
+				req.Body = io.NopCloser(bytes.NewReader(data))
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(data)), nil
+				}
+				req.ContentLength = int64(len(data))
+			}
+
+			if req.Header.Get("Accept") == "" {
+				req.Header.Set("Accept", "application/json")
+			}
+			if req.Body != nil && req.Body != http.NoBody && req.Header.Get("Content-Type") == "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// carriesBody reports whether the method of req can carry a JSON body.
+func carriesBody(req *http.Request) bool {
+	return req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead
+}
+
+// RequireJSON returns an Interceptor that fails requests whose response has a
+// body but does not declare a JSON content type, such as an HTML error page
+// from a proxy, with an error wrapping ErrNotJSON. Media types of
+// application/json and those with a +json suffix, such as
+// application/problem+json, are accepted. The body of a rejected response is
+// closed.
+func RequireJSON() Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || !hasBody(req, resp) {
+				return resp, err
+			}
+			contentType := resp.Header.Get("Content-Type")
+			if IsJSONContentType(contentType) {
+				return resp, nil
+			}
+			resp.Body.Close()
+			if contentType == "" {
+				return nil, newError("RequireJSON", req, fmt.Errorf("%w: no Content-Type", ErrNotJSON))
+			}
+			return nil, newError("RequireJSON", req, fmt.Errorf("%w: Content-Type is %s", ErrNotJSON, contentType))
+		})
+	}
+}
+
+// IsJSONContentType reports whether contentType names application/json or a
+// media type with a +json suffix.
+func IsJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// hasBody reports whether resp, a response to req, can carry content.
+func hasBody(req *http.Request, resp *http.Response) bool {
+	if req.Method == http.MethodHead || resp.Body == nil || resp.Body == http.NoBody || resp.ContentLength == 0 {
+		return false
+	}
+	return resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified
+}
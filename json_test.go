@@ -0,0 +1,109 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestJSONInterceptor(t *testing.T) {
+	var received *http.Request
+	var body string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			body = string(data)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := JSON()(transport)
+
+	ctx := WithJSONBody(context.Background(), map[string]string{"name": "alice"})
+	req, _ := http.NewRequestWithContext(ctx, "POST", "http://example.com/users", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if body != `{"name":"alice"}` {
+		t.Errorf("Expected the context value to be marshaled as the body, got %q", body)
+	}
+	if received.Header.Get("Content-Type") != "application/json" || received.Header.Get("Accept") != "application/json" {
+		t.Errorf("Expected JSON Accept and Content-Type headers, got %v", received.Header)
+	}
+	if received.GetBody == nil {
+		t.Errorf("Expected the marshaled body to be replayable")
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com/users", nil)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if received.Header.Get("Accept") != "application/vnd.api+json" || received.Header.Get("Content-Type") != "" {
+		t.Errorf("Expected headers set by the caller to be kept, got %v", received.Header)
+	}
+}
+
+func TestJSONInterceptorRedirect(t *testing.T) {
+	var bodies []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var data []byte
+		if req.Body != nil {
+			data, _ = io.ReadAll(req.Body)
+		}
+		bodies = append(bodies, req.Method+" "+string(data))
+		if req.URL.Path == "/users" {
+			header := http.Header{"Location": {"/users/1"}}
+			return &http.Response{StatusCode: http.StatusSeeOther, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	client := &http.Client{Transport: JSON()(transport)}
+
+	ctx := WithJSONBody(context.Background(), map[string]string{"name": "alice"})
+	req, _ := http.NewRequestWithContext(ctx, "POST", "http://example.com/users", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	resp.Body.Close()
+
+	expected := []string{`POST {"name":"alice"}`, "GET "}
+	if len(bodies) != 2 || bodies[0] != expected[0] || bodies[1] != expected[1] {
+		t.Errorf("Expected the redirected GET to be sent without a body, got %q", bodies)
+	}
+}
+
+func TestRequireJSONInterceptor(t *testing.T) {
+	tests := []struct {
+		contentType string
+		status      int
+		expectErr   bool
+	}{
+		{"application/json; charset=utf-8", http.StatusOK, false},
+		{"application/problem+json", http.StatusBadRequest, false},
+		{"text/html", http.StatusBadGateway, true},
+		{"", http.StatusOK, true},
+		{"", http.StatusNoContent, false},
+	}
+
+	for _, test := range tests {
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    test.status,
+				Header:        http.Header{"Content-Type": {test.contentType}},
+				Body:          io.NopCloser(strings.NewReader("content")),
+				ContentLength: -1,
+			}, nil
+		})
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		_, err := RequireJSON()(transport).RoundTrip(req)
+		if got := errors.Is(err, ErrNotJSON); got != test.expectErr {
+			t.Errorf("%q (%d): expected ErrNotJSON %v, got %v", test.contentType, test.status, test.expectErr, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package jsonhttp provides typed helpers for calling JSON APIs through an
+// http.Client whose transport is an interceptor.Pipeline. They are meant to be
+// used with the interceptor.JSON, interceptor.RequireJSON, and
+// interceptor.ErrorFromStatus interceptors:
+//
+//	client := interceptor.NewClient(interceptor.ClientInterceptors(
+//		interceptor.JSON(),
+//		interceptor.ErrorFromStatus(),
+//		interceptor.RequireJSON(),
+//	))
+//	req, err := jsonhttp.NewRequest(ctx, "POST", "https://api.example.com/users", newUser)
+//	// ...
+//	user, err := jsonhttp.DoJSON[User](client, req)
+package jsonhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// errorBodyLimit is how many bytes of a failed response body DoJSON keeps in
+// the error it returns.
+const errorBodyLimit = 4096
+
+// NewRequest returns a request for url whose context carries body, so that the
+// interceptor.JSON interceptor sends it marshaled as JSON. If body is nil, the
+// request is sent without a body.
+func NewRequest(ctx context.Context, method, url string, body any) (*http.Request, error) {
+	if body != nil {
+		ctx = interceptor.WithJSONBody(ctx, body)
+	}
+	return http.NewRequestWithContext(ctx, method, url, nil)
+}
+
+// DoJSON sends req with client and decodes the JSON response body into a value
+// of type T. Responses without content, such as 204 No Content, yield the zero
+// value of T.
+//
+// Responses without a 2xx status are returned as an *interceptor.StatusError.
+// Adding interceptor.ErrorFromStatus to the client's Pipeline gives such errors
+// parsed problem details as well.
+func DoJSON[T any](client *http.Client, req *http.Request) (T, error) {
+	var v T
+	resp, err := client.Do(req)
+	if err != nil {
+		return v, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+		return v, &interceptor.StatusError{
+			Method:     req.Method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			Body:       body,
+		}
+	}
+	if resp.StatusCode == http.StatusNoContent || req.Method == http.MethodHead {
+		return v, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil && err != io.EOF {
+		return v, fmt.Errorf("jsonhttp: decoding response: %w", err)
+	}
+	return v, nil
+}
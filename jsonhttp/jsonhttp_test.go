@@ -0,0 +1,69 @@
+package jsonhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestDoJSON(t *testing.T) {
+	var sent string
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		sent = string(data)
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"name":"alice"}`)),
+		}, nil
+	})
+	client := interceptor.NewClient(
+		interceptor.ClientTransport(transport),
+		interceptor.ClientInterceptors(interceptor.JSON(), interceptor.RequireJSON()),
+	)
+
+	req, err := NewRequest(context.Background(), "POST", "http://example.com/users", user{Name: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	got, err := DoJSON[user](client, req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if got.Name != "alice" {
+		t.Errorf("Expected the response to be decoded, got %+v", got)
+	}
+	if sent != `{"name":"alice"}` {
+		t.Errorf("Expected the request body to be marshaled, got %q", sent)
+	}
+}
+
+func TestDoJSONStatusError(t *testing.T) {
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Body:       io.NopCloser(strings.NewReader(`{"error":"missing"}`)),
+		}, nil
+	})
+	client := interceptor.NewClient(interceptor.ClientTransport(transport))
+
+	req, _ := NewRequest(context.Background(), "GET", "http://example.com/users/1", nil)
+	_, err := DoJSON[user](client, req)
+	var statusErr *interceptor.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected a *interceptor.StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound || string(statusErr.Body) != `{"error":"missing"}` {
+		t.Errorf("Expected the status and body to be kept, got %d %q", statusErr.StatusCode, statusErr.Body)
+	}
+}
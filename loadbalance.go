@@ -0,0 +1,144 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// Strategy chooses which of a LoadBalance interceptor's targets receives each
+// request. Implementations must be safe for concurrent use, and a Strategy
+// keeps state for a single set of targets, so it must not be shared between
+// LoadBalance interceptors.
+type Strategy interface {
+	// Pick returns the index, in [0, n), of the target to send req to, and a
+	// function that is called once the request has finished.
+	Pick(req *http.Request, n int) (index int, done func())
+}
+
+// RoundRobin returns a Strategy that sends requests to each target in turn.
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (r *roundRobin) Pick(_ *http.Request, n int) (int, func()) {
+	return int((r.next.Add(1) - 1) % uint64(n)), func() {}
+}
+
+// LeastInFlight returns a Strategy that sends each request to the target with
+// the fewest requests in flight, taking turns between targets that are tied. A
+// request stays in flight until its response body is read to the end or
+// closed.
+func LeastInFlight() Strategy {
+	return &leastInFlight{}
+}
+
+type leastInFlight struct {
+	mu       sync.Mutex
+	inFlight []int
+	next     int
+}
+
+func (l *leastInFlight) Pick(_ *http.Request, n int) (int, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.inFlight) != n {
+		l.inFlight = make([]int, n)
+	}
+
+	best := l.next % n
+	for k := 1; k < n; k++ {
+		if i := (l.next + k) % n; l.inFlight[i] < l.inFlight[best] {
+			best = i
+		}
+	}
+	l.next = best + 1
+	l.inFlight[best]++
+
+	return best, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight[best]--
+	}
+}
+
+// Weighted returns a Strategy that distributes requests in proportion to the
+// given weights, one for each target in order, interleaving targets as evenly
+// as possible. Targets without a weight, or with a weight below 1, get a weight
+// of 1.
+func Weighted(weights ...int) Strategy {
+	return &weighted{weights: append([]int(nil), weights...)}
+}
+
+// weighted implements smooth weighted round-robin: every pick adds each
+// target's weight to its current score, chooses the highest score, and
+// subtracts the total weight from the chosen target.
+type weighted struct {
+	mu      sync.Mutex
+	weights []int
+	current []int
+}
+
+func (w *weighted) weight(i int) int {
+	if i < len(w.weights) && w.weights[i] > 0 {
+		return w.weights[i]
+	}
+	return 1
+}
+
+func (w *weighted) Pick(_ *http.Request, n int) (int, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.current) != n {
+		w.current = make([]int, n)
+	}
+
+	best, total := 0, 0
+	for i := range w.current {
+		weight := w.weight(i)
+		w.current[i] += weight
+		total += weight
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= total
+	return best, func() {}
+}
+
+// LoadBalance returns an Interceptor that distributes requests across targets
+// using strategy. Each request is sent to the chosen target by replacing the
+// scheme and host of its URL with the target's and joining its path to the
+// target's path, as Failover does. Every call picks a target afresh, so an
+// interceptor placed before LoadBalance that retries requests may send each
+// attempt to a different backend.
+//
+// LoadBalance panics if targets is empty.
+func LoadBalance(targets []url.URL, strategy Strategy) Interceptor {
+	if len(targets) == 0 {
+		panic("interceptor: LoadBalance requires at least one target")
+	}
+	targets = append([]url.URL(nil), targets...)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			i, done := strategy.Pick(req, len(targets))
+			resp, err := next.RoundTrip(retarget(req, &targets[i]))
+			if err != nil {
+				done()
+				return nil, err
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				done()
+				return resp, nil
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: sync.OnceFunc(done)}
+			return resp, nil
+		})
+	}
+}
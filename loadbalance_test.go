@@ -0,0 +1,95 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadBalanceRoundRobin(t *testing.T) {
+	transport := &hostTransport{status: map[string]int{
+		"a.example.com": http.StatusOK,
+		"b.example.com": http.StatusOK,
+		"c.example.com": http.StatusOK,
+	}}
+	targets := mustParseURLs(t, "https://a.example.com/api", "https://b.example.com/api", "https://c.example.com/api")
+	rt := LoadBalance(targets, RoundRobin())(transport)
+
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("GET", "/users", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+
+	expected := []string{"a.example.com", "b.example.com", "c.example.com", "a.example.com"}
+	if !slices.Equal(transport.hosts, expected) {
+		t.Errorf("Expected hosts %v, got %v", expected, transport.hosts)
+	}
+	if transport.paths[0] != "/api/users" {
+		t.Errorf("Expected path to be joined with the target's, got '%s'", transport.paths[0])
+	}
+}
+
+func TestLoadBalanceLeastInFlight(t *testing.T) {
+	strategy := LeastInFlight()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	first, doneFirst := strategy.Pick(req, 3)
+	second, _ := strategy.Pick(req, 3)
+	third, _ := strategy.Pick(req, 3)
+	if first == second || second == third || first == third {
+		t.Fatalf("Expected idle targets to be picked first, got %d, %d, %d", first, second, third)
+	}
+
+	doneFirst()
+	if next, _ := strategy.Pick(req, 3); next != first {
+		t.Errorf("Expected the target that finished to be picked, got %d, expected %d", next, first)
+	}
+}
+
+func TestLoadBalanceLeastInFlightBody(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(req.URL.Host))}, nil
+	})
+	targets := mustParseURLs(t, "https://a.example.com", "https://b.example.com")
+	rt := LoadBalance(targets, LeastInFlight())(transport)
+
+	send := func() *http.Response {
+		req, _ := http.NewRequest("GET", "/", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		return resp
+	}
+
+	open := send()
+	host, _ := io.ReadAll(send().Body)
+	if string(host) != "b.example.com" {
+		t.Errorf("Expected the idle target while the first response is open, got '%s'", host)
+	}
+	open.Body.Close()
+	open.Body.Close()
+	if host, _ := io.ReadAll(send().Body); string(host) != "a.example.com" {
+		t.Errorf("Expected the first target once its response was closed, got '%s'", host)
+	}
+}
+
+func TestLoadBalanceWeighted(t *testing.T) {
+	strategy := Weighted(3, 1)
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	var picks []int
+	for i := 0; i < 8; i++ {
+		index, _ := strategy.Pick(req, 2)
+		picks = append(picks, index)
+	}
+
+	expected := []int{0, 0, 1, 0, 0, 0, 1, 0}
+	if !slices.Equal(picks, expected) {
+		t.Errorf("Expected picks %v, got %v", expected, picks)
+	}
+}
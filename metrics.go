@@ -0,0 +1,118 @@
+package interceptor
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsRecorder receives measurements from the Metrics interceptor. Its
+// methods are called concurrently and must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RequestStarted is called before a request is sent and is always followed
+	// by a call to RequestFinished with the same method and host.
+	RequestStarted(method, host string)
+	// RequestFinished is called once the response body has been read to the end
+	// or closed, or the request has failed.
+	RequestFinished(m RequestMetrics)
+}
+
+// RequestMetrics describes a completed request.
+type RequestMetrics struct {
+	Method string
+	Host   string
+	// StatusClass is the response status class, such as "2xx" or "5xx", or
+	// "error" if no response was received.
+	StatusClass string
+	// Duration is the time from sending the request until the response body
+	// was consumed or the request failed.
+	Duration time.Duration
+	// ResponseSize is the number of response body bytes read by the caller.
+	ResponseSize int64
+	// Attrs are the attributes carried by the request context, as set with
+	// WithAttrs.
+	Attrs []slog.Attr
+}
+
+// Metrics returns an Interceptor that reports request counts, durations,
+// in-flight requests, and response sizes to recorder. If recorder implements
+// io.Closer, for example to flush buffered measurements, Pipeline.Shutdown
+// closes it.
+func Metrics(recorder MetricsRecorder) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			m := RequestMetrics{Method: req.Method, Host: req.URL.Host, Attrs: AttrsFrom(req.Context())}
+			start := time.Now()
+			recorder.RequestStarted(m.Method, m.Host)
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				m.StatusClass = "error"
+				m.Duration = time.Since(start)
+				recorder.RequestFinished(m)
+				return nil, err
+			}
+
+			m.StatusClass = StatusClass(resp.StatusCode)
+			body := &metricsBody{ReadCloser: resp.Body, finish: func(size int64) {
+				m.Duration = time.Since(start)
+				m.ResponseSize = size
+				recorder.RequestFinished(m)
+			}}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				body.done()
+				return resp, nil
+			}
+			resp.Body = body
+			return resp, nil
+		})
+		if closer, ok := recorder.(io.Closer); ok {
+			return withClose(transport, closer.Close)
+		}
+		return transport
+	}
+}
+
+// StatusClass returns the class of an HTTP status code, such as "2xx" for 204.
+func StatusClass(code int) string {
+	if code < 100 || code > 999 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// metricsBody counts the bytes read from a response body and reports them
+// once the body is consumed or closed.
+type metricsBody struct {
+	io.ReadCloser
+	size   int64
+	once   sync.Once
+	finish func(size int64)
+}
+
+func (b *metricsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *metricsBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *metricsBody) done() {
+	b.once.Do(func() {
+		b.finish(b.size)
+	})
+}
@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"expvar"
+	"strings"
+)
+
+// ExpvarRecorder is a MetricsRecorder that publishes metrics through the expvar
+// package, where they are served as JSON at /debug/vars. Series are keyed by
+// their labels joined with spaces, for example "GET api.example.com 2xx".
+type ExpvarRecorder struct {
+	requests        *expvar.Map
+	durationSeconds *expvar.Map
+	responseBytes   *expvar.Map
+	inFlight        *expvar.Map
+}
+
+// NewExpvarRecorder returns an ExpvarRecorder that publishes its metrics as a
+// map under name. Like expvar.Publish, it panics if name is already in use.
+func NewExpvarRecorder(name string) *ExpvarRecorder {
+	r := &ExpvarRecorder{
+		requests:        new(expvar.Map),
+		durationSeconds: new(expvar.Map),
+		responseBytes:   new(expvar.Map),
+		inFlight:        new(expvar.Map),
+	}
+	root := expvar.NewMap(name)
+	root.Set("requests", r.requests)
+	root.Set("duration_seconds", r.durationSeconds)
+	root.Set("response_bytes", r.responseBytes)
+	root.Set("in_flight", r.inFlight)
+	return r
+}
+
+// RequestStarted implements MetricsRecorder.
+func (r *ExpvarRecorder) RequestStarted(method, host string) {
+	r.inFlight.Add(method+" "+host, 1)
+}
+
+// RequestFinished implements MetricsRecorder.
+func (r *ExpvarRecorder) RequestFinished(m RequestMetrics) {
+	r.inFlight.Add(m.Method+" "+m.Host, -1)
+
+	key := strings.Join([]string{m.Method, m.Host, m.StatusClass}, " ")
+	r.requests.Add(key, 1)
+	r.durationSeconds.AddFloat(key, m.Duration.Seconds())
+	r.responseBytes.Add(key, m.ResponseSize)
+}
@@ -0,0 +1,44 @@
+package interceptor
+
+import (
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestExpvarRecorder(t *testing.T) {
+	recorder := NewExpvarRecorder("test_http_client")
+
+	recorder.RequestStarted("GET", "api.example.com")
+	recorder.RequestFinished(RequestMetrics{
+		Method: "GET", Host: "api.example.com", StatusClass: "2xx",
+		Duration: 250 * time.Millisecond, ResponseSize: 42,
+	})
+	recorder.RequestStarted("GET", "api.example.com")
+
+	root, ok := expvar.Get("test_http_client").(*expvar.Map)
+	if !ok {
+		t.Fatalf("Expected metrics to be published under 'test_http_client'")
+	}
+	get := func(name, key string) string {
+		v := root.Get(name).(*expvar.Map).Get(key)
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+
+	tests := []struct {
+		name, key, expected string
+	}{
+		{"requests", "GET api.example.com 2xx", "1"},
+		{"duration_seconds", "GET api.example.com 2xx", "0.25"},
+		{"response_bytes", "GET api.example.com 2xx", "42"},
+		{"in_flight", "GET api.example.com", "1"},
+	}
+	for _, test := range tests {
+		if got := get(test.name, test.key); got != test.expected {
+			t.Errorf("Expected %s[%s] to be %s, got %s", test.name, test.key, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package interceptor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request
+// duration histogram kept by PrometheusRecorder.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusRecorder is a MetricsRecorder that keeps metrics in memory and
+// serves them in the Prometheus text exposition format. It exposes:
+//
+//   - http_client_requests_total, a counter by method, host, and status class
+//   - http_client_request_duration_seconds, a histogram by the same labels
+//   - http_client_response_size_bytes, a summary by the same labels
+//   - http_client_requests_in_flight, a gauge by method and host
+//
+// It implements http.Handler so it can be mounted directly as a scrape
+// endpoint.
+type PrometheusRecorder struct {
+	mu       sync.Mutex
+	buckets  []float64
+	requests map[requestKey]*requestSeries
+	inFlight map[hostKey]int64
+}
+
+type hostKey struct {
+	method, host string
+}
+
+type requestKey struct {
+	method, host, statusClass string
+}
+
+type requestSeries struct {
+	count         uint64
+	durationSum   float64
+	bucketCounts  []uint64
+	responseBytes int64
+}
+
+// NewPrometheusRecorder returns a PrometheusRecorder whose duration histogram
+// uses the given bucket upper bounds in seconds. If no buckets are given,
+// DefaultDurationBuckets is used.
+func NewPrometheusRecorder(buckets ...float64) *PrometheusRecorder {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusRecorder{
+		buckets:  buckets,
+		requests: make(map[requestKey]*requestSeries),
+		inFlight: make(map[hostKey]int64),
+	}
+}
+
+// RequestStarted implements MetricsRecorder.
+func (p *PrometheusRecorder) RequestStarted(method, host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight[hostKey{method, host}]++
+}
+
+// RequestFinished implements MetricsRecorder.
+func (p *PrometheusRecorder) RequestFinished(m RequestMetrics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight[hostKey{m.Method, m.Host}]--
+
+	key := requestKey{m.Method, m.Host, m.StatusClass}
+	series, ok := p.requests[key]
+	if !ok {
+		series = &requestSeries{bucketCounts: make([]uint64, len(p.buckets))}
+		p.requests[key] = series
+	}
+	seconds := m.Duration.Seconds()
+	series.count++
+	series.durationSum += seconds
+	series.responseBytes += m.ResponseSize
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			series.bucketCounts[i]++
+		}
+	}
+}
+
+// ServeHTTP writes the current metrics in the Prometheus text format.
+func (p *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes the current metrics to w in the Prometheus text format.
+func (p *PrometheusRecorder) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	keys := make([]requestKey, 0, len(p.requests))
+	for key := range p.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := keys[i], keys[j]
+		if x.method != y.method {
+			return x.method < y.method
+		}
+		if x.host != y.host {
+			return x.host < y.host
+		}
+		return x.statusClass < y.statusClass
+	})
+
+	b.WriteString("# HELP http_client_requests_total Total number of HTTP client requests.\n")
+	b.WriteString("# TYPE http_client_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "http_client_requests_total%s %d\n", key.labels(), p.requests[key].count)
+	}
+
+	b.WriteString("# HELP http_client_request_duration_seconds Duration of HTTP client requests.\n")
+	b.WriteString("# TYPE http_client_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		series := p.requests[key]
+		labels := key.labelPairs()
+		for i, bound := range p.buckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(&b, "http_client_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, le, series.bucketCounts[i])
+		}
+		fmt.Fprintf(&b, "http_client_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, series.count)
+		fmt.Fprintf(&b, "http_client_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(series.durationSum, 'g', -1, 64))
+		fmt.Fprintf(&b, "http_client_request_duration_seconds_count{%s} %d\n", labels, series.count)
+	}
+
+	b.WriteString("# HELP http_client_response_size_bytes Size of HTTP client response bodies.\n")
+	b.WriteString("# TYPE http_client_response_size_bytes summary\n")
+	for _, key := range keys {
+		series := p.requests[key]
+		fmt.Fprintf(&b, "http_client_response_size_bytes_sum%s %d\n", key.labels(), series.responseBytes)
+		fmt.Fprintf(&b, "http_client_response_size_bytes_count%s %d\n", key.labels(), series.count)
+	}
+
+	hosts := make([]hostKey, 0, len(p.inFlight))
+	for key := range p.inFlight {
+		hosts = append(hosts, key)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].method != hosts[j].method {
+			return hosts[i].method < hosts[j].method
+		}
+		return hosts[i].host < hosts[j].host
+	})
+	b.WriteString("# HELP http_client_requests_in_flight Number of HTTP client requests in flight.\n")
+	b.WriteString("# TYPE http_client_requests_in_flight gauge\n")
+	for _, key := range hosts {
+		fmt.Fprintf(&b, "http_client_requests_in_flight{method=%s,host=%s} %d\n",
+			quoteLabel(key.method), quoteLabel(key.host), p.inFlight[key])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (k requestKey) labelPairs() string {
+	return fmt.Sprintf("method=%s,host=%s,status_class=%s",
+		quoteLabel(k.method), quoteLabel(k.host), quoteLabel(k.statusClass))
+}
+
+func (k requestKey) labels() string {
+	return "{" + k.labelPairs() + "}"
+}
+
+// labelEscaper escapes label values as required by the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
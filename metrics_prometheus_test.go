@@ -0,0 +1,69 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusRecorder(t *testing.T) {
+	recorder := NewPrometheusRecorder(0.1, 1)
+
+	recorder.RequestStarted("GET", "api.example.com")
+	recorder.RequestStarted("GET", "api.example.com")
+	recorder.RequestFinished(RequestMetrics{
+		Method: "GET", Host: "api.example.com", StatusClass: "2xx",
+		Duration: 50 * time.Millisecond, ResponseSize: 100,
+	})
+	recorder.RequestStarted("GET", `odd"host`)
+	recorder.RequestFinished(RequestMetrics{
+		Method: "GET", Host: `odd"host`, StatusClass: "5xx",
+		Duration: 2 * time.Second,
+	})
+
+	w := httptest.NewRecorder()
+	recorder.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	output := w.Body.String()
+
+	expected := []string{
+		`http_client_requests_total{method="GET",host="api.example.com",status_class="2xx"} 1`,
+		`http_client_request_duration_seconds_bucket{method="GET",host="api.example.com",status_class="2xx",le="0.1"} 1`,
+		`http_client_request_duration_seconds_bucket{method="GET",host="odd\"host",status_class="5xx",le="1"} 0`,
+		`http_client_request_duration_seconds_bucket{method="GET",host="odd\"host",status_class="5xx",le="+Inf"} 1`,
+		`http_client_request_duration_seconds_count{method="GET",host="api.example.com",status_class="2xx"} 1`,
+		`http_client_response_size_bytes_sum{method="GET",host="api.example.com",status_class="2xx"} 100`,
+		`http_client_requests_in_flight{method="GET",host="api.example.com"} 1`,
+		`http_client_requests_in_flight{method="GET",host="odd\"host"} 0`,
+		"# TYPE http_client_request_duration_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain %s\n%s", line, output)
+		}
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain content type, got '%s'", ct)
+	}
+}
+
+func TestPrometheusRecorderWithMetricsInterceptor(t *testing.T) {
+	recorder := NewPrometheusRecorder()
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody},
+	}
+	rt := Metrics(recorder)(mockRT)
+
+	req, _ := http.NewRequest("DELETE", "http://api.example.com/items/1", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	var b strings.Builder
+	recorder.WriteTo(&b)
+	line := `http_client_requests_total{method="DELETE",host="api.example.com",status_class="4xx"} 1`
+	if !strings.Contains(b.String(), line) {
+		t.Errorf("Expected output to contain %s\n%s", line, b.String())
+	}
+}
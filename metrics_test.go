@@ -0,0 +1,97 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRecorder collects the calls made by the Metrics interceptor.
+type fakeRecorder struct {
+	mu       sync.Mutex
+	inFlight int
+	finished []RequestMetrics
+}
+
+func (r *fakeRecorder) RequestStarted(method, host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight++
+}
+
+func (r *fakeRecorder) RequestFinished(m RequestMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+	r.finished = append(r.finished, m)
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	recorder := &fakeRecorder{}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("12345"))}, nil
+	})
+	rt := Metrics(recorder)(transport)
+
+	req, _ := http.NewRequest("POST", "http://api.example.com/items", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if recorder.inFlight != 1 {
+		t.Errorf("Expected request to be in flight until the body is consumed")
+	}
+
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if recorder.inFlight != 0 {
+		t.Errorf("Expected no requests in flight, got %d", recorder.inFlight)
+	}
+	if len(recorder.finished) != 1 {
+		t.Fatalf("Expected request to be finished exactly once, got %d", len(recorder.finished))
+	}
+	m := recorder.finished[0]
+	if m.Method != "POST" || m.Host != "api.example.com" || m.StatusClass != "2xx" || m.ResponseSize != 5 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+func TestMetricsInterceptorError(t *testing.T) {
+	recorder := &fakeRecorder{}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	rt := Metrics(recorder)(transport)
+
+	req, _ := http.NewRequest("GET", "http://api.example.com", nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatalf("Expected request to fail")
+	}
+	if len(recorder.finished) != 1 || recorder.finished[0].StatusClass != "error" {
+		t.Errorf("Expected failed request to be recorded with status class 'error', got %+v", recorder.finished)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected string
+	}{
+		{100, "1xx"},
+		{204, "2xx"},
+		{304, "3xx"},
+		{429, "4xx"},
+		{503, "5xx"},
+		{0, "unknown"},
+	}
+
+	for _, test := range tests {
+		if got := StatusClass(test.code); got != test.expected {
+			t.Errorf("Expected status class '%s' for %d, got '%s'", test.expected, test.code, got)
+		}
+	}
+}
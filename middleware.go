@@ -0,0 +1,169 @@
+package interceptor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Middleware wraps an http.Handler, allowing custom behavior to be injected
+// into the handling of server requests. It is the server-side counterpart of
+// Interceptor.
+type Middleware func(http.Handler) http.Handler
+
+// HandlerPipeline is a wrapper around an http.Handler that executes a series
+// of Middleware added via the Use method, in the same way that a Pipeline
+// executes Interceptors around a transport.
+//
+// A HandlerPipeline is safe for concurrent use, and its middleware may be
+// changed while requests are being served. Each request runs with the chain as
+// it was when the request started.
+type HandlerPipeline struct {
+	mu sync.RWMutex
+
+	// middleware is a stack of middleware that is called on every request.
+	middleware []Middleware
+	// chain is middleware composed around Handler, or nil if it must be
+	// rebuilt.
+	chain http.Handler
+
+	// Handler is the underlying http.Handler. If nil, http.NotFoundHandler is
+	// used. It must not be changed once the HandlerPipeline is in use, because
+	// the chain built around it is reused across requests.
+	Handler http.Handler
+}
+
+// NewHandlerPipeline returns a HandlerPipeline that passes requests through
+// the given middleware, in order, before handing them to handler.
+func NewHandlerPipeline(handler http.Handler, middleware ...Middleware) *HandlerPipeline {
+	p := &HandlerPipeline{Handler: handler}
+	p.Use(middleware...)
+	return p
+}
+
+// Use appends one or more Middleware to the HandlerPipeline.
+func (p *HandlerPipeline) Use(middleware ...Middleware) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.middleware = append(slices.Clip(p.middleware), middleware...)
+	p.chain = nil
+}
+
+// ServeHTTP serves the request using the HandlerPipeline's middleware and the
+// underlying Handler. It implements the http.Handler interface.
+func (p *HandlerPipeline) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	chain := p.chain
+	p.mu.RUnlock()
+	if chain == nil {
+		p.mu.Lock()
+		if p.chain == nil {
+			handler := p.Handler
+			if handler == nil {
+				handler = http.NotFoundHandler()
+			}
+			for i := len(p.middleware) - 1; i >= 0; i-- {
+				handler = p.middleware[i](handler)
+			}
+			p.chain = handler
+		}
+		chain = p.chain
+		p.mu.Unlock()
+	}
+	chain.ServeHTTP(w, r)
+}
+
+// FromInterceptor adapts an Interceptor into Middleware, so that interceptors
+// such as RequestID, Metrics, or Attrs can be reused on the server side:
+//
+//	handler := interceptor.NewHandlerPipeline(mux,
+//		interceptor.FromInterceptor(interceptor.RequestID()),
+//		interceptor.FromInterceptor(interceptor.Metrics(recorder)),
+//	)
+//
+// The interceptor sees the server request, with its URL made absolute from the
+// Host header, and the handler receives the request as the interceptor passed
+// it on, including any headers or context values it added. The handler's
+// response is buffered in memory and given to the interceptor as an
+// http.Response before it is written to the client, so FromInterceptor is not
+// suited to handlers that stream their responses. If the interceptor fails,
+// the client receives 500 Internal Server Error.
+func FromInterceptor(i Interceptor) Middleware {
+	return func(next http.Handler) http.Handler {
+		rt := i(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			w := &bufferedResponseWriter{header: make(http.Header)}
+			next.ServeHTTP(w, req)
+			return w.response(req), nil
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := r.Clone(r.Context())
+			if req.URL.Host == "" {
+				req.URL.Host = r.Host
+			}
+			if req.URL.Scheme == "" {
+				req.URL.Scheme = "http"
+				if r.TLS != nil {
+					req.URL.Scheme = "https"
+				}
+			}
+
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			defer resp.Body.Close()
+			for name, values := range resp.Header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+		})
+	}
+}
+
+// bufferedResponseWriter is an http.ResponseWriter that holds a response in
+// memory.
+type bufferedResponseWriter struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// response returns the buffered response to req.
+func (w *bufferedResponseWriter) response(req *http.Request) *http.Response {
+	w.WriteHeader(http.StatusOK)
+	body := w.body.Bytes()
+	return &http.Response{
+		Status:        strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
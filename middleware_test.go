@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerPipeline(t *testing.T) {
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+	p := NewHandlerPipeline(handler, middleware("first"))
+	p.Use(middleware("second"))
+
+	p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	expected := []string{"first", "second", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestFromInterceptor(t *testing.T) {
+	var requestID, host string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, _ = RequestIDFrom(r.Context())
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	})
+	tagResponse := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host = req.URL.Host
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				resp.Header.Set("X-Served-By", "pipeline")
+			}
+			return resp, err
+		})
+	}
+	p := NewHandlerPipeline(handler,
+		FromInterceptor(RequestID(RequestIDGenerator(func() string { return "generated" }))),
+		FromInterceptor(tagResponse),
+	)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("POST", "http://api.example.com/users", nil))
+
+	if requestID != "generated" {
+		t.Errorf("Expected the handler to see the request ID, got %q", requestID)
+	}
+	if host != "api.example.com" {
+		t.Errorf("Expected the interceptor to see an absolute URL, got host %q", host)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "created" {
+		t.Errorf("Expected the handler's response, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Served-By") != "pipeline" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected headers from the handler and the interceptor, got %v", w.Header())
+	}
+}
@@ -0,0 +1,267 @@
+// Package mock provides an http.RoundTripper that answers requests with canned
+// responses instead of touching the network, for testing code built on
+// interceptor.Pipeline.
+//
+// Expectations are registered with ExpectCall and matched in the order they
+// were added:
+//
+//	m := mock.New()
+//	m.ExpectCall(mock.Method("GET"), mock.Path("/users/*")).
+//		Respond(http.StatusOK, `{"name":"alice"}`).
+//		Times(2)
+//
+//	pipeline := interceptor.New(m, interceptor.BaseURL(*base))
+//	// ... exercise code using the pipeline ...
+//	m.Verify(t)
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// Matcher reports whether a request satisfies an expectation.
+type Matcher func(*http.Request) bool
+
+// Method matches requests with the given HTTP method.
+func Method(method string) Matcher {
+	return func(req *http.Request) bool {
+		return strings.EqualFold(req.Method, method)
+	}
+}
+
+// URL matches requests whose full URL, without query or fragment, matches
+// pattern using path.Match syntax. For example "https://api.example.com/users/*"
+// matches "https://api.example.com/users/42".
+func URL(pattern string) Matcher {
+	return func(req *http.Request) bool {
+		u := *req.URL
+		u.RawQuery = ""
+		u.Fragment = ""
+		ok, _ := path.Match(pattern, u.String())
+		return ok
+	}
+}
+
+// Path matches requests whose URL path matches pattern using path.Match syntax.
+func Path(pattern string) Matcher {
+	return func(req *http.Request) bool {
+		ok, _ := path.Match(pattern, req.URL.Path)
+		return ok
+	}
+}
+
+// Query matches requests with a query parameter key equal to value.
+func Query(key, value string) Matcher {
+	return func(req *http.Request) bool {
+		return req.URL.Query().Get(key) == value
+	}
+}
+
+// Header matches requests with a header key equal to value.
+func Header(key, value string) Matcher {
+	return func(req *http.Request) bool {
+		return req.Header.Get(key) == value
+	}
+}
+
+// Body matches requests whose body is exactly body.
+func Body(body string) Matcher {
+	return func(req *http.Request) bool {
+		return readBody(req) == body
+	}
+}
+
+// BodyContains matches requests whose body contains substr.
+func BodyContains(substr string) Matcher {
+	return func(req *http.Request) bool {
+		return strings.Contains(readBody(req), substr)
+	}
+}
+
+func readBody(req *http.Request) string {
+	if req.Body == nil {
+		return ""
+	}
+	body, _ := io.ReadAll(req.Body)
+	return string(body)
+}
+
+// Transport is an http.RoundTripper that answers requests from registered
+// expectations. A request that matches no expectation fails with an error and
+// is reported by Verify. It is safe for concurrent use.
+type Transport struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	unmatched    []string
+}
+
+// New returns a Transport with no expectations.
+func New() *Transport {
+	return &Transport{}
+}
+
+// ExpectCall registers an expectation for requests satisfying all matchers. By
+// default the expectation must be matched exactly once and responds with an
+// empty 200 OK.
+func (m *Transport) ExpectCall(matchers ...Matcher) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := (&Expectation{matchers: matchers, times: 1}).Respond(http.StatusOK, "")
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Interceptor returns an Interceptor that answers requests from m instead of
+// passing them to the rest of the chain.
+func (m *Transport) Interceptor() interceptor.Interceptor {
+	return func(http.RoundTripper) http.RoundTripper {
+		return m
+	}
+}
+
+// RoundTrip answers req from the first expectation that matches it and has not
+// been exhausted.
+func (m *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	m.mu.Lock()
+	e := m.match(req, body)
+	if e == nil {
+		m.unmatched = append(m.unmatched, req.Method+" "+req.URL.String())
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		return nil, fmt.Errorf("mock: no expectation matches %s %s", req.Method, req.URL)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := e.respond(clone)
+	if resp != nil && resp.Request == nil {
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// match returns the first available expectation matching req and records the
+// call. The caller must hold m.mu.
+func (m *Transport) match(req *http.Request, body []byte) *Expectation {
+	for _, e := range m.expectations {
+		if e.times >= 0 && e.calls >= e.times {
+			continue
+		}
+		matched := true
+		for _, matcher := range e.matchers {
+			clone := req.Clone(req.Context())
+			clone.Body = io.NopCloser(bytes.NewReader(body))
+			if !matcher(clone) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			e.calls++
+			return e
+		}
+	}
+	return nil
+}
+
+// Verify reports an error on t for every expectation that was not called the
+// expected number of times and every request that matched no expectation.
+func (m *Transport) Verify(t testing.TB) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, e := range m.expectations {
+		if e.times >= 0 && e.calls != e.times {
+			t.Errorf("mock: expectation %d was called %d times, expected %d", i, e.calls, e.times)
+		}
+	}
+	for _, req := range m.unmatched {
+		t.Errorf("mock: unexpected request %s", req)
+	}
+}
+
+// Expectation describes a set of requests and how to answer them. Its methods
+// return the Expectation so they can be chained.
+type Expectation struct {
+	matchers []Matcher
+	// times is the number of expected calls, or -1 for any number.
+	times   int
+	calls   int
+	respond func(*http.Request) (*http.Response, error)
+	header  http.Header
+}
+
+// Times sets the number of calls the expectation must receive.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes allows the expectation to be called any number of times, including
+// zero.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = -1
+	return e
+}
+
+// Respond answers matching requests with the given status code and body.
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.respond = func(*http.Request) (*http.Response, error) {
+		header := make(http.Header)
+		for key, values := range e.header {
+			header[key] = append([]string(nil), values...)
+		}
+		return &http.Response{
+			StatusCode:    status,
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}, nil
+	}
+	return e
+}
+
+// RespondHeader adds a header to responses created by Respond.
+func (e *Expectation) RespondHeader(key, value string) *Expectation {
+	if e.header == nil {
+		e.header = make(http.Header)
+	}
+	e.header.Add(key, value)
+	return e
+}
+
+// RespondFunc answers matching requests by calling f.
+func (e *Expectation) RespondFunc(f func(*http.Request) (*http.Response, error)) *Expectation {
+	e.respond = f
+	return e
+}
+
+// ReturnError fails matching requests with err.
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.respond = func(*http.Request) (*http.Response, error) {
+		return nil, err
+	}
+	return e
+}
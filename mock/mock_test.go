@@ -0,0 +1,134 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// recordingTB captures errors reported by Verify.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTransport(t *testing.T) {
+	m := New()
+	m.ExpectCall(Method("GET"), Path("/users/*"), Header("Authorization", "Bearer token")).
+		Respond(http.StatusOK, `{"name":"alice"}`).
+		RespondHeader("Content-Type", "application/json").
+		Times(2)
+	m.ExpectCall(Method("POST"), URL("http://api.example.com/users"), BodyContains("bob")).
+		Respond(http.StatusCreated, "")
+
+	base, err := url.Parse("http://api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := interceptor.New(m, interceptor.BaseURL(*base), interceptor.Header("Authorization", "Bearer token"))
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "/users/42", nil)
+		resp, err := pipeline.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != `{"name":"alice"}` {
+			t.Errorf("Expected canned body, got '%s'", body)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected Content-Type header to be set")
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/users", strings.NewReader(`{"name":"bob"}`))
+	resp, err := pipeline.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	m.Verify(t)
+}
+
+func TestTransportVerifyFailures(t *testing.T) {
+	m := New()
+	m.ExpectCall(Method("GET")).Times(2)
+	m.ExpectCall(Method("DELETE")).AnyTimes()
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := m.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	req, _ = http.NewRequest("PUT", "http://example.com", nil)
+	if _, err := m.RoundTrip(req); err == nil {
+		t.Errorf("Expected unmatched request to fail")
+	}
+
+	tb := &recordingTB{}
+	m.Verify(tb)
+	if len(tb.errors) != 2 {
+		t.Fatalf("Expected 2 verification errors, got %v", tb.errors)
+	}
+	if !strings.Contains(tb.errors[0], "called 1 times, expected 2") {
+		t.Errorf("Unexpected error for call count: %s", tb.errors[0])
+	}
+	if !strings.Contains(tb.errors[1], "PUT http://example.com") {
+		t.Errorf("Unexpected error for unmatched request: %s", tb.errors[1])
+	}
+}
+
+func TestTransportExhaustedExpectations(t *testing.T) {
+	m := New()
+	m.ExpectCall(Path("/flaky")).ReturnError(errors.New("connection reset"))
+	m.ExpectCall(Path("/flaky")).Respond(http.StatusOK, "recovered")
+
+	req, _ := http.NewRequest("GET", "http://example.com/flaky", nil)
+	if _, err := m.RoundTrip(req); err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected first call to fail with canned error, got %v", err)
+	}
+	resp, err := m.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected second call to succeed, got %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "recovered" {
+		t.Errorf("Expected body 'recovered', got '%s'", body)
+	}
+	m.Verify(t)
+}
+
+func TestTransportInterceptor(t *testing.T) {
+	m := New()
+	m.ExpectCall(Query("realm", "alpha")).Respond(http.StatusTeapot, "")
+
+	network := interceptor.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatalf("Expected request not to reach the network")
+		return nil, nil
+	})
+	pipeline := interceptor.New(network, m.Interceptor())
+
+	req, _ := http.NewRequest("GET", "http://example.com?realm=alpha", nil)
+	resp, err := pipeline.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected status %d, got %d", http.StatusTeapot, resp.StatusCode)
+	}
+	m.Verify(t)
+}
@@ -0,0 +1,169 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoToken is returned by the OAuth2 interceptor when its token source
+// returns neither a token nor an error.
+var ErrNoToken = errors.New("interceptor: token source returned no token")
+
+// OAuth2 returns an Interceptor that authorizes every request with an access
+// token obtained from tokenSource. Tokens are cached and shared by all requests
+// passing through the Interceptor until they expire.
+//
+// If the server responds with 401 Unauthorized, the token is refreshed and the
+// request is replayed once with the new token. Concurrent requests that fail
+// with the same token trigger a single refresh. Because the Interceptor does its
+// own caching, tokenSource should fetch a new token on every call. The sources
+// returned by oauth2.Config.TokenSource and clientcredentials.Config.TokenSource
+// are wrapped in oauth2.ReuseTokenSource and hand back the rejected token until
+// it expires; if the source returns the rejected token again, the 401 response
+// is returned to the caller instead of being replayed with it. For the client
+// credentials flow, use a source that fetches every time:
+//
+//	interceptor.OAuth2(interceptor.TokenSourceFunc(func() (*oauth2.Token, error) {
+//		return config.Token(ctx)
+//	}))
+//
+// oauth2.TokenSource.Token takes no context, so tokens are fetched in the
+// background and a request whose context is done stops waiting for one.
+//
+// Requests with a body can only be replayed if req.GetBody is set; otherwise the
+// 401 response is returned to the caller unchanged. If GetBody fails, the
+// request fails with its error.
+func OAuth2(tokenSource oauth2.TokenSource) Interceptor {
+	cache := &tokenCache{source: tokenSource}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			token, err := cache.current(req.Context())
+			if err != nil {
+				return nil, newError("OAuth2", req, err)
+			}
+
+			resp, err := next.RoundTrip(authorize(req, token))
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			// Only replay requests whose body can be read a second time.
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+
+			fresh, err := cache.refresh(req.Context(), token)
+			if err != nil || fresh.AccessToken == token.AccessToken {
+				return resp, nil
+			}
+
+			retry := authorize(req, fresh)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					discard(resp)
+					return nil, newError("OAuth2", req, err)
+				}
+				retry.Body = body
+			}
+			// Release the connection held by the rejected response before replaying.
+			discard(resp)
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as
+// oauth2.TokenSource, for example to fetch a new token on every call.
+type TokenSourceFunc func() (*oauth2.Token, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// authorize returns a copy of req carrying the given token in its
+// Authorization header.
+func authorize(req *http.Request, token *oauth2.Token) *http.Request {
+	clone := withHeader(req)
+	token.SetAuthHeader(clone)
+	return clone
+}
+
+// tokenCache holds the most recent token fetched from an oauth2.TokenSource and
+// serializes fetches so only one is in progress at a time.
+type tokenCache struct {
+	mu     sync.Mutex
+	source oauth2.TokenSource
+	token  *oauth2.Token
+	// fetching is the fetch in progress, if any.
+	fetching *tokenFetch
+}
+
+// tokenFetch is a call to the token source shared by the requests waiting for
+// it. token and err are set before done is closed.
+type tokenFetch struct {
+	done  chan struct{}
+	token *oauth2.Token
+	err   error
+}
+
+// current returns the cached token, fetching a new one if none is cached or the
+// cached token has expired.
+func (c *tokenCache) current(ctx context.Context) (*oauth2.Token, error) {
+	c.mu.Lock()
+	if token := c.token; token.Valid() {
+		c.mu.Unlock()
+		return token, nil
+	}
+	return c.fetch(ctx)
+}
+
+// refresh replaces a token that was rejected by the server. If another request
+// has already replaced stale, the newer token is returned without fetching.
+func (c *tokenCache) refresh(ctx context.Context, stale *oauth2.Token) (*oauth2.Token, error) {
+	c.mu.Lock()
+	if token := c.token; token != stale && token.Valid() {
+		c.mu.Unlock()
+		return token, nil
+	}
+	return c.fetch(ctx)
+}
+
+// fetch waits for a new token from the source, joining the fetch in progress
+// if there is one, until ctx is done. The caller must hold c.mu, which fetch
+// releases. The fetch itself runs in its own goroutine without holding c.mu,
+// since the source cannot be interrupted.
+func (c *tokenCache) fetch(ctx context.Context) (*oauth2.Token, error) {
+	f := c.fetching
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		c.fetching = f
+		go func() {
+			token, err := c.source.Token()
+			if err == nil && token == nil {
+				err = ErrNoToken
+			}
+			c.mu.Lock()
+			if err == nil {
+				c.token = token
+			}
+			c.fetching = nil
+			c.mu.Unlock()
+			f.token, f.err = token, err
+			close(f.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
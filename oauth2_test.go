@@ -0,0 +1,218 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// countingTokenSource hands out "token-1", "token-2", ... on successive calls.
+type countingTokenSource struct {
+	calls atomic.Int32
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	n := s.calls.Add(1)
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", n), TokenType: "Bearer"}, nil
+}
+
+func TestOAuth2Interceptor(t *testing.T) {
+	source := &countingTokenSource{}
+	var got []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = append(got, req.Header.Get("Authorization"))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := OAuth2(source)(transport)
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("Expected original request to be left unmodified")
+		}
+	}
+
+	for _, header := range got {
+		if header != "Bearer token-1" {
+			t.Errorf("Expected header to be 'Bearer token-1', got '%s'", header)
+		}
+	}
+	if calls := source.calls.Load(); calls != 1 {
+		t.Errorf("Expected token to be fetched once, got %d", calls)
+	}
+}
+
+func TestOAuth2InterceptorRefreshesOnUnauthorized(t *testing.T) {
+	source := &countingTokenSource{}
+	var bodies []string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		if req.Header.Get("Authorization") == "Bearer token-1" {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := OAuth2(source)(transport)
+
+	req, err := http.NewRequest("POST", "http://example.com", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Errorf("Expected request body to be replayed, got %q", bodies)
+	}
+}
+
+func TestOAuth2InterceptorDoesNotReplayUnrewindableBody(t *testing.T) {
+	source := &countingTokenSource{}
+	calls := 0
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+	})
+	rt := OAuth2(source)(transport)
+
+	req, err := http.NewRequest("POST", "http://example.com", io.NopCloser(bytes.NewBufferString("payload")))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestOAuth2InterceptorSingleConcurrentRefresh(t *testing.T) {
+	source := &countingTokenSource{}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") == "Bearer token-1" {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := OAuth2(source)(transport)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Errorf("Failed to perform request: %v", err)
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := source.calls.Load(); calls != 2 {
+		t.Errorf("Expected exactly one refresh (2 token fetches), got %d fetches", calls)
+	}
+}
+
+func TestOAuth2InterceptorReuseTokenSource(t *testing.T) {
+	calls := 0
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+	})
+	// A caching source hands back the rejected token, which must not be re-sent.
+	source := oauth2.ReuseTokenSource(nil, &countingTokenSource{})
+	rt := OAuth2(source)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || calls != 1 {
+		t.Errorf("Expected the 401 to be returned after a single attempt, got %d after %d", resp.StatusCode, calls)
+	}
+}
+
+func TestOAuth2InterceptorFetchHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	source := TokenSourceFunc(func() (*oauth2.Token, error) {
+		<-release
+		return &oauth2.Token{AccessToken: "late"}, nil
+	})
+	rt := OAuth2(source)(&mockRoundTripper{})
+
+	// Neither request waits for the source once its context is done.
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+		_, err := rt.RoundTrip(req)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	}
+}
+
+func TestOAuth2InterceptorGetBodyError(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+	})
+	rt := OAuth2(&countingTokenSource{})(transport)
+
+	failure := errors.New("body unavailable")
+	req, _ := http.NewRequest("POST", "http://example.com", bytes.NewBufferString("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, failure }
+	_, err := rt.RoundTrip(req)
+	var ierr *Error
+	if !errors.Is(err, failure) || !errors.As(err, &ierr) || ierr.Interceptor != "OAuth2" {
+		t.Errorf("Expected an *Error from OAuth2 wrapping the GetBody error, got %v", err)
+	}
+}
+
+func TestOAuth2InterceptorNilToken(t *testing.T) {
+	source := TokenSourceFunc(func() (*oauth2.Token, error) { return nil, nil })
+	mockRT := &mockRoundTripper{Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}}
+	rt := OAuth2(source)(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+	if mockRT.Request != nil {
+		t.Errorf("Expected the request not to be sent")
+	}
+}
@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"maps"
+	"net/http"
+)
+
+type overridesKey struct{}
+
+// overrides records which named interceptors a request context disables.
+type overrides struct {
+	// skip holds the names disabled by Skip.
+	skip map[string]bool
+	// only holds the names enabled by Only, or is nil if Only was not used.
+	only map[string]bool
+}
+
+// Skip returns a copy of ctx that makes a Pipeline bypass the interceptors
+// registered under any of names with UseNamed, for requests made with it. It
+// lets a call site opt out of behavior such as caching or retries, for example
+// for a health check, without a second client:
+//
+//	ctx = interceptor.Skip(ctx, "retry")
+//
+// Names skipped by earlier calls stay skipped.
+func Skip(ctx context.Context, names ...string) context.Context {
+	o := overridesFrom(ctx)
+	o.skip = maps.Clone(o.skip)
+	if o.skip == nil {
+		o.skip = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		o.skip[name] = true
+	}
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// Only returns a copy of ctx that makes a Pipeline bypass every named
+// interceptor except those registered under one of names, for requests made
+// with it. Interceptors added without a name always run. A later call to Only
+// replaces the names of an earlier one, and names passed to Skip are bypassed
+// even if listed here.
+func Only(ctx context.Context, names ...string) context.Context {
+	o := overridesFrom(ctx)
+	o.only = make(map[string]bool, len(names))
+	for _, name := range names {
+		o.only[name] = true
+	}
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// Skipped reports whether ctx makes a Pipeline bypass the interceptor
+// registered under name.
+func Skipped(ctx context.Context, name string) bool {
+	o, ok := ctx.Value(overridesKey{}).(overrides)
+	if !ok {
+		return false
+	}
+	return o.skip[name] || (o.only != nil && !o.only[name])
+}
+
+// overridesFrom returns the overrides carried by ctx, or none.
+func overridesFrom(ctx context.Context) overrides {
+	o, _ := ctx.Value(overridesKey{}).(overrides)
+	return o
+}
+
+// skippable returns wrapped, the interceptor registered under name applied to
+// next, such that requests whose context skips name go straight to next.
+func skippable(name string, wrapped, next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if Skipped(req.Context(), name) {
+			return next.RoundTrip(req)
+		}
+		return wrapped.RoundTrip(req)
+	})
+}
@@ -0,0 +1,43 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSkipAndOnly(t *testing.T) {
+	var ran []string
+	mark := recorder(&ran)
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	pipeline := New(transport, mark("unnamed"))
+	pipeline.UseNamed("cache", mark("cache"))
+	pipeline.UseNamed("retry", mark("retry"))
+	pipeline.UseNamed("auth", mark("auth"))
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected []string
+	}{
+		{"no overrides", context.Background(), []string{"unnamed", "cache", "retry", "auth"}},
+		{"skip", Skip(context.Background(), "retry"), []string{"unnamed", "cache", "auth"}},
+		{"skip twice", Skip(Skip(context.Background(), "retry"), "cache"), []string{"unnamed", "auth"}},
+		{"only", Only(context.Background(), "auth"), []string{"unnamed", "auth"}},
+		{"only and skip", Skip(Only(context.Background(), "auth", "cache"), "cache"), []string{"unnamed", "auth"}},
+	}
+
+	for _, test := range tests {
+		ran = nil
+		req, _ := http.NewRequestWithContext(test.ctx, "GET", "http://example.com", nil)
+		if _, err := pipeline.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if !slices.Equal(ran, test.expected) {
+			t.Errorf("%s: expected %v to run, got %v", test.name, test.expected, ran)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Package pagination walks paginated REST collections through an http.Client,
+// typically one whose transport is an interceptor.Pipeline, so that every
+// page request gets the same authentication, retries, and rate limiting:
+//
+//	pager := pagination.Paginate(client, req)
+//	for pager.Next() {
+//		var users []User
+//		if err := json.Unmarshal(pager.Body(), &users); err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//	if err := pager.Err(); err != nil {
+//		return err
+//	}
+//
+// By default the next page is found in the Link header of each response, as
+// described by RFC 8288 (formerly RFC 5988). Offset and Cursor handle APIs
+// that page through query parameters instead.
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// ErrCrossOrigin ends the iteration when the next page is on another scheme or
+// host than the pages before it. Page requests carry the headers of the first
+// request, such as Authorization and cookies, which must not be sent to a host
+// named by a server's response.
+var ErrCrossOrigin = errors.New("pagination: next page is on another origin")
+
+// ErrRepeatedPage ends the iteration when the next page is one that was
+// already fetched, which would otherwise loop forever.
+var ErrRepeatedPage = errors.New("pagination: next page was already fetched")
+
+// NextFunc returns the URL of the page after the one at current, given its
+// response and body, or nil if it is the last page.
+type NextFunc func(current *url.URL, resp *http.Response, body []byte) (*url.URL, error)
+
+// Option configures Paginate.
+type Option func(*config)
+
+type config struct {
+	next     NextFunc
+	maxPages int
+}
+
+// Next sets how the URL of the next page is found.
+func Next(f NextFunc) Option {
+	return func(c *config) {
+		c.next = f
+	}
+}
+
+// Link finds the next page in the Link header entry whose relation type is
+// "next", resolved against the current URL. It is the default.
+func Link() Option {
+	return Next(func(current *url.URL, resp *http.Response, _ []byte) (*url.URL, error) {
+		for _, value := range resp.Header.Values("Link") {
+			if target, ok := nextLink(value); ok {
+				next, err := current.Parse(target)
+				if err != nil {
+					return nil, fmt.Errorf("invalid Link header: %w", err)
+				}
+				return next, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// Offset pages by setting the query parameter param to the number of items
+// already received, asking for pageSize items at a time. count returns the
+// number of items in a page body. A page with fewer than pageSize items is the
+// last.
+func Offset(param string, pageSize int, count func(body []byte) (int, error)) Option {
+	return Next(func(current *url.URL, _ *http.Response, body []byte) (*url.URL, error) {
+		n, err := count(body)
+		if err != nil || n < pageSize || n == 0 {
+			return nil, err
+		}
+		query := current.Query()
+		offset, _ := strconv.Atoi(query.Get(param))
+		query.Set(param, strconv.Itoa(offset+n))
+		next := *current
+		next.RawQuery = query.Encode()
+		return &next, nil
+	})
+}
+
+// Cursor pages by setting the query parameter param to the cursor that cursor
+// extracts from each page body. An empty cursor marks the last page.
+func Cursor(param string, cursor func(body []byte) (string, error)) Option {
+	return Next(func(current *url.URL, _ *http.Response, body []byte) (*url.URL, error) {
+		c, err := cursor(body)
+		if err != nil || c == "" {
+			return nil, err
+		}
+		query := current.Query()
+		query.Set(param, c)
+		next := *current
+		next.RawQuery = query.Encode()
+		return &next, nil
+	})
+}
+
+// MaxPages stops after n pages. By default there is no limit.
+func MaxPages(n int) Option {
+	return func(c *config) {
+		c.maxPages = n
+	}
+}
+
+// Pager iterates over the pages of a collection. Each call to Next fetches a
+// page, whose response and body are then available until the following call.
+// A Pager is not safe for concurrent use.
+type Pager struct {
+	client *http.Client
+	cfg    config
+	next   *http.Request
+	resp   *http.Response
+	body   []byte
+	pages  int
+	err    error
+	// origin is the scheme and host of the pages, and seen holds the URLs of
+	// the pages requested so far.
+	origin string
+	seen   map[string]bool
+	// stop is the error that ends the iteration after the current page.
+	stop error
+}
+
+// Paginate returns a Pager that fetches the pages of the collection starting
+// at req with client, or http.DefaultClient if client is nil. Each page is
+// requested with a copy of req, including its context and headers, carrying
+// the page URL. Requests with a body must have GetBody set so that it can be
+// sent again.
+//
+// Since the headers of req are sent with every page, the iteration ends with
+// ErrCrossOrigin if a next page is on another scheme or host than req, or than
+// the first absolute page URL if req's URL is relative. It ends with
+// ErrRepeatedPage if a next page was already fetched.
+func Paginate(client *http.Client, req *http.Request, opts ...Option) *Pager {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	p := &Pager{client: client, next: req, seen: map[string]bool{req.URL.String(): true}}
+	if req.URL.Host != "" {
+		p.origin = origin(req.URL)
+	}
+	Link()(&p.cfg)
+	for _, opt := range opts {
+		opt(&p.cfg)
+	}
+	return p
+}
+
+// Next fetches the next page and reports whether it succeeded. It returns
+// false after the last page or on the first error, which is then returned by
+// Err. Responses without a 2xx status end the iteration with an
+// *interceptor.StatusError.
+func (p *Pager) Next() bool {
+	if p.stop != nil {
+		p.err, p.stop = p.stop, nil
+	}
+	if p.err != nil || p.next == nil || (p.cfg.maxPages > 0 && p.pages >= p.cfg.maxPages) {
+		return false
+	}
+	req := p.next
+	p.next, p.resp, p.body = nil, nil, nil
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		p.err = fmt.Errorf("pagination: reading page: %w", err)
+		return false
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		p.err = &interceptor.StatusError{
+			Method:     req.Method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			Body:       body,
+		}
+		return false
+	}
+
+	next, err := p.cfg.next(req.URL, resp, body)
+	if err != nil {
+		p.err = fmt.Errorf("pagination: finding next page: %w", err)
+		return false
+	}
+	if next != nil {
+		if err := p.check(next); err != nil {
+			// The current page is fine; the iteration ends after it.
+			p.stop = err
+			next = nil
+		}
+	}
+	if next != nil {
+		if p.next, err = nextRequest(req, next); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	p.resp, p.body = resp, body
+	p.pages++
+	return true
+}
+
+// Response returns the response of the current page. Its body has already
+// been read and closed; the content is returned by Body.
+func (p *Pager) Response() *http.Response {
+	return p.resp
+}
+
+// Body returns the body of the current page.
+func (p *Pager) Body() []byte {
+	return p.body
+}
+
+// Page returns the number of the current page, starting at 1.
+func (p *Pager) Page() int {
+	return p.pages
+}
+
+// Err returns the error that ended the iteration, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// check returns an error if the next page at u must not be fetched.
+func (p *Pager) check(u *url.URL) error {
+	if u.Host != "" {
+		if p.origin == "" {
+			p.origin = origin(u)
+		} else if origin(u) != p.origin {
+			return fmt.Errorf("%w: %s", ErrCrossOrigin, u.Redacted())
+		}
+	}
+	if p.seen[u.String()] {
+		return fmt.Errorf("%w: %s", ErrRepeatedPage, u.Redacted())
+	}
+	p.seen[u.String()] = true
+	return nil
+}
+
+// origin returns the scheme and host of u in lower case.
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// nextRequest returns a copy of req for the URL u.
+func nextRequest(req *http.Request, u *url.URL) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	next.URL = u
+	next.Host = ""
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("pagination: resetting request body: %w", err)
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+// nextLink returns the target of the link with relation type "next" in a Link
+// header value, such as `<https://api.example.com/items?page=2>; rel="next"`.
+func nextLink(header string) (string, bool) {
+	for header != "" {
+		start := strings.IndexByte(header, '<')
+		end := strings.IndexByte(header, '>')
+		if start < 0 || end < start {
+			return "", false
+		}
+		target := header[start+1 : end]
+		params := header[end+1:]
+		if next := strings.IndexByte(params, '<'); next >= 0 {
+			params, header = params[:next], params[next:]
+		} else {
+			header = ""
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return target, true
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,162 @@
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+)
+
+// items is a fake collection of seven items served in pages.
+var items = []string{"a", "b", "c", "d", "e", "f", "g"}
+
+func collect(t *testing.T, pager *Pager) []string {
+	t.Helper()
+	var got []string
+	for pager.Next() {
+		var page []string
+		if err := json.Unmarshal(pager.Body(), &page); err != nil {
+			t.Fatalf("Failed to decode page %d: %v", pager.Page(), err)
+		}
+		got = append(got, page...)
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Failed to paginate: %v", err)
+	}
+	return got
+}
+
+func respond(header http.Header, page []string) *http.Response {
+	body, _ := json.Marshal(page)
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(string(body)))}
+}
+
+func countItems(body []byte) (int, error) {
+	var page []string
+	err := json.Unmarshal(body, &page)
+	return len(page), err
+}
+
+func TestPaginateLink(t *testing.T) {
+	var auth []string
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		auth = append(auth, req.Header.Get("Authorization"))
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		header := make(http.Header)
+		if end := (page + 1) * 3; end < len(items) {
+			header.Set("Link", `</items?page=0>; rel="first", </items?page=`+strconv.Itoa(page+1)+`>; rel="next"`)
+			return respond(header, items[page*3:end]), nil
+		}
+		return respond(header, items[page*3:]), nil
+	})
+	client := interceptor.NewClient(
+		interceptor.ClientTransport(transport),
+		interceptor.ClientInterceptors(interceptor.Header("Authorization", "Bearer token")),
+	)
+
+	req, _ := http.NewRequest("GET", "http://example.com/items", nil)
+	if got := collect(t, Paginate(client, req)); !slices.Equal(got, items) {
+		t.Errorf("Expected %v, got %v", items, got)
+	}
+	if len(auth) != 3 || auth[2] != "Bearer token" {
+		t.Errorf("Expected every page to go through the pipeline, got %v", auth)
+	}
+
+	pager := Paginate(client, req, MaxPages(2))
+	if got := collect(t, pager); len(got) != 6 || pager.Page() != 2 {
+		t.Errorf("Expected 2 pages, got %d (%v)", pager.Page(), got)
+	}
+}
+
+func TestPaginateOffsetAndCursor(t *testing.T) {
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		start, _ := strconv.Atoi(query.Get("offset") + query.Get("cursor"))
+		end := min(start+3, len(items))
+		if query.Has("cursor") || req.URL.Path == "/cursor" {
+			next := ""
+			if end < len(items) {
+				next = strconv.Itoa(end)
+			}
+			body, _ := json.Marshal(map[string]any{"items": items[start:end], "next": next})
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+		}
+		return respond(nil, items[start:end]), nil
+	})
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequest("GET", "http://example.com/offset?limit=3", nil)
+	if got := collect(t, Paginate(client, req, Offset("offset", 3, countItems))); !slices.Equal(got, items) {
+		t.Errorf("Expected %v with offsets, got %v", items, got)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com/cursor", nil)
+	pager := Paginate(client, req, Cursor("cursor", func(body []byte) (string, error) {
+		var page struct{ Next string }
+		err := json.Unmarshal(body, &page)
+		return page.Next, err
+	}))
+	pages := 0
+	for pager.Next() {
+		pages++
+	}
+	if pager.Err() != nil || pages != 3 {
+		t.Errorf("Expected 3 pages with cursors, got %d (%v)", pages, pager.Err())
+	}
+}
+
+func TestPaginateStatusError(t *testing.T) {
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusForbidden, Status: "403 Forbidden", Body: http.NoBody}, nil
+	})
+	req, _ := http.NewRequest("GET", "http://example.com/items", nil)
+	pager := Paginate(&http.Client{Transport: transport}, req)
+
+	if pager.Next() {
+		t.Error("Expected no pages")
+	}
+	var statusErr *interceptor.StatusError
+	if !errors.As(pager.Err(), &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a StatusError, got %v", pager.Err())
+	}
+}
+
+func TestPaginateUnsafeLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		err  error
+	}{
+		{"cross origin", `<https://evil.example.net/items?page=2>; rel="next"`, ErrCrossOrigin},
+		{"other scheme", `<https://example.com/items?page=2>; rel="next"`, ErrCrossOrigin},
+		{"self", `</items>; rel="next"`, ErrRepeatedPage},
+	}
+
+	for _, test := range tests {
+		var hosts []string
+		transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			hosts = append(hosts, req.URL.Host)
+			return respond(http.Header{"Link": {test.link}}, items[:3]), nil
+		})
+		req, _ := http.NewRequest("GET", "http://example.com/items", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		pager := Paginate(&http.Client{Transport: transport}, req)
+
+		pages := 0
+		for pager.Next() {
+			pages++
+		}
+		if !errors.Is(pager.Err(), test.err) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.err, pager.Err())
+		}
+		if pages != 1 || len(hosts) != 1 {
+			t.Errorf("%s: Expected only the first page to be fetched, got %d pages from %v", test.name, pages, hosts)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Policy bundles the resilience settings of a client so that WithPolicy can
+// apply them in the right order. The zero value of each field disables the
+// corresponding behavior.
+type Policy struct {
+	// Timeout bounds the whole request, including every attempt and the waits
+	// between them.
+	Timeout time.Duration
+	// Retry configures retries of failed attempts.
+	Retry RetryPolicy
+	// PerTryTimeout bounds each attempt.
+	PerTryTimeout time.Duration
+	// CircuitBreaker enables a circuit breaker per host, configured by
+	// CircuitBreakerOptions.
+	CircuitBreaker        bool
+	CircuitBreakerOptions []CircuitBreakerOption
+	// RateLimit limits attempts to this many per second, with bursts of up to
+	// RateLimitBurst, configured by RateLimitOptions.
+	RateLimit        rate.Limit
+	RateLimitBurst   int
+	RateLimitOptions []RateLimitOption
+}
+
+// WithPolicy returns an Interceptor that applies the settings of p. Composing
+// the corresponding interceptors by hand is easy to get wrong, so WithPolicy
+// always applies them in this order, from outermost to innermost:
+//
+//  1. Timeout, so that the deadline covers all attempts.
+//  2. Retry, so that every attempt passes through the steps below. Attempts
+//     rejected by the circuit breaker or rate limiter are not retried, which
+//     keeps retries from hammering an open circuit.
+//  3. RateLimit, so that each attempt, not each request, consumes a token.
+//  4. CircuitBreaker, so that it sees the outcome of every attempt, including
+//     attempts cut short by the per-try timeout, but not rate limit errors.
+//  5. PerTryTimeout, so that each attempt gets its own budget.
+func WithPolicy(p Policy) Interceptor {
+	var interceptors []Interceptor
+	if p.Timeout > 0 {
+		interceptors = append(interceptors, Timeout(p.Timeout))
+	}
+	if p.Retry.MaxAttempts > 1 {
+		interceptors = append(interceptors, Retry(p.Retry))
+	}
+	if p.RateLimit > 0 {
+		interceptors = append(interceptors, RateLimit(p.RateLimit, max(p.RateLimitBurst, 1), p.RateLimitOptions...))
+	}
+	if p.CircuitBreaker {
+		interceptors = append(interceptors, CircuitBreaker(p.CircuitBreakerOptions...))
+	}
+	if p.PerTryTimeout > 0 {
+		interceptors = append(interceptors, PerTryTimeout(p.PerTryTimeout))
+	}
+	return Chain(interceptors...)
+}
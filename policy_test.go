@@ -0,0 +1,43 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithPolicy(t *testing.T) {
+	attempts := 0
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	rt := WithPolicy(Policy{
+		Timeout:               time.Second,
+		Retry:                 RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond},
+		PerTryTimeout:         100 * time.Millisecond,
+		CircuitBreaker:        true,
+		CircuitBreakerOptions: []CircuitBreakerOption{CircuitBreakerThreshold(2)},
+	})(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, err := rt.RoundTrip(req)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected retries to stop at the open circuit, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts before the circuit opened, got %d", attempts)
+	}
+}
+
+func TestWithPolicyEmpty(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := WithPolicy(Policy{})(transport).RoundTrip(req); err != nil {
+		t.Errorf("Expected the empty policy to pass requests through, got %v", err)
+	}
+}
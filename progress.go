@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+)
+
+// ProgressOption configures the Progress interceptor.
+type ProgressOption func(*progressConfig)
+
+type progressConfig struct {
+	download func(read, total int64)
+}
+
+// ProgressDownload reports the progress of response bodies to cb as they are
+// read, in the same way that Progress reports uploads.
+func ProgressDownload(cb func(read, total int64)) ProgressOption {
+	return func(c *progressConfig) {
+		c.download = cb
+	}
+}
+
+// Progress returns an Interceptor that reports the progress of request body
+// uploads, for example to drive a progress bar or to measure throughput. cb is
+// called each time part of the body is read by the transport with the number
+// of bytes written so far and the total size, which is -1 if unknown. If the
+// body is sent again, for example by an interceptor that retries requests,
+// the count starts over.
+//
+// cb is called from the goroutine sending the request and should return
+// quickly. Requests without a body are not reported.
+func Progress(cb func(written, total int64), opts ...ProgressOption) Interceptor {
+	var cfg progressConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if cb != nil && req.Body != nil && req.Body != http.NoBody {
+				req = req.Clone(req.Context())
+				total := req.ContentLength
+				if total == 0 {
+					total = -1
+				}
+				req.Body = &progressBody{ReadCloser: req.Body, total: total, report: cb}
+				if getBody := req.GetBody; getBody != nil {
+					req.GetBody = func() (io.ReadCloser, error) {
+						body, err := getBody()
+						if err != nil {
+							return nil, err
+						}
+						return &progressBody{ReadCloser: body, total: total, report: cb}, nil
+					}
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || cfg.download == nil || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, err
+			}
+			resp.Body = &progressBody{ReadCloser: resp.Body, total: resp.ContentLength, report: cfg.download}
+			return resp, nil
+		})
+	}
+}
+
+// progressBody counts the bytes read from a body and reports them after each
+// read.
+type progressBody struct {
+	io.ReadCloser
+	count  int64
+	total  int64
+	report func(count, total int64)
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.count += int64(n)
+		b.report(b.count, b.total)
+	}
+	return n, err
+}
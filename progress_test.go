@@ -0,0 +1,42 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestProgressInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		io.Copy(io.Discard, io.LimitReader(req.Body, 4))
+		io.Copy(io.Discard, req.Body)
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(strings.NewReader("response")),
+			ContentLength: 8,
+		}, nil
+	})
+
+	type report struct{ n, total int64 }
+	var uploads, downloads []report
+	rt := Progress(func(written, total int64) {
+		uploads = append(uploads, report{written, total})
+	}, ProgressDownload(func(read, total int64) {
+		downloads = append(downloads, report{read, total})
+	}))(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("0123456789"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	io.ReadAll(resp.Body)
+
+	if len(uploads) != 2 || uploads[0] != (report{4, 10}) || uploads[1] != (report{10, 10}) {
+		t.Errorf("Expected upload progress at 4 and 10 of 10 bytes, got %v", uploads)
+	}
+	if len(downloads) == 0 || downloads[len(downloads)-1] != (report{8, 8}) {
+		t.Errorf("Expected download progress to reach 8 of 8 bytes, got %v", downloads)
+	}
+}
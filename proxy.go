@@ -0,0 +1,140 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ProxyRule routes the requests matched by Match through Proxy.
+type ProxyRule struct {
+	// Match selects the requests the rule applies to. If nil, the rule applies
+	// to every request.
+	Match Matcher
+	// Proxy is the URL of the proxy, such as "http://proxy.example.com:3128".
+	// User information in the URL is sent as proxy credentials. If nil, matching
+	// requests connect directly.
+	Proxy *url.URL
+}
+
+type proxyKey struct{}
+
+// ProxyFrom returns the proxy chosen for a request by the Proxy interceptor.
+// It reports false if no rule matched the request, and returns a nil URL if the
+// rule that matched sends it directly.
+func ProxyFrom(ctx context.Context) (*url.URL, bool) {
+	proxy, ok := ctx.Value(proxyKey{}).(*url.URL)
+	return proxy, ok
+}
+
+// Proxy returns an Interceptor that chooses an outbound proxy for each request
+// using the first of rules that matches it, and records the choice in the
+// request context. The choice only takes effect when the Pipeline's transport
+// is an http.Transport whose Proxy field is set to ProxyFromPipeline:
+//
+//	transport := &http.Transport{Proxy: interceptor.ProxyFromPipeline}
+//	pipeline := interceptor.New(transport, interceptor.Proxy(
+//		interceptor.ProxyRule{Match: interceptor.NoProxy("localhost,.internal")},
+//		interceptor.ProxyRule{Match: interceptor.HostGlob("*.partner.com"), Proxy: partnerProxy},
+//		interceptor.ProxyRule{Proxy: corporateProxy},
+//	))
+//
+// Requests that match no rule are left to the environment, as described at
+// ProxyFromPipeline.
+func Proxy(rules ...ProxyRule) Interceptor {
+	rules = append([]ProxyRule(nil), rules...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			for _, rule := range rules {
+				if rule.Match == nil || rule.Match(req) {
+					ctx := context.WithValue(req.Context(), proxyKey{}, rule.Proxy)
+					return next.RoundTrip(req.WithContext(ctx))
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// ProxyFromPipeline returns the proxy chosen for req by the Proxy interceptor.
+// It is meant to be used as the Proxy field of an http.Transport. Requests for
+// which Proxy made no choice fall back to http.ProxyFromEnvironment.
+func ProxyFromPipeline(req *http.Request) (*url.URL, error) {
+	if proxy, ok := ProxyFrom(req.Context()); ok {
+		return proxy, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// NoProxy returns a Matcher for requests that should bypass a proxy, using the
+// comma-separated format of the NO_PROXY environment variable:
+//
+//   - a host name, such as "example.com", matches that host and its subdomains
+//   - a name with a leading dot, such as ".example.com", matches subdomains only
+//   - an IP address matches that address, and a CIDR range, such as
+//     "10.0.0.0/8", matches the addresses in it
+//   - any of the above followed by ":port" matches only that port
+//   - "*" matches every request
+//
+// Entries are compared case-insensitively.
+func NoProxy(list string) Matcher {
+	var entries []noProxyEntry
+	for _, field := range strings.Split(list, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if field == "*" {
+			return func(*http.Request) bool { return true }
+		}
+		var entry noProxyEntry
+		if prefix, err := netip.ParsePrefix(field); err == nil {
+			entry.prefix = prefix
+		} else {
+			entry.host = field
+			if host, port, err := net.SplitHostPort(field); err == nil {
+				entry.host, entry.port = host, port
+			}
+			if addr, err := netip.ParseAddr(strings.Trim(entry.host, "[]")); err == nil {
+				entry.prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return func(req *http.Request) bool {
+		host := strings.ToLower(req.URL.Hostname())
+		port := req.URL.Port()
+		addr, addrErr := netip.ParseAddr(host)
+		for _, entry := range entries {
+			if entry.port != "" && entry.port != port {
+				continue
+			}
+			if entry.prefix.IsValid() {
+				if addrErr == nil && entry.prefix.Contains(addr) {
+					return true
+				}
+				continue
+			}
+			if strings.HasPrefix(entry.host, ".") {
+				if strings.HasSuffix(host, entry.host) {
+					return true
+				}
+			} else if host == entry.host || strings.HasSuffix(host, "."+entry.host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// noProxyEntry is a single entry of a NoProxy list. Address entries are held
+// as prefixes, and name entries as host names.
+type noProxyEntry struct {
+	prefix netip.Prefix
+	host   string
+	port   string
+}
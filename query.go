@@ -0,0 +1,40 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Query returns an Interceptor that adds or overrides the query parameter key
+// with value on each request, for APIs that expect a parameter such as an
+// api_key or realm on every call. Empty keys are ignored.
+func Query(key string, value string) Interceptor {
+	return QueryFunc(func(*http.Request) url.Values {
+		if key == "" {
+			return nil
+		}
+		return url.Values{key: {value}}
+	})
+}
+
+// QueryFunc returns an Interceptor that merges the parameters returned by f
+// into each request's query. Every key returned by f replaces the values
+// already present for that key, and other parameters are kept. If f returns no
+// parameters the URL is left unchanged.
+func QueryFunc(f func(*http.Request) url.Values) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			params := f(req)
+			if len(params) == 0 {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			query := req.URL.Query()
+			for key, values := range params {
+				query[key] = values
+			}
+			req.URL.RawQuery = query.Encode()
+			return next.RoundTrip(req)
+		})
+	}
+}
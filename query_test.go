@@ -0,0 +1,59 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestQueryInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+
+	tests := []struct {
+		key         string
+		value       string
+		originalURL string
+		expectedURL string
+	}{
+		{"api_key", "secret", "http://example.com/items", "http://example.com/items?api_key=secret"},
+		{"realm", "alpha", "http://example.com/items?page=2", "http://example.com/items?page=2&realm=alpha"},
+		{"realm", "alpha", "http://example.com/items?realm=bravo", "http://example.com/items?realm=alpha"},
+		{"realm", "a b&c", "http://example.com", "http://example.com?realm=a+b%26c"},
+		{"", "ignored", "http://example.com/items?page=2", "http://example.com/items?page=2"},
+	}
+
+	for _, test := range tests {
+		rt := Query(test.key, test.value)(mockRT)
+		req, _ := http.NewRequest("GET", test.originalURL, nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if sent := mockRT.Request.URL.String(); sent != test.expectedURL {
+			t.Errorf("Expected URL to be '%s', got '%s'", test.expectedURL, sent)
+		}
+		if req.URL.String() != test.originalURL {
+			t.Errorf("Expected original request URL to be left as '%s', got '%s'", test.originalURL, req.URL.String())
+		}
+	}
+}
+
+func TestQueryFuncInterceptor(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := QueryFunc(func(req *http.Request) url.Values {
+		return url.Values{"method": {req.Method}, "tag": {"a", "b"}}
+	})(mockRT)
+
+	req, _ := http.NewRequest("DELETE", "http://example.com/items?tag=old&keep=1", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	expected := "http://example.com/items?keep=1&method=DELETE&tag=a&tag=b"
+	if sent := mockRT.Request.URL.String(); sent != expected {
+		t.Errorf("Expected URL to be '%s', got '%s'", expected, sent)
+	}
+}
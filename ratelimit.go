@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned by the RateLimit interceptor when it is configured
+// not to wait and a request exceeds the allowed rate.
+var ErrRateLimited = errors.New("interceptor: rate limit exceeded")
+
+// RateLimitOption configures the RateLimit interceptor.
+type RateLimitOption func(*rateLimitConfig)
+
+type rateLimitConfig struct {
+	key  func(*http.Request) string
+	wait bool
+}
+
+// RateLimitPerHost gives every request host its own token bucket instead of
+// sharing a single bucket across all requests.
+func RateLimitPerHost() RateLimitOption {
+	return RateLimitKeyFunc(func(req *http.Request) string {
+		return req.URL.Host
+	})
+}
+
+// RateLimitKeyFunc gives every distinct key returned by f its own token bucket.
+// It can be used to limit by path pattern, tenant, or any other request
+// attribute. Requests for which f returns the same key share a bucket.
+func RateLimitKeyFunc(f func(*http.Request) string) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.key = f
+	}
+}
+
+// RateLimitNoWait makes requests that exceed the rate fail immediately with
+// ErrRateLimited instead of waiting for a token.
+func RateLimitNoWait() RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.wait = false
+	}
+}
+
+// RateLimit returns an Interceptor that limits requests to r per second with
+// bursts of up to burst requests, using a token bucket.
+//
+// By default requests block until a token is available. Waiting respects the
+// request context, so a canceled context or one whose deadline would pass
+// before a token is available fails the request with the context's error.
+func RateLimit(r rate.Limit, burst int, opts ...RateLimitOption) Interceptor {
+	cfg := rateLimitConfig{
+		key:  func(*http.Request) string { return "" },
+		wait: true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+	limiterFor := func(key string) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := limiters[key]
+		if !ok {
+			l = rate.NewLimiter(r, burst)
+			limiters[key] = l
+		}
+		return l
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			limiter := limiterFor(cfg.key(req))
+			if !cfg.wait {
+				if !limiter.Allow() {
+					return nil, newError("RateLimit", req, ErrRateLimited)
+				}
+			} else if err := limiter.Wait(req.Context()); err != nil {
+				return nil, newError("RateLimit", req, err)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
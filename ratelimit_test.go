@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitInterceptorNoWait(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := RateLimit(rate.Every(time.Hour), 2, RateLimitPerHost(), RateLimitNoWait())(mockRT)
+
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"http://a.example.com", nil},
+		{"http://a.example.com", nil},
+		{"http://a.example.com", ErrRateLimited},
+		{"http://b.example.com", nil},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		_, err = rt.RoundTrip(req)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("Expected error %v for %s, got %v", test.wantErr, test.url, err)
+		}
+	}
+}
+
+func TestRateLimitInterceptorWaitRespectsContext(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := RateLimit(rate.Every(time.Hour), 1)(mockRT)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Expected first request to be sent, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rt.RoundTrip(req.WithContext(ctx)); err == nil {
+		t.Errorf("Expected canceled request to fail while waiting for a token")
+	}
+}
+
+func TestRateLimitInterceptorWaits(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	rt := RateLimit(rate.Every(20*time.Millisecond), 1)(mockRT)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected requests to be paced, took %v", elapsed)
+	}
+}
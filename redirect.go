@@ -0,0 +1,159 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrTooManyRedirects is returned by the FollowRedirects interceptor when a
+// request is redirected more times than allowed.
+var ErrTooManyRedirects = errors.New("interceptor: too many redirects")
+
+// RedirectPolicy controls how the FollowRedirects interceptor handles
+// redirects.
+type RedirectPolicy struct {
+	// Check, if set, is called before following each redirect with the
+	// upcoming request and the requests made so far, oldest first. If it
+	// returns http.ErrUseLastResponse, the redirect response is returned to the
+	// caller unchanged, and any other error is returned in its place.
+	Check func(req *http.Request, via []*http.Request) error
+	// StripAuthCrossOrigin removes the Authorization, Proxy-Authorization, and
+	// Cookie headers from redirects to another scheme, host, or port, so that
+	// credentials meant for one server are not sent to another.
+	StripAuthCrossOrigin bool
+}
+
+// FollowRedirects returns an Interceptor that follows 3xx responses carrying a
+// Location header, for Pipelines used directly as an http.RoundTripper without
+// the redirect handling of http.Client. Each redirected request is sent
+// through the interceptors after this one, so they apply to every hop.
+//
+// As with http.Client, 301, 302, and 303 responses are followed with a GET
+// request without a body, except that HEAD requests remain HEAD, while 307 and
+// 308 responses repeat the original method and body. A 307 or 308 redirect of
+// a request whose body cannot be read again through req.GetBody is not
+// followed. At most max redirects are followed, after which the request fails
+// with ErrTooManyRedirects.
+func FollowRedirects(max int, policy RedirectPolicy) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			via := []*http.Request{req}
+			current := req
+			for {
+				resp, err := next.RoundTrip(current)
+				if err != nil {
+					return nil, err
+				}
+				redirect, err := redirectRequest(req, current, resp, policy)
+				if err != nil {
+					discard(resp)
+					return nil, newError("FollowRedirects", req, err)
+				}
+				if redirect == nil {
+					return resp, nil
+				}
+
+				if len(via) > max {
+					discard(resp)
+					return nil, newError("FollowRedirects", req, ErrTooManyRedirects)
+				}
+				if policy.Check != nil {
+					if err := policy.Check(redirect, via); err != nil {
+						if errors.Is(err, http.ErrUseLastResponse) {
+							return resp, nil
+						}
+						discard(resp)
+						return nil, newError("FollowRedirects", req, err)
+					}
+				}
+				discard(resp)
+				via = append(via, redirect)
+				current = redirect
+			}
+		})
+	}
+}
+
+// redirectRequest returns the request that follows resp, a response to
+// current, or nil if resp is not a redirect that can be followed. original is
+// the request the caller made.
+func redirectRequest(original, current *http.Request, resp *http.Response, policy RedirectPolicy) (*http.Request, error) {
+	var keepMethod bool
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther:
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		keepMethod = true
+		if current.Body != nil && current.Body != http.NoBody && original.GetBody == nil {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, nil
+	}
+	target, err := current.URL.Parse(location)
+	if err != nil {
+		return nil, nil
+	}
+
+	redirect := current.Clone(current.Context())
+	redirect.URL = target
+	redirect.Host = ""
+	if keepMethod {
+		if original.GetBody != nil {
+			if redirect.Body, err = original.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if redirect.Method != http.MethodHead {
+			redirect.Method = http.MethodGet
+		}
+		redirect.Body = nil
+		redirect.GetBody = nil
+		redirect.ContentLength = 0
+		redirect.Header.Del("Content-Type")
+		redirect.Header.Del("Content-Length")
+	}
+	if policy.StripAuthCrossOrigin && !sameOrigin(current.URL, target) {
+		redirect.Header.Del("Authorization")
+		redirect.Header.Del("Proxy-Authorization")
+		redirect.Header.Del("Cookie")
+	}
+	return redirect, nil
+}
+
+// sameOrigin reports whether a and b have the same scheme, host, and port.
+// Schemes and hosts are compared case-insensitively, and a missing port
+// equals the default port of the scheme.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		originPort(a) == originPort(b)
+}
+
+// originPort returns the port of u, or the default port of its scheme.
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// discard reads the rest of resp's body and closes it, so that its connection
+// can be reused.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,170 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// redirectTransport redirects requests according to routes, a map from request
+// URL to status code and Location, and answers other requests with 200 OK. It
+// records every request it receives.
+func redirectTransport(routes map[string][2]string, seen *[]*http.Request) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*seen = append(*seen, req)
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		if route, ok := routes[req.URL.String()]; ok {
+			switch route[0] {
+			case "301":
+				resp.StatusCode = http.StatusMovedPermanently
+			case "307":
+				resp.StatusCode = http.StatusTemporaryRedirect
+			}
+			resp.Header.Set("Location", route[1])
+		}
+		return resp, nil
+	})
+}
+
+func TestFollowRedirectsInterceptor(t *testing.T) {
+	var seen []*http.Request
+	transport := redirectTransport(map[string][2]string{
+		"http://example.com/old": {"301", "/new"},
+		"http://example.com/new": {"307", "http://other.com/final"},
+	}, &seen)
+	rt := FollowRedirects(5, RedirectPolicy{StripAuthCrossOrigin: true})(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com/old", strings.NewReader("payload"))
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the final response, got %d", resp.StatusCode)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(seen))
+	}
+
+	if seen[1].Method != http.MethodGet || seen[1].URL.String() != "http://example.com/new" {
+		t.Errorf("Expected a 301 to be followed with GET /new, got %s %s", seen[1].Method, seen[1].URL)
+	}
+	if seen[1].Header.Get("Authorization") == "" {
+		t.Errorf("Expected Authorization to be kept on a same-origin redirect")
+	}
+	if seen[2].Method != http.MethodGet || seen[2].URL.String() != "http://other.com/final" {
+		t.Errorf("Expected a 307 to keep the method, got %s %s", seen[2].Method, seen[2].URL)
+	}
+	if seen[2].Header.Get("Authorization") != "" {
+		t.Errorf("Expected Authorization to be stripped on a cross-origin redirect")
+	}
+}
+
+func TestFollowRedirectsInterceptorKeepsBody(t *testing.T) {
+	var seen []*http.Request
+	var bodies []string
+	transport := redirectTransport(map[string][2]string{
+		"http://example.com/old": {"307", "/new"},
+	}, &seen)
+	rt := FollowRedirects(5, RedirectPolicy{})(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		return transport.RoundTrip(req)
+	}))
+
+	req, _ := http.NewRequest("PUT", "http://example.com/old", strings.NewReader("payload"))
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Errorf("Expected the body to be resent after a 307, got %q", bodies)
+	}
+	if seen[1].Method != http.MethodPut {
+		t.Errorf("Expected a 307 to keep the method, got %s", seen[1].Method)
+	}
+}
+
+func TestFollowRedirectsInterceptorLimits(t *testing.T) {
+	var seen []*http.Request
+	transport := redirectTransport(map[string][2]string{
+		"http://example.com/a": {"301", "/b"},
+		"http://example.com/b": {"301", "/a"},
+	}, &seen)
+
+	req, _ := http.NewRequest("GET", "http://example.com/a", nil)
+	if _, err := FollowRedirects(3, RedirectPolicy{})(transport).RoundTrip(req); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Expected ErrTooManyRedirects, got %v", err)
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 requests, got %d", len(seen))
+	}
+
+	stop := RedirectPolicy{Check: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := FollowRedirects(3, stop)(transport).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("Expected the redirect response to be returned, got %d", resp.StatusCode)
+	}
+}
+
+// closeRecorder is a response body that records whether it was closed.
+type closeRecorder struct {
+	io.ReadCloser
+	closed *bool
+}
+
+func (b *closeRecorder) Close() error {
+	*b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func TestFollowRedirectsInterceptorGetBodyError(t *testing.T) {
+	failure := errors.New("body gone")
+	closed := false
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTemporaryRedirect,
+			Header:     http.Header{"Location": {"/new"}},
+			Body:       &closeRecorder{ReadCloser: http.NoBody, closed: &closed},
+		}, nil
+	})
+	rt := FollowRedirects(5, RedirectPolicy{})(transport)
+
+	req, _ := http.NewRequest("PUT", "http://example.com/old", strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, failure }
+	resp, err := rt.RoundTrip(req)
+	if resp != nil || !errors.Is(err, failure) {
+		t.Errorf("Expected only the GetBody error, got %v, %v", resp, err)
+	}
+	if !closed {
+		t.Errorf("Expected the redirect response body to be closed")
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"https://Example.com:443/a", "https://example.com/b", true},
+		{"http://example.com/a", "HTTP://EXAMPLE.COM:80/b", true},
+		{"https://example.com/a", "https://example.com:8443/b", false},
+		{"https://example.com/a", "http://example.com/b", false},
+		{"https://example.com/a", "https://other.com/b", false},
+	}
+	for _, test := range tests {
+		a, _ := url.Parse(test.a)
+		b, _ := url.Parse(test.b)
+		if got := sameOrigin(a, b); got != test.expected {
+			t.Errorf("Expected sameOrigin(%s, %s) to be %v, got %v", test.a, test.b, test.expected, got)
+		}
+	}
+}
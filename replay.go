@@ -0,0 +1,377 @@
+package interceptor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrInteractionNotFound is returned by the Replay interceptor when a request
+// has no matching interaction in the cassette and the mode does not allow it to
+// be sent to the network.
+var ErrInteractionNotFound = errors.New("interceptor: no matching interaction in cassette")
+
+// CassetteMode controls how the Replay interceptor uses its cassette.
+type CassetteMode int
+
+const (
+	// CassetteReplay answers requests only from the cassette.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends every request to the network and records it.
+	CassetteRecord
+	// CassetteHybrid answers requests from the cassette when possible and
+	// records the ones that are missing.
+	CassetteHybrid
+	// CassettePassthrough sends every request to the network without recording.
+	CassettePassthrough
+)
+
+// Cassette is a set of recorded request/response pairs that can be saved to and
+// loaded from a JSON file, or a file in another format such as YAML with
+// LoadCassetteWith and SaveWith. It is safe for concurrent use.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	used         map[int]bool
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// CassetteRequest is the recorded form of an http.Request.
+type CassetteRequest struct {
+	Method string       `json:"method" yaml:"method"`
+	URL    string       `json:"url" yaml:"url"`
+	Header http.Header  `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   CassetteBody `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// CassetteResponse is the recorded form of an http.Response.
+type CassetteResponse struct {
+	StatusCode int          `json:"status_code" yaml:"status_code"`
+	Header     http.Header  `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       CassetteBody `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// CassetteBody is a recorded message body. It is stored as a plain string when
+// it is valid UTF-8 and base64 encoded otherwise, so cassettes stay readable.
+type CassetteBody []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b CassetteBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *CassetteBody) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = CassetteBody(s)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = decoded
+	return err
+}
+
+// MarshalYAML implements the Marshaler interface of YAML libraries such as
+// gopkg.in/yaml.v3, using the same plain or base64 form as MarshalJSON.
+func (b CassetteBody) MarshalYAML() (any, error) {
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+	return map[string]string{"base64": base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of YAML libraries such as
+// gopkg.in/yaml.v2, which gopkg.in/yaml.v3 also supports.
+func (b *CassetteBody) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*b = CassetteBody(s)
+		return nil
+	}
+	var encoded map[string]string
+	if err := unmarshal(&encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded["base64"])
+	*b = decoded
+	return err
+}
+
+// NewCassette returns an empty Cassette that is saved to path.
+func NewCassette(path string) *Cassette {
+	return &Cassette{path: path}
+}
+
+// LoadCassette reads the Cassette stored at path. If the file does not exist an
+// empty Cassette is returned, so that it can be recorded and saved.
+func LoadCassette(path string) (*Cassette, error) {
+	return LoadCassetteWith(path, json.Unmarshal)
+}
+
+// LoadCassetteWith is like LoadCassette but decodes the file with unmarshal,
+// such as yaml.Unmarshal from gopkg.in/yaml.v3.
+func LoadCassetteWith(path string, unmarshal func(data []byte, v any) error) (*Cassette, error) {
+	c := NewCassette(path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("interceptor: invalid cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the Cassette to the path it was created or loaded with.
+func (c *Cassette) Save() error {
+	return c.SaveWith(func(v any) ([]byte, error) {
+		return json.MarshalIndent(v, "", "  ")
+	})
+}
+
+// SaveWith is like Save but encodes the Cassette with marshal, such as
+// yaml.Marshal from gopkg.in/yaml.v3.
+func (c *Cassette) SaveWith(marshal func(v any) ([]byte, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// find returns the response of the first interaction matching req that has not
+// been replayed yet, or of the first matching interaction if all of them have.
+func (c *Cassette) find(req CassetteRequest, match CassetteMatcher) (CassetteResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first := -1
+	for i, interaction := range c.Interactions {
+		if !match(interaction.Request, req) {
+			continue
+		}
+		if !c.used[i] {
+			if c.used == nil {
+				c.used = make(map[int]bool)
+			}
+			c.used[i] = true
+			return interaction.Response, true
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return CassetteResponse{}, false
+	}
+	return c.Interactions[first].Response, true
+}
+
+func (c *Cassette) add(interaction Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// CassetteMatcher reports whether a recorded request matches an incoming one.
+type CassetteMatcher func(recorded, req CassetteRequest) bool
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(recorded, req CassetteRequest) bool {
+	return recorded.Method == req.Method
+}
+
+// MatchURL matches requests with the same URL, including the query.
+func MatchURL(recorded, req CassetteRequest) bool {
+	return recorded.URL == req.URL
+}
+
+// MatchBody matches requests with identical bodies.
+func MatchBody(recorded, req CassetteRequest) bool {
+	return bytes.Equal(recorded.Body, req.Body)
+}
+
+// MatchHeaders returns a CassetteMatcher that matches requests with the same
+// values for each of the named headers.
+func MatchHeaders(names ...string) CassetteMatcher {
+	return func(recorded, req CassetteRequest) bool {
+		for _, name := range names {
+			if recorded.Header.Get(name) != req.Header.Get(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ReplayOption configures the Replay interceptor.
+type ReplayOption func(*replayConfig)
+
+type replayConfig struct {
+	matchers []CassetteMatcher
+	filter   func(*Interaction)
+}
+
+// ReplayMatchers sets the matchers a recorded request must satisfy to be
+// replayed for an incoming request. The default is MatchMethod and MatchURL.
+func ReplayMatchers(matchers ...CassetteMatcher) ReplayOption {
+	return func(c *replayConfig) {
+		c.matchers = matchers
+	}
+}
+
+// ReplayFilter sets a function that is called on every interaction before it
+// is recorded, for example to strip credentials from headers.
+func ReplayFilter(f func(*Interaction)) ReplayOption {
+	return func(c *replayConfig) {
+		c.filter = f
+	}
+}
+
+// Replay returns an Interceptor that records requests and their responses to
+// cassette, or answers requests from it, depending on mode. Recorded
+// interactions are kept in memory until cassette.Save is called.
+//
+// Replay is meant for tests: record real traffic once, commit the cassette
+// file, and replay it deterministically afterwards.
+//
+// Streaming requests and responses, as reported by IsStreamingRequest and
+// IsStreamingResponse, are passed through without being recorded, since their
+// bodies may never end.
+func Replay(cassette *Cassette, mode CassetteMode, opts ...ReplayOption) Interceptor {
+	cfg := replayConfig{matchers: []CassetteMatcher{MatchMethod, MatchURL}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	match := func(recorded, req CassetteRequest) bool {
+		for _, m := range cfg.matchers {
+			if !m(recorded, req) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			if mode == CassettePassthrough {
+				return next.RoundTrip(req)
+			}
+
+			recordedReq, sent, err := recordRequest(req)
+			if err != nil {
+				return nil, newError("Replay", req, err)
+			}
+
+			if mode == CassetteReplay || mode == CassetteHybrid {
+				if recordedResp, ok := cassette.find(recordedReq, match); ok {
+					return replayResponse(sent, recordedResp), nil
+				}
+				if mode == CassetteReplay {
+					return nil, newError("Replay", req, ErrInteractionNotFound)
+				}
+			}
+
+			resp, err := next.RoundTrip(sent)
+			if err != nil {
+				return nil, err
+			}
+			if IsStreamingRequest(sent) || IsStreamingResponse(resp) {
+				return resp, nil
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, newError("Replay", req, err)
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+
+			interaction := Interaction{
+				Request: recordedReq,
+				Response: CassetteResponse{
+					StatusCode: resp.StatusCode,
+					Header:     resp.Header.Clone(),
+					Body:       body,
+				},
+			}
+			if cfg.filter != nil {
+				cfg.filter(&interaction)
+			}
+			cassette.add(interaction)
+			return resp, nil
+		})
+	}
+}
+
+// recordRequest captures req. If req has a body, it is read and a copy of req
+// carrying an unread copy of the body is returned to be sent in its place.
+func recordRequest(req *http.Request) (CassetteRequest, *http.Request, error) {
+	recorded := CassetteRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return CassetteRequest{}, nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		recorded.Body = body
+	}
+	return recorded, req, nil
+}
+
+// replayResponse builds an http.Response for req from a recorded response.
+func replayResponse(req *http.Request, recorded CassetteResponse) *http.Response {
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
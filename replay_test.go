@@ -0,0 +1,261 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReplayInterceptorRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	calls := 0
+	network := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		body, _ := io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("echo:" + string(body))),
+		}, nil
+	})
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	record := Replay(cassette, CassetteRecord, ReplayFilter(func(i *Interaction) {
+		i.Request.Header.Del("Authorization")
+	}))(network)
+
+	req, _ := http.NewRequest("POST", "http://example.com/echo", strings.NewReader("hello"))
+	req.Header.Set("Authorization", "secret")
+	resp, err := record.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "echo:hello" {
+		t.Errorf("Expected recorded response to be returned, got '%s'", body)
+	}
+	if err := cassette.Save(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	if len(loaded.Interactions) != 1 {
+		t.Fatalf("Expected 1 interaction, got %d", len(loaded.Interactions))
+	}
+	if loaded.Interactions[0].Request.Header.Get("Authorization") != "" {
+		t.Errorf("Expected filter to remove the Authorization header")
+	}
+
+	replay := Replay(loaded, CassetteReplay, ReplayMatchers(MatchMethod, MatchURL, MatchBody))(network)
+	req, _ = http.NewRequest("POST", "http://example.com/echo", strings.NewReader("hello"))
+	resp, err = replay.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to replay request: %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "echo:hello" {
+		t.Errorf("Expected replayed body 'echo:hello', got '%s'", body)
+	}
+	if resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected replayed headers")
+	}
+	if calls != 1 {
+		t.Errorf("Expected replay not to reach the network, got %d calls", calls)
+	}
+
+	req, _ = http.NewRequest("POST", "http://example.com/echo", strings.NewReader("other"))
+	if _, err := replay.RoundTrip(req); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("Expected ErrInteractionNotFound for unmatched body, got %v", err)
+	}
+}
+
+func TestCassetteWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	cassette := NewCassette(path)
+	cassette.Interactions = []Interaction{{
+		Request:  CassetteRequest{Method: "POST", URL: "http://example.com", Body: CassetteBody{0xff, 0x00}},
+		Response: CassetteResponse{StatusCode: http.StatusOK, Body: CassetteBody("ok")},
+	}}
+
+	// A YAML library encodes the values returned by MarshalYAML, and decodes
+	// them again by calling UnmarshalYAML with a function for each node.
+	var nodes []any
+	marshal := func(v any) ([]byte, error) {
+		for _, interaction := range v.(*Cassette).Interactions {
+			for _, body := range []CassetteBody{interaction.Request.Body, interaction.Response.Body} {
+				node, err := body.MarshalYAML()
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, node)
+			}
+		}
+		return []byte("interactions: [...]"), nil
+	}
+	decode := func(node any) func(any) error {
+		return func(v any) error {
+			switch v := v.(type) {
+			case *string:
+				s, ok := node.(string)
+				if !ok {
+					return errors.New("not a string")
+				}
+				*v = s
+			case *map[string]string:
+				m, ok := node.(map[string]string)
+				if !ok {
+					return errors.New("not a mapping")
+				}
+				*v = m
+			}
+			return nil
+		}
+	}
+	unmarshal := func(data []byte, v any) error {
+		if string(data) != "interactions: [...]" {
+			return errors.New("unexpected document")
+		}
+		var interaction Interaction
+		if err := interaction.Request.Body.UnmarshalYAML(decode(nodes[0])); err != nil {
+			return err
+		}
+		if err := interaction.Response.Body.UnmarshalYAML(decode(nodes[1])); err != nil {
+			return err
+		}
+		v.(*Cassette).Interactions = []Interaction{interaction}
+		return nil
+	}
+
+	if err := cassette.SaveWith(marshal); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+	if m, ok := nodes[0].(map[string]string); !ok || m["base64"] != "/wA=" {
+		t.Errorf("Expected binary bodies to be base64 encoded, got %v", nodes[0])
+	}
+	if nodes[1] != "ok" {
+		t.Errorf("Expected text bodies to be plain strings, got %v", nodes[1])
+	}
+
+	loaded, err := LoadCassetteWith(path, unmarshal)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	if body := loaded.Interactions[0].Request.Body; string(body) != "\xff\x00" {
+		t.Errorf("Expected the binary body to round-trip, got %q", body)
+	}
+	if body := loaded.Interactions[0].Response.Body; string(body) != "ok" {
+		t.Errorf("Expected the text body to round-trip, got %q", body)
+	}
+}
+
+func TestReplayInterceptorModes(t *testing.T) {
+	calls := 0
+	network := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("live"))}, nil
+	})
+	cassette := NewCassette("")
+	cassette.Interactions = []Interaction{{
+		Request:  CassetteRequest{Method: "GET", URL: "http://example.com/known"},
+		Response: CassetteResponse{StatusCode: http.StatusAccepted, Body: CassetteBody("recorded")},
+	}}
+
+	tests := []struct {
+		mode         CassetteMode
+		url          string
+		expectedBody string
+		expectedLive bool
+	}{
+		{CassetteHybrid, "http://example.com/known", "recorded", false},
+		{CassetteHybrid, "http://example.com/unknown", "live", true},
+		{CassetteHybrid, "http://example.com/unknown", "live", false},
+		{CassettePassthrough, "http://example.com/known", "live", true},
+	}
+
+	for _, test := range tests {
+		before := calls
+		rt := Replay(cassette, test.mode)(network)
+		req, _ := http.NewRequest("GET", test.url, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != test.expectedBody {
+			t.Errorf("Expected body '%s' for %s, got '%s'", test.expectedBody, test.url, body)
+		}
+		if live := calls > before; live != test.expectedLive {
+			t.Errorf("Expected network use to be %v for %s", test.expectedLive, test.url)
+		}
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Errorf("Expected hybrid mode to record the missing interaction, got %d", len(cassette.Interactions))
+	}
+}
+
+func TestReplayInterceptorBodyError(t *testing.T) {
+	failure := errors.New("read failed")
+	rt := Replay(NewCassette(""), CassetteRecord)(&mockRoundTripper{})
+
+	req, _ := http.NewRequest("POST", "http://example.com/users", io.NopCloser(iotest.ErrReader(failure)))
+	_, err := rt.RoundTrip(req)
+	var ierr *Error
+	if !errors.As(err, &ierr) || !errors.Is(err, failure) {
+		t.Fatalf("Expected an *Error wrapping the read error, got %v", err)
+	}
+	if ierr.Method != "POST" || ierr.URL != "http://example.com/users" {
+		t.Errorf("Expected the original request to be recorded, got %+v", ierr)
+	}
+}
+
+func TestReplayInterceptorStreaming(t *testing.T) {
+	stream := io.NopCloser(strings.NewReader("data: ping\n\n"))
+	network := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Content-Type": {"text/event-stream"}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: stream}, nil
+	})
+	cassette := NewCassette("")
+
+	for _, mode := range []CassetteMode{CassetteRecord, CassetteHybrid} {
+		rt := Replay(cassette, mode)(network)
+		req, _ := http.NewRequest("GET", "http://example.com/events", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if resp.Body != stream {
+			t.Errorf("Expected mode %d to pass the stream through unread", mode)
+		}
+	}
+	if len(cassette.Interactions) != 0 {
+		t.Errorf("Expected streams not to be recorded, got %d interactions", len(cassette.Interactions))
+	}
+}
+
+func TestCassetteBodyEncoding(t *testing.T) {
+	tests := []CassetteBody{
+		CassetteBody("plain text"),
+		CassetteBody{0xff, 0x00, 0xfe},
+	}
+
+	for _, body := range tests {
+		data, err := body.MarshalJSON()
+		if err != nil {
+			t.Fatalf("Failed to marshal body: %v", err)
+		}
+		var decoded CassetteBody
+		if err := decoded.UnmarshalJSON(data); err != nil {
+			t.Fatalf("Failed to unmarshal body %s: %v", data, err)
+		}
+		if string(decoded) != string(body) {
+			t.Errorf("Expected body %q to round-trip, got %q", body, decoded)
+		}
+	}
+}
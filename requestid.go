@@ -0,0 +1,101 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header used by RequestID unless configured
+// otherwise.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying id. Requests made with the
+// returned context are sent by RequestID with this ID instead of a generated
+// one.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by ctx, if any. Interceptors
+// placed after RequestID can use it on req.Context() to correlate their output
+// with the ID that was sent.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestIDOption configures the RequestID interceptor.
+type RequestIDOption func(*requestIDConfig)
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+}
+
+// RequestIDHeader sets the header the request ID is sent in. The default is
+// DefaultRequestIDHeader.
+func RequestIDHeader(name string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		if name != "" {
+			c.header = name
+		}
+	}
+}
+
+// RequestIDGenerator sets the function used to create new request IDs. The
+// default generates random (version 4) UUIDs.
+func RequestIDGenerator(f func() string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		if f != nil {
+			c.generate = f
+		}
+	}
+}
+
+// RequestID returns an Interceptor that tags every request with a correlation
+// ID. The ID is taken from the request context if it was set with
+// WithRequestID, then from the request's header if the caller already set it,
+// and is generated otherwise.
+//
+// The ID is sent in the configured header and stored in the context of the
+// request passed down the chain, where RequestIDFrom can read it.
+func RequestID(opts ...RequestIDOption) Interceptor {
+	cfg := requestIDConfig{
+		header:   DefaultRequestIDHeader,
+		generate: NewUUID,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			id, ok := RequestIDFrom(req.Context())
+			if !ok {
+				id = req.Header.Get(cfg.header)
+			}
+			if id == "" {
+				id = cfg.generate()
+			}
+
+			req = req.Clone(WithRequestID(req.Context(), id))
+			req.Header.Set(cfg.header, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// NewUUID returns a random (version 4) UUID in its canonical string form.
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("interceptor: failed to read random bytes: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
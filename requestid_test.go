@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDInterceptor(t *testing.T) {
+	var ctxID string
+	mockRT := &mockRoundTripper{
+		Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+	}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctxID, _ = RequestIDFrom(req.Context())
+		return mockRT.RoundTrip(req)
+	})
+
+	tests := []struct {
+		name     string
+		opts     []RequestIDOption
+		ctxID    string
+		headerID string
+		header   string
+		expected string
+	}{
+		{"generated", nil, "", "", DefaultRequestIDHeader, ""},
+		{"from context", nil, "ctx-id", "", DefaultRequestIDHeader, "ctx-id"},
+		{"from header", nil, "", "header-id", DefaultRequestIDHeader, "header-id"},
+		{"context wins", nil, "ctx-id", "header-id", DefaultRequestIDHeader, "ctx-id"},
+		{"custom header", []RequestIDOption{RequestIDHeader("X-Correlation-ID")}, "", "", "X-Correlation-ID", ""},
+		{"custom generator", []RequestIDOption{RequestIDGenerator(func() string { return "fixed" })}, "", "", DefaultRequestIDHeader, "fixed"},
+	}
+
+	for _, test := range tests {
+		rt := RequestID(test.opts...)(transport)
+		ctx := context.Background()
+		if test.ctxID != "" {
+			ctx = WithRequestID(ctx, test.ctxID)
+		}
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+		if test.headerID != "" {
+			req.Header.Set(test.header, test.headerID)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+
+		sent := mockRT.Request.Header.Get(test.header)
+		if test.expected == "" {
+			if !uuidPattern.MatchString(sent) {
+				t.Errorf("%s: expected a generated UUID, got '%s'", test.name, sent)
+			}
+		} else if sent != test.expected {
+			t.Errorf("%s: expected ID '%s', got '%s'", test.name, test.expected, sent)
+		}
+		if ctxID != sent {
+			t.Errorf("%s: expected context ID '%s' to match header ID '%s'", test.name, ctxID, sent)
+		}
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewUUID()
+		if !uuidPattern.MatchString(id) {
+			t.Fatalf("Expected a version 4 UUID, got '%s'", id)
+		}
+		if seen[id] {
+			t.Fatalf("Expected unique UUIDs, got '%s' twice", id)
+		}
+		seen[id] = true
+	}
+}
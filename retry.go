@@ -0,0 +1,156 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures the Retry interceptor.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent at most, including
+	// the first. If it is less than 2, requests are not retried.
+	MaxAttempts int
+	// Backoff is the wait before the first retry. It doubles with each retry up
+	// to MaxBackoff, and a random portion of it is used to spread retries out.
+	// The defaults are 100 milliseconds and 10 seconds.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Budget limits retries to this fraction of requests, such as 0.1 for one
+	// retry per ten requests, so that retries cannot multiply the load on a
+	// struggling server. A reserve of ten retries is available before the
+	// fraction applies. If zero, retries are not limited.
+	Budget float64
+	// RetryIf reports whether a failed attempt should be retried. By default
+	// requests for which IsIdempotent reports true are retried after transport
+	// errors and 429, 502, 503, and 504 responses, but not when the error comes
+	// from the request's own context, an open circuit, or a rate limit.
+	RetryIf func(req *http.Request, resp *http.Response, err error) bool
+}
+
+// defaultRetryIf is the default RetryPolicy.RetryIf.
+func defaultRetryIf(req *http.Request, resp *http.Response, err error) bool {
+	if !IsIdempotent(req) {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCircuitOpen) &&
+			!errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrConcurrencyLimited)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retry returns an Interceptor that sends failed requests again according to
+// p, waiting between attempts with exponential backoff, or for as long as a
+// Retry-After header on a 429 or 503 response asks if that is longer. Waiting
+// respects the request context, and no attempt is made once it is done.
+//
+// Requests with a body are only retried if req.GetBody is set. The response
+// of the last attempt, or its error, is returned to the caller. Place Timeout
+// before Retry to bound all attempts together, and PerTryTimeout after it to
+// bound each attempt; WithPolicy composes them in this order.
+func Retry(p RetryPolicy) Interceptor {
+	if p.Backoff <= 0 {
+		p.Backoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.RetryIf == nil {
+		p.RetryIf = defaultRetryIf
+	}
+	budget := newRetryBudget(p.Budget)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			budget.deposit()
+			retryable := p.MaxAttempts > 1 && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+			attempt := req
+			for n := 1; ; n++ {
+				resp, err := next.RoundTrip(attempt)
+				if !retryable || n >= p.MaxAttempts || req.Context().Err() != nil ||
+					!p.RetryIf(req, resp, err) || !budget.withdraw() {
+					return resp, err
+				}
+
+				wait := backoff(p.Backoff, p.MaxBackoff, n)
+				if err == nil {
+					if until, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+						wait = max(wait, time.Until(until))
+					}
+					discard(resp)
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, newError("Retry", req, req.Context().Err())
+				}
+
+				attempt = req.Clone(req.Context())
+				if req.GetBody != nil {
+					if attempt.Body, err = req.GetBody(); err != nil {
+						return nil, newError("Retry", req, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// backoff returns the wait before retry n, starting at 1: a random duration
+// between half and all of base doubled n-1 times, capped at limit.
+func backoff(base, limit time.Duration, n int) time.Duration {
+	d := base
+	for i := 1; i < n && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryBudget limits retries to a fraction of requests with a token bucket
+// that earns ratio tokens per request, and spends one per retry.
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+// retryBudgetReserve is the number of retries a budget holds at most.
+const retryBudgetReserve = 10
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio, tokens: retryBudgetReserve}
+}
+
+func (b *retryBudget) deposit() {
+	if b.ratio <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, retryBudgetReserve)
+}
+
+func (b *retryBudget) withdraw() bool {
+	if b.ratio <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
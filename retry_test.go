@@ -0,0 +1,89 @@
+package interceptor
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusSequence answers each attempt with the next status in statuses and
+// records the bodies it receives.
+func statusSequence(statuses []int, bodies *[]string) http.RoundTripper {
+	n := 0
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(data))
+		}
+		status := statuses[min(n, len(statuses)-1)]
+		n++
+		return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody}, nil
+	})
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	var bodies []string
+	transport := statusSequence([]int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, &bodies)
+	rt := Retry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})(transport)
+
+	req, _ := http.NewRequest("PUT", "http://example.com", strings.NewReader("payload"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request to succeed on the third attempt, got %d", resp.StatusCode)
+	}
+	if len(bodies) != 3 || bodies[2] != "payload" {
+		t.Errorf("Expected the body to be sent with every attempt, got %q", bodies)
+	}
+}
+
+func TestRetryInterceptorSkipsRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		policy   RetryPolicy
+		expected int
+	}{
+		{"not idempotent", "POST", RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}, 1},
+		{"attempts exhausted", "GET", RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}, 2},
+		{"custom condition", "POST", RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond,
+			RetryIf: func(*http.Request, *http.Response, error) bool { return true }}, 3},
+	}
+
+	for _, test := range tests {
+		var bodies []string
+		transport := statusSequence([]int{http.StatusServiceUnavailable}, &bodies)
+		req, _ := http.NewRequest(test.method, "http://example.com", http.NoBody)
+		resp, err := Retry(test.policy)(transport).RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected the last response, got %d", test.name, resp.StatusCode)
+		}
+		if len(bodies) != test.expected {
+			t.Errorf("%s: expected %d attempts, got %d", test.name, test.expected, len(bodies))
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(0.5)
+	for range retryBudgetReserve {
+		if !b.withdraw() {
+			t.Fatalf("Expected the reserve to allow %d retries", retryBudgetReserve)
+		}
+	}
+	if b.withdraw() {
+		t.Errorf("Expected the budget to be exhausted")
+	}
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Errorf("Expected two requests to earn one retry")
+	}
+}
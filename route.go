@@ -0,0 +1,52 @@
+package interceptor
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Route returns an Interceptor that runs interceptors, in order, only for
+// requests satisfying matcher. Other requests bypass them entirely. This lets a
+// single Pipeline serve several APIs with their own base URLs, credentials, or
+// retry policies:
+//
+//	pipeline.Use(
+//		interceptor.Route(interceptor.HostGlob("*.identity.example.com"),
+//			interceptor.OAuth2(amTokens),
+//		),
+//		interceptor.Route(interceptor.PathPrefix("/openidm"),
+//			interceptor.Header("X-Requested-With", "interceptor"),
+//		),
+//	)
+//
+// Matchers see the request as it arrives at the Route, so a Route that matches
+// on host should be placed after any BaseURL interceptor that fills the host in.
+func Route(matcher Matcher, interceptors ...Interceptor) Interceptor {
+	return When(matcher, Chain(interceptors...))
+}
+
+// HostGlob returns a Matcher that matches requests whose host name, without
+// the port, matches pattern using path.Match syntax. For example
+// "*.example.com" matches "api.example.com". Matching is case-insensitive.
+func HostGlob(pattern string) Matcher {
+	pattern = strings.ToLower(pattern)
+	return func(req *http.Request) bool {
+		ok, _ := path.Match(pattern, strings.ToLower(req.URL.Hostname()))
+		return ok
+	}
+}
+
+// PathPrefix returns a Matcher that matches requests whose URL path is prefix
+// or lies below it. Matching respects path segments, so "/api" matches "/api"
+// and "/api/users" but not "/apiary".
+func PathPrefix(prefix string) Matcher {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(req *http.Request) bool {
+		p := req.URL.Path
+		if !strings.HasPrefix(p, prefix) {
+			return false
+		}
+		return len(p) == len(prefix) || p[len(prefix)] == '/'
+	}
+}
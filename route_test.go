@@ -0,0 +1,74 @@
+package interceptor
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouteInterceptor(t *testing.T) {
+	var got *http.Request
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := New(transport,
+		Route(HostGlob("*.identity.example.com"),
+			Header("Authorization", "Bearer am"),
+			Header("X-Realm", "alpha"),
+		),
+		Route(PathPrefix("/openidm"),
+			Header("Authorization", "Bearer idm"),
+		),
+	)
+
+	tests := []struct {
+		url           string
+		expectedAuth  string
+		expectedRealm string
+	}{
+		{"http://am.identity.example.com/oauth2", "Bearer am", "alpha"},
+		{"http://AM.identity.example.com:8443/oauth2", "Bearer am", "alpha"},
+		{"http://other.example.com/openidm/query", "Bearer idm", ""},
+		{"http://other.example.com/openidm", "Bearer idm", ""},
+		{"http://other.example.com/openidmx", "", ""},
+		{"http://am.identity.example.com/openidm", "Bearer idm", "alpha"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		if auth := got.Header.Get("Authorization"); auth != test.expectedAuth {
+			t.Errorf("%s: expected Authorization '%s', got '%s'", test.url, test.expectedAuth, auth)
+		}
+		if realm := got.Header.Get("X-Realm"); realm != test.expectedRealm {
+			t.Errorf("%s: expected X-Realm '%s', got '%s'", test.url, test.expectedRealm, realm)
+		}
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		expected bool
+	}{
+		{"/api", "/api", true},
+		{"/api", "/api/users", true},
+		{"/api/", "/api/users", true},
+		{"/api", "/apiary", false},
+		{"/api", "/", false},
+		{"/", "/anything", true},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "http://example.com"+test.path, nil)
+		if got := PathPrefix(test.prefix)(req); got != test.expected {
+			t.Errorf("Expected PathPrefix(%q) on %q to be %v, got %v", test.prefix, test.path, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// ErrShutdown is returned by Pipeline.RoundTrip for requests made after
+// Pipeline.Shutdown has been called.
+var ErrShutdown = errors.New("interceptor: pipeline is shut down")
+
+// Shutdown gracefully shuts down the Pipeline. New requests immediately fail
+// with ErrShutdown, while requests already in flight are allowed to complete.
+// A request is in flight until RoundTrip returns; reading the response body
+// is not waited for.
+//
+// Once every in-flight request has completed, Shutdown closes the transports
+// that the Pipeline's interceptors returned when its chain was last built, if
+// they implement io.Closer, outermost first. This lets interceptors holding
+// resources release or flush them: Cache and Canary cancel the background
+// revalidations and shadow requests they started and wait for them to return,
+// Cache then closes its store, Metrics its recorder, and HAR flushes to the writer given with HARFlushOnClose, while
+// ClientCert, DialTarget, and TLSPin close the idle connections of their
+// transports. Chain, Route, When, and Unless close the interceptors they
+// wrap. It then closes the idle connections of the Transport if it is
+// set and has a CloseIdleConnections method, as *http.Transport does. The
+// errors returned by Close are joined and returned.
+//
+// If ctx is done before the in-flight requests complete, Shutdown returns the
+// context's error without closing anything; calling it again waits again. The
+// interceptors are closed at most once.
+func (t *Pipeline) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	t.shutdown = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.build()
+	closers, transport := t.closers, t.Transport
+	t.mu.Unlock()
+
+	err := closeAll(closers)
+	if idle, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		idle.CloseIdleConnections()
+	}
+	return err
+}
+
+// closingRoundTripper is an http.RoundTripper with a Close method, returned by
+// interceptors that hold resources so that Pipeline.Shutdown releases them.
+type closingRoundTripper struct {
+	http.RoundTripper
+	close func() error
+}
+
+func (c *closingRoundTripper) Close() error {
+	return c.close()
+}
+
+// withClose returns rt with a Close method that calls close.
+func withClose(rt http.RoundTripper, close func() error) http.RoundTripper {
+	return &closingRoundTripper{RoundTripper: rt, close: close}
+}
+
+// layerCloser returns wrapped, the transport returned by an interceptor for
+// next, if it implements io.Closer. An interceptor that has nothing to do may
+// return next itself, which is then not reported, so that it is not closed
+// twice.
+func layerCloser(wrapped, next http.RoundTripper) (io.Closer, bool) {
+	closer, ok := wrapped.(io.Closer)
+	if !ok {
+		return nil, false
+	}
+	// Comparing interfaces holding functions or other uncomparable values
+	// panics, and such values cannot be the same layer anyway.
+	if t := reflect.TypeOf(wrapped); t == reflect.TypeOf(next) && t.Comparable() && wrapped == next {
+		return nil, false
+	}
+	return closer, true
+}
+
+// closeAll closes closers in order and joins the errors they return.
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// background tracks goroutines that an interceptor runs after the request
+// that started them has returned, such as revalidations and shadow requests,
+// so that Pipeline.Shutdown can cancel them and wait for them to finish.
+type background struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func newBackground() *background {
+	b := &background{}
+	b.ctx, b.cancel = context.WithCancelCause(context.Background())
+	return b
+}
+
+// detach returns a context carrying the values of ctx that is not canceled
+// with it, but with ErrShutdown once Close is called, and a function that
+// cancels it with the given cause.
+func (b *background) detach(ctx context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(b.ctx, func() { cancel(context.Cause(b.ctx)) })
+	return ctx, func(cause error) {
+		stop()
+		cancel(cause)
+	}
+}
+
+// run calls f in a new goroutine that Close waits for.
+func (b *background) run(f func()) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		f()
+	}()
+}
+
+// Close cancels the contexts returned by detach and waits for the goroutines
+// started by run to return.
+func (b *background) Close() error {
+	b.cancel(ErrShutdown)
+	b.wg.Wait()
+	return nil
+}
@@ -0,0 +1,230 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closingTransport is a RoundTripper that records whether it was closed.
+type closingTransport struct {
+	next   http.RoundTripper
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c *closingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.next.RoundTrip(req)
+}
+
+func (c *closingTransport) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestPipelineShutdown(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		close(started)
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	var closed []string
+	closing := func(name string, err error) Interceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return &closingTransport{next: next, name: name, closed: &closed, err: err}
+		}
+	}
+	errFlush := errors.New("flush failed")
+	pipeline := New(transport, closing("outer", nil), passThrough)
+	pipeline.UseNamed("inner", closing("inner", errFlush))
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := pipeline.RoundTrip(req)
+		inflight <- err
+	}()
+	<-started
+
+	// The deadline passes while the request is still in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pipeline.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(closed) != 0 {
+		t.Errorf("Expected no interceptors to be closed before requests complete, got %v", closed)
+	}
+
+	if _, err := pipeline.RoundTrip(req); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected ErrShutdown for a new request, got %v", err)
+	}
+
+	close(release)
+	if err := pipeline.Shutdown(context.Background()); !errors.Is(err, errFlush) {
+		t.Errorf("Expected the Close error to be returned, got %v", err)
+	}
+	if err := <-inflight; err != nil {
+		t.Errorf("Expected the in-flight request to complete, got %v", err)
+	}
+	if len(closed) != 2 || closed[0] != "outer" || closed[1] != "inner" {
+		t.Errorf("Expected interceptors to be closed outermost first, got %v", closed)
+	}
+
+	if err := pipeline.Shutdown(context.Background()); err != nil || len(closed) != 2 {
+		t.Errorf("Expected a second Shutdown to close nothing, got %v and %v", err, closed)
+	}
+}
+
+func TestPipelineShutdownHooks(t *testing.T) {
+	pipeline := New(&mockRoundTripper{})
+	var hookErr error
+	pipeline.OnError(func(req *http.Request, err error) {
+		hookErr = err
+	})
+	if err := pipeline.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, err := pipeline.RoundTrip(req)
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Interceptor != "Pipeline" {
+		t.Errorf("Expected an *Error from Pipeline, got %v", err)
+	}
+	if !errors.Is(hookErr, ErrShutdown) {
+		t.Errorf("Expected the OnError hook to see ErrShutdown, got %v", hookErr)
+	}
+}
+
+// closingStore is a CacheStore that records whether it was closed.
+type closingStore struct {
+	*MemoryCacheStore
+	closed int
+}
+
+func (s *closingStore) Close() error {
+	s.closed++
+	return nil
+}
+
+// closingRecorder is a MetricsRecorder that records whether it was closed.
+type closingRecorder struct {
+	fakeRecorder
+	closed int
+}
+
+func (r *closingRecorder) Close() error {
+	r.closed++
+	return nil
+}
+
+func TestPipelineShutdownClosesBuiltins(t *testing.T) {
+	recorder := NewHARRecorder()
+	var har bytes.Buffer
+	store := &closingStore{MemoryCacheStore: NewMemoryCacheStore(10)}
+	metrics := &closingRecorder{}
+	// noop returns the next transport itself, which must not be closed twice.
+	noop := func(next http.RoundTripper) http.RoundTripper { return next }
+
+	pipeline := New(&mockRoundTripper{Response: &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}},
+		Route(HostIs("example.com"), HAR(recorder, HARFlushOnClose(&har))),
+		When(func(*http.Request) bool { return true }, Metrics(metrics)),
+		noop,
+		Chain(noop, Cache(store), noop),
+	)
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := pipeline.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if err := pipeline.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	if !strings.Contains(har.String(), `"url": "http://example.com"`) || recorder.Len() != 0 {
+		t.Errorf("Expected the HAR entries to be flushed, got %s", har.String())
+	}
+	if metrics.closed != 1 {
+		t.Errorf("Expected the metrics recorder to be closed once, got %d", metrics.closed)
+	}
+	if store.closed != 1 {
+		t.Errorf("Expected the cache store to be closed once, got %d", store.closed)
+	}
+}
+
+func TestPipelineShutdownCancelsBackgroundWork(t *testing.T) {
+	now := time.Now()
+	started := make(chan struct{}, 3)
+	var finished atomic.Int32
+	var causes [2]error
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		background := -1
+		switch {
+		case req.Header.Get("If-None-Match") != "":
+			background = 0
+		case req.URL.Host == "canary.example.com":
+			background = 1
+		}
+		if background >= 0 {
+			// Block like a slow server until the request is canceled.
+			started <- struct{}{}
+			<-req.Context().Done()
+			causes[background] = context.Cause(req.Context())
+			finished.Add(1)
+			return nil, req.Context().Err()
+		}
+		header := http.Header{"Cache-Control": {"max-age=10, stale-while-revalidate=60"}, "Etag": {`"v1"`}}
+		return cachedResponse(http.StatusOK, header, "v1"), nil
+	})
+	canaryBase, _ := url.Parse("http://canary.example.com")
+	diverged := 0
+	pipeline := New(transport,
+		newCache(NewMemoryCacheStore(10), func() time.Time { return now }).interceptor,
+		When(func(req *http.Request) bool { return req.URL.Path == "/shadowed" },
+			Canary(*canaryBase, 100, CanaryShadow(), CanaryLatencyTolerance(0), CanaryOnDivergence(func(CanaryResult) { diverged++ }))),
+	)
+
+	// The second request finds the cached response stale and revalidates it,
+	// and the third is shadowed.
+	for _, path := range []string{"/cached", "/cached", "/shadowed"} {
+		req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+		resp, err := pipeline.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		readBody(t, resp)
+		now = now.Add(30 * time.Second)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the revalidation and the shadow request to be sent")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pipeline.Shutdown(ctx); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	if finished.Load() != 2 {
+		t.Fatalf("Expected Shutdown to wait for the background requests, got %d finished", finished.Load())
+	}
+	if !errors.Is(causes[0], ErrShutdown) || !errors.Is(causes[1], ErrShutdown) {
+		t.Errorf("Expected the background requests to be canceled with ErrShutdown, got %v", causes)
+	}
+	if diverged != 0 {
+		t.Errorf("Expected canceled shadow requests not to be reported, got %d", diverged)
+	}
+}
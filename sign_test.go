@@ -0,0 +1,185 @@
+package interceptor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignInterceptorBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{"with GetBody", strings.NewReader("payload")},
+		{"without GetBody", io.NopCloser(strings.NewReader("payload"))},
+	}
+
+	for _, test := range tests {
+		var signed []byte
+		signer := RequestSignerFunc(func(req *http.Request, body []byte) error {
+			signed = body
+			req.Header.Set("X-Signature", "signed")
+			return nil
+		})
+		mockRT := &mockRoundTripper{
+			Response: &http.Response{StatusCode: http.StatusOK, Body: http.NoBody},
+		}
+		rt := Sign(signer)(mockRT)
+
+		req, _ := http.NewRequest("POST", "http://example.com", test.body)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("%s: failed to perform request: %v", test.name, err)
+		}
+
+		if string(signed) != "payload" {
+			t.Errorf("%s: expected signer to receive the body, got '%s'", test.name, signed)
+		}
+		sent, _ := io.ReadAll(mockRT.Request.Body)
+		if string(sent) != "payload" {
+			t.Errorf("%s: expected the full body to be sent, got '%s'", test.name, sent)
+		}
+		if mockRT.Request.Header.Get("X-Signature") != "signed" {
+			t.Errorf("%s: expected signature header to be sent", test.name)
+		}
+		if req.Header.Get("X-Signature") != "" {
+			t.Errorf("%s: expected original request to be left unmodified", test.name)
+		}
+	}
+}
+
+func TestSignInterceptorError(t *testing.T) {
+	signErr := errors.New("no credentials")
+	signer := RequestSignerFunc(func(*http.Request, []byte) error { return signErr })
+	mockRT := &mockRoundTripper{}
+	rt := Sign(signer)(mockRT)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, signErr) {
+		t.Errorf("Expected signing error, got %v", err)
+	}
+	if mockRT.Request != nil {
+		t.Errorf("Expected request not to be sent")
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer := &HMACSigner{
+		KeyID:         "key-1",
+		Secret:        []byte("secret"),
+		SignedHeaders: []string{"Content-Type"},
+		Now:           func() time.Time { return time.Unix(1700000000, 0) },
+	}
+	req, _ := http.NewRequest("PUT", "http://example.com/items/1?v=2", nil)
+	req.Header.Set("Content-Type", "application/json")
+	body := []byte(`{"a":1}`)
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+
+	bodyHash := sha256.Sum256(body)
+	canonical := "PUT\n/items/1?v=2\n1700000000\ncontent-type:application/json\n" + hex.EncodeToString(bodyHash[:])
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(canonical))
+	expected := "HMAC-SHA256 KeyId=key-1, SignedHeaders=content-type, Signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Expected Authorization to be '%s', got '%s'", expected, got)
+	}
+	if got := req.Header.Get("X-Signature-Timestamp"); got != "1700000000" {
+		t.Errorf("Expected timestamp header '1700000000', got '%s'", got)
+	}
+}
+
+// The expected signatures come from the AWS Signature Version 4 test suite.
+func TestAWSV4Signer(t *testing.T) {
+	signer := &AWSV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		Now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			"get-vanilla",
+			"https://example.amazonaws.com/",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			"get-vanilla-query-order-key-case",
+			"https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", test.url, nil)
+		if err := signer.Sign(req, nil); err != nil {
+			t.Fatalf("%s: failed to sign request: %v", test.name, err)
+		}
+		if got := req.Header.Get("Authorization"); got != test.expected {
+			t.Errorf("%s: expected Authorization\n%s\ngot\n%s", test.name, test.expected, got)
+		}
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: expected X-Amz-Date '20150830T123600Z', got '%s'", test.name, got)
+		}
+	}
+}
+
+func TestAWSV4SignerS3(t *testing.T) {
+	signer := &AWSV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Region:          "eu-west-1",
+		Service:         "s3",
+	}
+	body := []byte("object data")
+	req, _ := http.NewRequest("PUT", "https://bucket.s3.amazonaws.com/my%20key", bytes.NewReader(body))
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+
+	hash := sha256.Sum256(body)
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(hash[:]) {
+		t.Errorf("Expected payload hash header, got '%s'", got)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "token" {
+		t.Errorf("Expected session token header, got '%s'", got)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Expected S3 headers to be signed, got '%s'", auth)
+	}
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	tests := []struct {
+		input       string
+		encodeSlash bool
+		expected    string
+	}{
+		{"/path/to/file", false, "/path/to/file"},
+		{"/my key", false, "/my%20key"},
+		{"a/b", true, "a%2Fb"},
+		{"~unreserved-_.", true, "~unreserved-_."},
+		{"%20", false, "%2520"},
+	}
+	for _, test := range tests {
+		if got := awsURIEncode(test.input, test.encodeSlash); got != test.expected {
+			t.Errorf("Expected %q to encode to %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// StatusError is returned by the ErrorFromStatus interceptor in place of a
+// response whose status code indicates failure. Use errors.As to inspect it.
+type StatusError struct {
+	// Method and URL identify the request that failed.
+	Method string
+	URL    string
+	// StatusCode and Status are copied from the response.
+	StatusCode int
+	Status     string
+	// Header is the response header.
+	Header http.Header
+	// Body holds the start of the response body, up to the configured limit.
+	Body []byte
+	// Problem holds the details of an RFC 7807 application/problem+json body,
+	// or is nil if the response had none.
+	Problem *Problem
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("interceptor: %s %s: %s", e.Method, e.URL, e.Status)
+	if e.Problem != nil {
+		if e.Problem.Title != "" {
+			msg += ": " + e.Problem.Title
+		}
+		if e.Problem.Detail != "" {
+			msg += ": " + e.Problem.Detail
+		}
+	}
+	return msg
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds the members of the object other than the ones above.
+	Extensions map[string]any `json:"-"`
+}
+
+// ErrorFromStatusOption configures the ErrorFromStatus interceptor.
+type ErrorFromStatusOption func(*errorFromStatusConfig)
+
+type errorFromStatusConfig struct {
+	bodyLimit int64
+	isError   func(*http.Response) bool
+}
+
+// ErrorFromStatusBodyLimit sets how many bytes of the response body are kept in
+// StatusError.Body. The default is 4096.
+func ErrorFromStatusBodyLimit(n int64) ErrorFromStatusOption {
+	return func(c *errorFromStatusConfig) {
+		if n >= 0 {
+			c.bodyLimit = n
+		}
+	}
+}
+
+// ErrorFromStatusFunc overrides which responses are converted into errors. By
+// default every response without a 2xx status is, except 304 Not Modified, the
+// expected answer to a conditional request.
+func ErrorFromStatusFunc(f func(*http.Response) bool) ErrorFromStatusOption {
+	return func(c *errorFromStatusConfig) {
+		if f != nil {
+			c.isError = f
+		}
+	}
+}
+
+// ErrorFromStatus returns an Interceptor that turns failed responses into a
+// *StatusError, so that callers can handle them with errors.As instead of
+// checking status codes after every request:
+//
+//	var statusErr *interceptor.StatusError
+//	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+//		// ...
+//	}
+//
+// The error keeps the start of the response body and, when the response is
+// application/problem+json, its parsed problem details. The response body is
+// closed and no response is returned with the error.
+func ErrorFromStatus(opts ...ErrorFromStatusOption) Interceptor {
+	cfg := errorFromStatusConfig{
+		bodyLimit: 4096,
+		isError: func(resp *http.Response) bool {
+			return (resp.StatusCode < 200 || resp.StatusCode > 299) && resp.StatusCode != http.StatusNotModified
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || !cfg.isError(resp) {
+				return resp, err
+			}
+
+			statusErr := &StatusError{
+				Method:     req.Method,
+				URL:        req.URL.Redacted(),
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Header:     resp.Header,
+			}
+			if statusErr.Method == "" {
+				statusErr.Method = http.MethodGet
+			}
+			if statusErr.Status == "" {
+				statusErr.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+			}
+			if resp.Body != nil {
+				statusErr.Body, _ = io.ReadAll(io.LimitReader(resp.Body, cfg.bodyLimit))
+				resp.Body.Close()
+			}
+			if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/problem+json" {
+				statusErr.Problem = parseProblem(statusErr.Body)
+			}
+			return nil, statusErr
+		})
+	}
+}
+
+// parseProblem decodes an RFC 7807 problem details object, returning nil if
+// data is not one.
+func parseProblem(data []byte) *Problem {
+	var problem Problem
+	if err := json.Unmarshal(data, &problem); err != nil {
+		return nil
+	}
+	var members map[string]any
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil
+	}
+	for _, name := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, name)
+	}
+	if len(members) > 0 {
+		problem.Extensions = members
+	}
+	return &problem
+}
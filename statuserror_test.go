@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestErrorFromStatusInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Header:     http.Header{"Content-Type": {"application/problem+json"}},
+			Body: io.NopCloser(strings.NewReader(
+				`{"type":"https://example.com/not-found","title":"Not Found","status":404,"detail":"No such user","user":"42"}`)),
+		}, nil
+	})
+	rt := ErrorFromStatus()(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com/users/42", nil)
+	resp, err := rt.RoundTrip(req)
+	if resp != nil {
+		t.Errorf("Expected no response with the error")
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected a *StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", statusErr.StatusCode)
+	}
+	if statusErr.Problem == nil || statusErr.Problem.Detail != "No such user" {
+		t.Fatalf("Expected the problem details to be parsed, got %+v", statusErr.Problem)
+	}
+	if statusErr.Problem.Extensions["user"] != "42" {
+		t.Errorf("Expected extension members to be kept, got %v", statusErr.Problem.Extensions)
+	}
+	expected := "interceptor: GET http://example.com/users/42: 404 Not Found: Not Found: No such user"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestErrorFromStatusInterceptorOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		opts     []ErrorFromStatusOption
+		expected bool
+	}{
+		{"success", http.StatusOK, nil, false},
+		{"not modified", http.StatusNotModified, nil, false},
+		{"server error", http.StatusInternalServerError, nil, true},
+		{"custom", http.StatusNotFound, []ErrorFromStatusOption{ErrorFromStatusFunc(func(resp *http.Response) bool {
+			return resp.StatusCode >= 500
+		})}, false},
+	}
+
+	for _, test := range tests {
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: test.status, Body: io.NopCloser(strings.NewReader(strings.Repeat("x", 100)))}, nil
+		})
+		opts := append(test.opts, ErrorFromStatusBodyLimit(10))
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		_, err := ErrorFromStatus(opts...)(transport).RoundTrip(req)
+
+		var statusErr *StatusError
+		if got := errors.As(err, &statusErr); got != test.expected {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.expected, err)
+		}
+		if statusErr != nil && len(statusErr.Body) != 10 {
+			t.Errorf("%s: expected the body to be limited to 10 bytes, got %d", test.name, len(statusErr.Body))
+		}
+	}
+}
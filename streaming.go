@@ -0,0 +1,67 @@
+package interceptor
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+type streamingKey struct{}
+
+// WithStreaming returns a copy of ctx that marks requests made with it as
+// streaming, for long-lived responses that cannot be recognized by their
+// content type, such as long polls. Built-in interceptors that would otherwise
+// read the whole response body leave streaming responses to be read as they
+// arrive.
+func WithStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingKey{}, true)
+}
+
+// streamingTypes are the media types of responses delivered as a stream of
+// events that may never end.
+var streamingTypes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+	"application/stream+json",
+}
+
+// IsStreamingRequest reports whether req expects a streaming response, either
+// because its context was marked with WithStreaming or because it accepts
+// text/event-stream, as Server-Sent Events clients do. It can be used with
+// Unless to keep buffering interceptors away from streams:
+//
+//	pipeline.Use(interceptor.Unless(interceptor.IsStreamingRequest, audit))
+func IsStreamingRequest(req *http.Request) bool {
+	if streaming, _ := req.Context().Value(streamingKey{}).(bool); streaming {
+		return true
+	}
+	for _, value := range req.Header.Values("Accept") {
+		for _, accepted := range strings.Split(value, ",") {
+			mediaType, _, _ := mime.ParseMediaType(accepted)
+			if mediaType == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsStreamingResponse reports whether resp is a stream whose body should be
+// read incrementally rather than all at once: a response to a request marked
+// with WithStreaming, or one whose content type is text/event-stream,
+// application/x-ndjson, or application/stream+json.
+func IsStreamingResponse(resp *http.Response) bool {
+	if resp.Request != nil {
+		if streaming, _ := resp.Request.Context().Value(streamingKey{}).(bool); streaming {
+			return true
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	for _, streamingType := range streamingTypes {
+		if mediaType == streamingType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsStreaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		accept      string
+		contentType string
+		expectedReq bool
+		expectedRes bool
+	}{
+		{"plain", context.Background(), "application/json", "application/json", false, false},
+		{"event stream", context.Background(), "application/json, text/event-stream", "text/event-stream; charset=utf-8", true, true},
+		{"ndjson", context.Background(), "", "application/x-ndjson", false, true},
+		{"long poll", WithStreaming(context.Background()), "", "application/json", true, true},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequestWithContext(test.ctx, "GET", "http://example.com", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		resp := &http.Response{Header: http.Header{"Content-Type": {test.contentType}}, Request: req}
+		if got := IsStreamingRequest(req); got != test.expectedReq {
+			t.Errorf("%s: expected IsStreamingRequest %v, got %v", test.name, test.expectedReq, got)
+		}
+		if got := IsStreamingResponse(resp); got != test.expectedRes {
+			t.Errorf("%s: expected IsStreamingResponse %v, got %v", test.name, test.expectedRes, got)
+		}
+	}
+}
+
+func TestStreamingPassthrough(t *testing.T) {
+	var out bytes.Buffer
+	interceptors := map[string]Interceptor{
+		"Dump":   Dump(&out),
+		"Cache":  Cache(NewMemoryCacheStore(0)),
+		"Dedupe": Dedupe(nil),
+	}
+
+	for name, i := range interceptors {
+		pr, pw := io.Pipe()
+		transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/event-stream"}, "Cache-Control": {"max-age=60"}},
+				Body:       pr,
+				Request:    req,
+			}, nil
+		})
+
+		done := make(chan *http.Response, 1)
+		go func() {
+			req, _ := http.NewRequest("GET", "http://example.com/events", nil)
+			resp, _ := i(transport).RoundTrip(req)
+			done <- resp
+		}()
+
+		select {
+		case resp := <-done:
+			go pw.Write([]byte("data: hello\n\n"))
+			buf := make([]byte, 13)
+			if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "data: hello\n\n" {
+				t.Errorf("%s: expected the first event, got %q (%v)", name, buf, err)
+			}
+			resp.Body.Close()
+		case <-time.After(time.Second):
+			t.Errorf("%s: expected the stream to be returned before it ends", name)
+		}
+		pw.Close()
+	}
+}
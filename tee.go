@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// TeeResponse returns an Interceptor that streams a copy of each response body
+// to sink, for example to write it to an audit log or upload it to object
+// storage. sink is called in its own goroutine with the request and a reader
+// that yields the body as the caller reads it, so the body is never held in
+// memory as a whole and the caller receives it unchanged.
+//
+// The caller's reads wait for sink to consume what was read before, so sink
+// should read steadily until the reader is exhausted. If sink returns early,
+// the rest of the body is not copied. If the caller closes the body before
+// reading it to the end, sink's reader fails with io.ErrUnexpectedEOF.
+// Responses without a body are not passed to sink.
+func TeeResponse(sink func(*http.Request, io.Reader)) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, err
+			}
+
+			pr, pw := io.Pipe()
+			go func() {
+				sink(req, pr)
+				pr.Close()
+			}()
+			resp.Body = &teeBody{ReadCloser: resp.Body, pw: pw}
+			return resp, nil
+		})
+	}
+}
+
+// teeBody copies what is read from a response body into a pipe.
+type teeBody struct {
+	io.ReadCloser
+	pw   *io.PipeWriter
+	once sync.Once
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		// A failed write means that the sink stopped reading.
+		b.pw.Write(p[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		b.finish(nil)
+	} else if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	b.finish(io.ErrUnexpectedEOF)
+	return b.ReadCloser.Close()
+}
+
+// finish closes the pipe, with err if the body was not read to the end.
+func (b *teeBody) finish(err error) {
+	b.once.Do(func() {
+		b.pw.CloseWithError(err)
+	})
+}
@@ -0,0 +1,50 @@
+package interceptor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTeeResponseInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("response body"))}, nil
+	})
+
+	type copied struct {
+		url  string
+		body string
+		err  error
+	}
+	done := make(chan copied, 1)
+	rt := TeeResponse(func(req *http.Request, r io.Reader) {
+		data, err := io.ReadAll(r)
+		done <- copied{req.URL.String(), string(data), err}
+	})(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com/audit", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "response body" {
+		t.Errorf("Expected the caller to read the body unchanged, got %q", body)
+	}
+	got := <-done
+	if got.err != nil || got.body != "response body" || got.url != "http://example.com/audit" {
+		t.Errorf("Expected the sink to receive a copy of the body, got %+v", got)
+	}
+
+	// A body closed early is reported to the sink as truncated.
+	resp, _ = rt.RoundTrip(req)
+	resp.Body.Read(make([]byte, 4))
+	resp.Body.Close()
+	if got := <-done; !errors.Is(got.err, io.ErrUnexpectedEOF) || got.body != "resp" {
+		t.Errorf("Expected a truncated copy, got %+v", got)
+	}
+}
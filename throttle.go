@@ -0,0 +1,239 @@
+package interceptor
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottleOption configures the Throttle interceptor.
+type ThrottleOption func(*throttleConfig)
+
+type throttleConfig struct {
+	reserve int
+	spread  bool
+	maxWait time.Duration
+	now     func() time.Time
+}
+
+// ThrottleReserve sets how many requests of a host's quota are left unused.
+// Requests wait for the quota to reset once no more than n requests remain.
+// The default is 0, which waits only when the quota is exhausted.
+func ThrottleReserve(n int) ThrottleOption {
+	return func(c *throttleConfig) {
+		if n >= 0 {
+			c.reserve = n
+		}
+	}
+}
+
+// ThrottleSpread spaces requests evenly over the time left until the quota
+// resets, instead of sending them as fast as possible until it runs out.
+func ThrottleSpread() ThrottleOption {
+	return func(c *throttleConfig) {
+		c.spread = true
+	}
+}
+
+// ThrottleMaxWait sets the longest a request waits for the quota. Requests that
+// would wait longer fail immediately with ErrRateLimited, without using up the
+// quota. By default requests wait as long as needed, bounded only by their
+// context.
+func ThrottleMaxWait(d time.Duration) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.maxWait = d
+	}
+}
+
+// Throttle returns an Interceptor that paces requests to each host according to
+// the rate-limit headers of its responses, so that clients stay under a quota
+// instead of only reacting to 429 Too Many Requests.
+//
+// The remaining quota is read from the RateLimit-Remaining or
+// X-RateLimit-Remaining header, and the time it resets from RateLimit-Reset or
+// X-RateLimit-Reset, which may hold either a number of seconds or, as used by
+// GitHub and Okta, a Unix timestamp. A Retry-After header on a 429 or 503
+// response pauses requests to the host for the time it gives. Hosts that send
+// none of these headers are not throttled.
+//
+// Waiting respects the request context, so a canceled context fails the
+// request with the context's error.
+func Throttle(opts ...ThrottleOption) Interceptor {
+	cfg := throttleConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex
+	hosts := make(map[string]*throttleState)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if IsPreparing(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			host := req.URL.Host
+
+			mu.Lock()
+			state, ok := hosts[host]
+			if !ok {
+				state = &throttleState{remaining: -1}
+				hosts[host] = state
+			}
+			wait, reservation := state.reserve(&cfg, cfg.now())
+			mu.Unlock()
+			// giveBack returns the reservation of a request that is not sent.
+			giveBack := func() {
+				mu.Lock()
+				state.cancel(reservation)
+				mu.Unlock()
+			}
+
+			if wait > 0 {
+				if cfg.maxWait > 0 && wait > cfg.maxWait {
+					giveBack()
+					return nil, newError("Throttle", req, ErrRateLimited)
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					giveBack()
+					return nil, newError("Throttle", req, req.Context().Err())
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			state.update(resp, cfg.now())
+			mu.Unlock()
+			return resp, nil
+		})
+	}
+}
+
+// throttleState is the quota of a host as last reported by its responses.
+type throttleState struct {
+	// remaining is the number of requests left until reset, counting down as
+	// requests are sent, or -1 if unknown.
+	remaining int
+	reset     time.Time
+	// pausedUntil is set from Retry-After.
+	pausedUntil time.Time
+	// next is the earliest time the next request may be sent when spreading.
+	next time.Time
+}
+
+// throttleReservation is the share of a host's quota taken by reserve, which
+// is given back with cancel if the request is not sent.
+type throttleReservation struct {
+	// counted reports whether the request was counted against remaining in
+	// the window ending at reset.
+	counted bool
+	reset   time.Time
+	// prevNext and next are the values of throttleState.next before and after
+	// the reservation.
+	prevNext, next time.Time
+}
+
+// reserve accounts for a request about to be sent at now and returns how long
+// it must wait first.
+func (s *throttleState) reserve(cfg *throttleConfig, now time.Time) (time.Duration, throttleReservation) {
+	var r throttleReservation
+	start := now
+	if s.pausedUntil.After(start) {
+		start = s.pausedUntil
+	}
+	if s.remaining >= 0 {
+		if !s.reset.After(now) {
+			// The window has passed, so the quota is unknown until the next
+			// response.
+			s.remaining = -1
+		} else if s.remaining <= cfg.reserve {
+			if s.reset.After(start) {
+				start = s.reset
+			}
+		} else {
+			r.prevNext = s.next
+			if cfg.spread {
+				interval := s.reset.Sub(now) / time.Duration(s.remaining-cfg.reserve)
+				if s.next.After(start) {
+					start = s.next
+				}
+				s.next = start.Add(interval)
+			}
+			s.remaining--
+			r.counted, r.reset, r.next = true, s.reset, s.next
+		}
+	}
+	return start.Sub(now), r
+}
+
+// cancel gives back r, unless a response has reported a new quota since. The
+// spreading schedule is only rolled back if no request was scheduled after r.
+func (s *throttleState) cancel(r throttleReservation) {
+	if !r.counted || s.remaining < 0 || !s.reset.Equal(r.reset) {
+		return
+	}
+	s.remaining++
+	if s.next.Equal(r.next) {
+		s.next = r.prevNext
+	}
+}
+
+// update records the quota reported by resp, received at now.
+func (s *throttleState) update(resp *http.Response, now time.Time) {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if until, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+			s.pausedUntil = until
+		}
+	}
+
+	remaining, ok := headerInt(resp.Header, "RateLimit-Remaining", "X-RateLimit-Remaining")
+	if !ok {
+		return
+	}
+	reset, ok := headerInt(resp.Header, "RateLimit-Reset", "X-RateLimit-Reset")
+	if !ok {
+		return
+	}
+	s.remaining = remaining
+	// Values too large to be a number of seconds in a window are timestamps.
+	if reset > 1_000_000_000 {
+		s.reset = time.Unix(int64(reset), 0)
+	} else {
+		s.reset = now.Add(time.Duration(reset) * time.Second)
+	}
+}
+
+// headerInt returns the value of the first of names present in h as an
+// integer.
+func headerInt(h http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if value := h.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			return n, err == nil && n >= 0
+		}
+	}
+	return 0, false
+}
+
+// retryAfter returns the time given by a Retry-After header value, which is
+// either a number of seconds or an HTTP date.
+func retryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
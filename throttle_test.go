@@ -0,0 +1,98 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestThrottleInterceptor(t *testing.T) {
+	reset := time.Unix(2_000_000_000, 0)
+	// The quota resets 30ms after the fake clock's time.
+	now := reset.Add(-30 * time.Millisecond)
+	remaining := 1
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		resp.Header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		remaining--
+		return resp, nil
+	})
+	rt := Throttle(func(c *throttleConfig) { c.now = func() time.Time { return now } })(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	elapsed := func() time.Duration {
+		start := time.Now()
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+		return time.Since(start)
+	}
+
+	if d := elapsed(); d > 20*time.Millisecond {
+		t.Errorf("Expected the first request not to wait, took %v", d)
+	}
+	if d := elapsed(); d > 20*time.Millisecond {
+		t.Errorf("Expected a request within the quota not to wait, took %v", d)
+	}
+	// The quota is exhausted, so the next request waits for it to reset.
+	if d := elapsed(); d < 25*time.Millisecond {
+		t.Errorf("Expected the request to wait for the quota to reset, took %v", d)
+	}
+
+	other, _ := http.NewRequest("GET", "http://other.example.com", nil)
+	start := time.Now()
+	if _, err := rt.RoundTrip(other); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("Expected other hosts not to be throttled, took %v", d)
+	}
+}
+
+func TestThrottleInterceptorRetryAfter(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": {"60"}},
+			Body:       http.NoBody,
+		}, nil
+	})
+	rt := Throttle(ThrottleMaxWait(time.Second))(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited while paused by Retry-After, got %v", err)
+	}
+}
+
+func TestThrottleReservationCancel(t *testing.T) {
+	now := time.Unix(2_000_000_000, 0)
+	cfg := throttleConfig{spread: true}
+	state := &throttleState{remaining: 2, reset: now.Add(time.Minute)}
+
+	// Spreading 2 requests over a minute sends the first at once and the
+	// second 30 seconds later.
+	if wait, _ := state.reserve(&cfg, now); wait != 0 {
+		t.Fatalf("Expected the first request not to wait, got %v", wait)
+	}
+	wait, reservation := state.reserve(&cfg, now)
+	if wait != 30*time.Second {
+		t.Fatalf("Expected the second request to wait 30s, got %v", wait)
+	}
+
+	// A request rejected by ThrottleMaxWait or canceled while waiting gives
+	// its slot to the next one.
+	state.cancel(reservation)
+	if state.remaining != 1 {
+		t.Errorf("Expected the quota to be given back, got %d remaining", state.remaining)
+	}
+	if wait, _ := state.reserve(&cfg, now); wait != 30*time.Second {
+		t.Errorf("Expected the next request to take the given back slot, got %v", wait)
+	}
+}
@@ -0,0 +1,86 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrPerTryTimeout is returned, wrapped together with
+// context.DeadlineExceeded, when a request fails because the deadline set by
+// PerTryTimeout passed while the request's own context was still live. This
+// lets a retrying interceptor distinguish a slow attempt, which is worth
+// retrying, from the overall deadline having passed.
+var ErrPerTryTimeout = errors.New("interceptor: attempt timed out")
+
+// Timeout returns an Interceptor that gives each request a deadline of d from
+// the moment it enters the Interceptor, in addition to any deadline already on
+// its context. Unlike http.Client.Timeout it applies only to requests passing
+// through the Pipeline, and can be scoped with When or Route.
+//
+// The deadline also covers reading the response body. The context is released
+// once the body has been read to the end or closed.
+//
+// When composed with an interceptor that retries requests, place Timeout
+// before it to bound the total time spent on all attempts. Use PerTryTimeout
+// after it to bound each attempt.
+func Timeout(d time.Duration) Interceptor {
+	return timeout(d, false)
+}
+
+// PerTryTimeout returns an Interceptor like Timeout, meant to be placed after
+// an interceptor that retries requests so that every attempt gets its own
+// budget of d. When an attempt runs out of time but the request's context has
+// not, the error wraps ErrPerTryTimeout.
+func PerTryTimeout(d time.Duration) Interceptor {
+	return timeout(d, true)
+}
+
+func timeout(d time.Duration, perTry bool) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			parent := req.Context()
+			ctx, cancel := context.WithTimeout(parent, d)
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					if perTry {
+						return nil, newError("PerTryTimeout", req, fmt.Errorf("%w: %w", ErrPerTryTimeout, err))
+					}
+					return nil, newError("Timeout", req, err)
+				}
+				return nil, err
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				cancel()
+				return resp, nil
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelBody releases a context once the body is read to the end or closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.cancel()
+	}
+	return n, err
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
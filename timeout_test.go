@@ -0,0 +1,88 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowTransport waits for delay or for the request context to be done.
+func slowTransport(delay time.Duration) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-time.After(delay):
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("done"))}, nil
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	})
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		interceptor Interceptor
+		parent      time.Duration
+		delay       time.Duration
+		wantErr     error
+		wantPerTry  bool
+	}{
+		{"within timeout", Timeout(time.Second), 0, 0, nil, false},
+		{"timeout exceeded", Timeout(10 * time.Millisecond), 0, time.Second, context.DeadlineExceeded, false},
+		{"per-try exceeded", PerTryTimeout(10 * time.Millisecond), 0, time.Second, context.DeadlineExceeded, true},
+		{"parent exceeded first", PerTryTimeout(time.Second), 10 * time.Millisecond, 2 * time.Second, context.DeadlineExceeded, false},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.parent > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, test.parent)
+			defer cancel()
+		}
+		rt := test.interceptor(slowTransport(test.delay))
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+		resp, err := rt.RoundTrip(req)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.wantErr, err)
+		}
+		if errors.Is(err, ErrPerTryTimeout) != test.wantPerTry {
+			t.Errorf("%s: expected ErrPerTryTimeout to be %v, got %v", test.name, test.wantPerTry, err)
+		}
+		if err == nil {
+			if body := readBody(t, resp); body != "done" {
+				t.Errorf("%s: expected body 'done', got '%s'", test.name, body)
+			}
+		}
+	}
+}
+
+func TestTimeoutInterceptorCoversBody(t *testing.T) {
+	var reqCtx context.Context
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		reqCtx = req.Context()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+	})
+	rt := Timeout(time.Minute)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if _, ok := reqCtx.Deadline(); !ok {
+		t.Errorf("Expected request context to have a deadline")
+	}
+	if reqCtx.Err() != nil {
+		t.Errorf("Expected context to stay live until the body is closed")
+	}
+	resp.Body.Close()
+	if reqCtx.Err() == nil {
+		t.Errorf("Expected context to be released once the body is closed")
+	}
+}
@@ -0,0 +1,167 @@
+package interceptor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPinMismatch is returned by the TLSPin interceptor when none of the
+// certificates presented by a pinned host matches its pins.
+var ErrPinMismatch = errors.New("interceptor: server certificate does not match pinned keys")
+
+// ErrPinnedHostInsecure is returned by the TLSPin interceptor for plain HTTP
+// requests to a pinned host, which cannot be verified.
+var ErrPinnedHostInsecure = errors.New("interceptor: pinned host requires https")
+
+// TLSPinOption configures the TLSPin interceptor.
+type TLSPinOption func(*tlsPinConfig)
+
+type tlsPinConfig struct {
+	base    *http.Transport
+	rootCAs *x509.CertPool
+}
+
+// TLSPinTransport sets the transport that the transport for the pinned host is
+// cloned from, so that they share its proxy, timeouts, and TLS settings. The
+// default is http.DefaultTransport.
+func TLSPinTransport(base *http.Transport) TLSPinOption {
+	return func(c *tlsPinConfig) {
+		if base != nil {
+			c.base = base
+		}
+	}
+}
+
+// TLSPinRootCAs makes the pinned host's certificate chain be verified against
+// pool instead of the roots of the base transport, for example a private CA
+// used only by internal identity endpoints.
+func TLSPinRootCAs(pool *x509.CertPool) TLSPinOption {
+	return func(c *tlsPinConfig) {
+		c.rootCAs = pool
+	}
+}
+
+// TLSPin returns an Interceptor that pins the public keys of the certificates
+// presented by host. Each pin is the base64-encoded SHA-256 hash of a
+// certificate's SubjectPublicKeyInfo, optionally prefixed with "sha256/", as
+// printed by:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// A connection to host is accepted only if its certificate chain passes the
+// usual verification and one of its certificates, leaf, intermediate, or
+// root, matches one of pins; otherwise the request fails with ErrPinMismatch.
+// If the base transport skips verification with InsecureSkipVerify, only the
+// leaf certificate is matched.
+// With no pins, only the verification against the TLSPinRootCAs pool is
+// applied. Plain HTTP requests to host fail with ErrPinnedHostInsecure, and
+// requests to other hosts are passed down the chain unchanged. host is matched
+// against the request's host name without its port.
+//
+// The TLS configuration of an http.Transport cannot vary per request, so
+// TLSPin sends requests to host through a transport of its own, cloned from
+// the one given with TLSPinTransport, and shares no connections with the
+// rest of the Pipeline. Requests to host therefore do not reach the
+// interceptors after TLSPin, and like ClientCert it should be the last
+// interceptor in the chain. Use one TLSPin per host to pin several hosts.
+// Pipeline.Shutdown closes the idle connections of its transport.
+func TLSPin(host string, pins []string, opts ...TLSPinOption) Interceptor {
+	var cfg tlsPinConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.base == nil {
+		cfg.base = defaultTransport()
+	}
+
+	hashes, pinErr := parsePins(pins)
+	transport := cfg.base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if cfg.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = cfg.rootCAs
+	}
+	verify := transport.TLSClientConfig.VerifyConnection
+	transport.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		return verifyPins(cs, hashes)
+	}
+
+	closeIdle := func() error {
+		transport.CloseIdleConnections()
+		return nil
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withClose(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !strings.EqualFold(req.URL.Hostname(), host) {
+				return next.RoundTrip(req)
+			}
+			if pinErr != nil {
+				return nil, newError("TLSPin", req, pinErr)
+			}
+			if req.URL.Scheme != "https" {
+				return nil, newError("TLSPin", req, ErrPinnedHostInsecure)
+			}
+			if IsPreparing(req.Context()) {
+				return nil, newError("TLSPin", req, ErrNotPrepared)
+			}
+			resp, err := transport.RoundTrip(req)
+			if errors.Is(err, ErrPinMismatch) {
+				return nil, newError("TLSPin", req, ErrPinMismatch)
+			}
+			return resp, err
+		}), closeIdle)
+	}
+}
+
+// parsePins decodes pins into SHA-256 hashes.
+func parsePins(pins []string) ([][sha256.Size]byte, error) {
+	hashes := make([][sha256.Size]byte, len(pins))
+	for i, pin := range pins {
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("interceptor: invalid pin %q", pin)
+		}
+		hashes[i] = [sha256.Size]byte(sum)
+	}
+	return hashes, nil
+}
+
+// verifyPins checks that a certificate of the connection matches one of
+// hashes. The verified chains are checked if there are any. Otherwise, as
+// with InsecureSkipVerify, only the leaf certificate is checked: the server
+// can present any other certificate, including a copy of the pinned one,
+// without holding its key.
+func verifyPins(cs tls.ConnectionState, hashes [][sha256.Size]byte) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	var certs []*x509.Certificate
+	for _, chain := range cs.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	if len(certs) == 0 && len(cs.PeerCertificates) > 0 {
+		certs = cs.PeerCertificates[:1]
+	}
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, hash := range hashes {
+			if sum == hash {
+				return nil
+			}
+		}
+	}
+	return ErrPinMismatch
+}
@@ -0,0 +1,112 @@
+package interceptor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSPinInterceptor(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// Silence the handshake error logged for the rejected connection.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	sum := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	pin := "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+	other := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	base := server.Client().Transport.(*http.Transport)
+
+	tests := []struct {
+		name    string
+		host    string
+		pins    []string
+		url     string
+		reached bool
+		err     error
+	}{
+		{"pin matches", "127.0.0.1", []string{other, pin}, server.URL, false, nil},
+		{"pin mismatch", "127.0.0.1", []string{other}, server.URL, false, ErrPinMismatch},
+		{"plain http", "127.0.0.1", []string{pin}, "http://127.0.0.1/", false, ErrPinnedHostInsecure},
+		{"other host", "identity.example.com", []string{other}, server.URL, true, nil},
+	}
+
+	for _, test := range tests {
+		reached := false
+		next := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			reached = true
+			return base.RoundTrip(req)
+		})
+		rt := TLSPin(test.host, test.pins, TLSPinTransport(base))(next)
+
+		req, _ := http.NewRequest("GET", test.url, nil)
+		resp, err := rt.RoundTrip(req)
+		if test.err != nil {
+			var perr *Error
+			if !errors.Is(err, test.err) || !errors.As(err, &perr) || perr.Interceptor != "TLSPin" {
+				t.Errorf("%s: Expected %v from TLSPin, got %v", test.name, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: Failed to perform request: %v", test.name, err)
+		} else {
+			resp.Body.Close()
+		}
+		if reached != test.reached {
+			t.Errorf("%s: Expected next transport reached to be %v, got %v", test.name, test.reached, reached)
+		}
+	}
+}
+
+func TestTLSPinRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	pool := server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	// The default transport does not trust the test server, but the pool does.
+	rt := TLSPin("127.0.0.1", nil, TLSPinRootCAs(pool))(http.DefaultTransport)
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTLSPinInvalidPin(t *testing.T) {
+	rt := TLSPin("example.com", []string{"not a pin"})(&mockRoundTripper{})
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Errorf("Expected an error for an invalid pin")
+	}
+}
+
+func TestTLSPinUnverifiedChain(t *testing.T) {
+	leaf := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("leaf")}
+	pinned := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("pinned")}
+	hashes := [][sha256.Size]byte{sha256.Sum256(pinned.RawSubjectPublicKeyInfo)}
+
+	// Without verification a server can append a copy of the pinned
+	// certificate to its own, so only the leaf counts.
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}}
+	if err := verifyPins(cs, hashes); !errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected ErrPinMismatch for a pinned certificate that is not the leaf, got %v", err)
+	}
+	cs = tls.ConnectionState{PeerCertificates: []*x509.Certificate{pinned, leaf}}
+	if err := verifyPins(cs, hashes); err != nil {
+		t.Errorf("Expected a pinned leaf to be accepted, got %v", err)
+	}
+	cs = tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   [][]*x509.Certificate{{leaf, pinned}},
+	}
+	if err := verifyPins(cs, hashes); err != nil {
+		t.Errorf("Expected a pinned certificate in the verified chain to be accepted, got %v", err)
+	}
+}
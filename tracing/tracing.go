@@ -0,0 +1,143 @@
+// Package tracing provides an OpenTelemetry tracing interceptor. It lives in its
+// own package so that programs which do not use OpenTelemetry do not depend on
+// it.
+package tracing
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package as the source of its spans.
+const instrumentationName = "github.com/brain-hol/http-interceptors-go/tracing"
+
+// Option configures the Trace interceptor.
+type Option func(*config)
+
+type config struct {
+	propagator propagation.TextMapPropagator
+	spanName   func(*http.Request) string
+}
+
+// WithPropagator sets the propagator used to inject the span context into
+// request headers. The default is W3C Trace Context (the traceparent and
+// tracestate headers).
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithSpanNameFormatter sets the function used to name spans. By default spans
+// are named after the request method, for example "GET".
+func WithSpanNameFormatter(f func(*http.Request) string) Option {
+	return func(c *config) {
+		c.spanName = f
+	}
+}
+
+// Trace returns an Interceptor that starts a client span for every request
+// using a tracer from tp, and injects the span context into the outgoing
+// request headers.
+//
+// The span records the response status code, and is marked as failed for
+// transport errors and 4xx or 5xx responses. It ends once the response body has
+// been read to the end or closed, so its duration covers the whole exchange.
+func Trace(tp trace.TracerProvider, opts ...Option) interceptor.Interceptor {
+	cfg := config{
+		propagator: propagation.TraceContext{},
+		spanName: func(req *http.Request) string {
+			return req.Method
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := tp.Tracer(instrumentationName)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), cfg.spanName(req),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(requestAttributes(req)...),
+			)
+
+			req = req.Clone(ctx)
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return nil, err
+			}
+
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				span.End()
+				return resp, nil
+			}
+			resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+			return resp, nil
+		})
+	}
+}
+
+func requestAttributes(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.Redacted()),
+	}
+	if host := req.URL.Hostname(); host != "" {
+		attrs = append(attrs, attribute.String("server.address", host))
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+	return attrs
+}
+
+// spanBody ends its span when the body is read to the end, fails, or is closed.
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.end()
+	} else if err != nil {
+		b.once.Do(func() {
+			b.span.RecordError(err)
+			b.span.SetStatus(codes.Error, err.Error())
+			b.span.End()
+		})
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end()
+	return err
+}
+
+func (b *spanBody) end() {
+	b.once.Do(func() {
+		b.span.End()
+	})
+}
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	interceptor "github.com/brain-hol/http-interceptors-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func statusAttribute(span sdktrace.ReadOnlySpan) (int64, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == "http.response.status_code" {
+			return attr.Value.AsInt64(), true
+		}
+	}
+	return 0, false
+}
+
+func TestTraceInterceptor(t *testing.T) {
+	tp, recorder := newProvider()
+	var traceparent string
+	transport := interceptor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		traceparent = req.Header.Get("Traceparent")
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+	})
+	rt := Trace(tp)(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com:8080/resource", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if traceparent == "" {
+		t.Errorf("Expected traceparent header to be injected")
+	}
+	if req.Header.Get("Traceparent") != "" {
+		t.Errorf("Expected original request to be left unmodified")
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Errorf("Expected span to stay open until the body is consumed")
+	}
+
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 ended span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET" {
+		t.Errorf("Expected span name 'GET', got '%s'", span.Name())
+	}
+	if !strings.Contains(traceparent, span.SpanContext().SpanID().String()) {
+		t.Errorf("Expected traceparent '%s' to reference span %s", traceparent, span.SpanContext().SpanID())
+	}
+	if status, ok := statusAttribute(span); !ok || status != http.StatusOK {
+		t.Errorf("Expected status code attribute %d, got %d", http.StatusOK, status)
+	}
+	wantAttrs := map[attribute.Key]string{
+		"http.request.method": "GET",
+		"url.full":            "http://example.com:8080/resource",
+		"server.address":      "example.com",
+	}
+	for _, attr := range span.Attributes() {
+		if want, ok := wantAttrs[attr.Key]; ok && attr.Value.AsString() != want {
+			t.Errorf("Expected attribute %s to be '%s', got '%s'", attr.Key, want, attr.Value.AsString())
+		}
+	}
+}
+
+func TestTraceInterceptorErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+	}{
+		{"transport error", interceptor.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})},
+		{"server error", interceptor.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+		})},
+	}
+
+	for _, test := range tests {
+		tp, recorder := newProvider()
+		rt := Trace(tp)(test.transport)
+
+		req, _ := http.NewRequest("POST", "http://example.com", nil)
+		rt.RoundTrip(req)
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("%s: expected 1 ended span, got %d", test.name, len(spans))
+		}
+		if spans[0].Status().Code != codes.Error {
+			t.Errorf("%s: expected span status to be Error, got %v", test.name, spans[0].Status().Code)
+		}
+	}
+}
+
+func TestTraceInterceptorSpanName(t *testing.T) {
+	tp, recorder := newProvider()
+	transport := interceptor.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := Trace(tp, WithSpanNameFormatter(func(req *http.Request) string {
+		return req.Method + " " + req.URL.Path
+	}))(transport)
+
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	rt.RoundTrip(req)
+
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "GET /users" {
+		t.Errorf("Expected a single span named 'GET /users'")
+	}
+}
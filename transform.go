@@ -0,0 +1,94 @@
+package interceptor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// TransformRequest returns an Interceptor that rewrites request bodies with f,
+// which receives the original body and returns the body to send, for example to
+// redact fields or to wrap a payload in the envelope a legacy API expects.
+// Requests without a body are passed through untouched.
+//
+// The transformed body is read into memory before the request is sent so that
+// its Content-Length is known and, through req.GetBody, it can be sent again by
+// interceptors that retry requests. If f or reading its output fails, the
+// request fails with the error and is not sent.
+func TransformRequest(f func(io.Reader) (io.Reader, error)) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body == nil || req.Body == http.NoBody {
+				return next.RoundTrip(req)
+			}
+
+			transformed, err := f(req.Body)
+			if err != nil {
+				req.Body.Close()
+				return nil, newError("TransformRequest", req, err)
+			}
+			body, err := io.ReadAll(transformed)
+			if closer, ok := transformed.(io.Closer); ok {
+				closer.Close()
+			}
+			req.Body.Close()
+			if err != nil {
+				return nil, newError("TransformRequest", req, err)
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			req.ContentLength = int64(len(body))
+			req.Header.Del("Content-Length")
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// TransformResponse returns an Interceptor that rewrites response bodies with
+// f, which receives the original body and returns the body to give to the
+// caller, for example to unwrap an envelope or to convert XML to JSON. The body
+// is transformed as it is read, so large responses are not held in memory.
+// Since the length of the result is not known in advance, the Content-Length
+// header is removed and resp.ContentLength is set to -1.
+//
+// Closing the returned body closes the original body, and the reader returned
+// by f if it is an io.Closer. If f fails, the response body is closed and the
+// error is returned in place of the response.
+func TransformResponse(f func(io.Reader) (io.Reader, error)) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return resp, err
+			}
+
+			transformed, err := f(resp.Body)
+			if err != nil {
+				resp.Body.Close()
+				return nil, newError("TransformResponse", req, err)
+			}
+			resp.Body = &transformedBody{Reader: transformed, src: resp.Body}
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			return resp, nil
+		})
+	}
+}
+
+// transformedBody reads a transformed response body and closes both the
+// transformation and the original body.
+type transformedBody struct {
+	io.Reader
+	src io.ReadCloser
+}
+
+func (b *transformedBody) Close() error {
+	if closer, ok := b.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.src.Close()
+}
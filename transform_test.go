@@ -0,0 +1,87 @@
+package interceptor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func upper(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(bytes.ToUpper(data)), nil
+}
+
+func TestTransformRequestInterceptor(t *testing.T) {
+	var bodies []string
+	var lengths []int64
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		lengths = append(lengths, req.ContentLength)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := TransformRequest(upper)(transport)
+
+	req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if bodies[0] != "PAYLOAD" || lengths[0] != 7 {
+		t.Errorf("Expected the transformed body with its length, got %q (%d)", bodies[0], lengths[0])
+	}
+
+	// The transformed body can be replayed by retrying interceptors.
+	retry := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		transport.RoundTrip(req)
+		req.Body, _ = req.GetBody()
+		return transport.RoundTrip(req)
+	})
+	req, _ = http.NewRequest("POST", "http://example.com", strings.NewReader("again"))
+	if _, err := TransformRequest(upper)(retry).RoundTrip(req); err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	if bodies[2] != "AGAIN" {
+		t.Errorf("Expected the replayed body to be transformed, got %q", bodies[2])
+	}
+}
+
+func TestTransformResponseInterceptor(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Length": {"4"}},
+			Body:          io.NopCloser(strings.NewReader("body")),
+			ContentLength: 4,
+		}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := TransformResponse(func(r io.Reader) (io.Reader, error) {
+		return io.MultiReader(strings.NewReader(`{"data":"`), r, strings.NewReader(`"}`)), nil
+	})(transport).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"data":"body"}` {
+		t.Errorf("Expected the transformed body, got %q", body)
+	}
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" {
+		t.Errorf("Expected the length to be unknown, got %d", resp.ContentLength)
+	}
+
+	failure := errors.New("bad payload")
+	_, err = TransformResponse(func(io.Reader) (io.Reader, error) {
+		return nil, failure
+	})(transport).RoundTrip(req)
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the transformation error, got %v", err)
+	}
+}